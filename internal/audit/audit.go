@@ -0,0 +1,186 @@
+// Package audit provides an append-only JSONL log of mutating tool calls.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Status values recorded for a tool call.
+const (
+	StatusSuccess   = "success"
+	StatusError     = "error"
+	StatusPending   = "pending_confirmation"
+	StatusCancelled = "cancelled"
+)
+
+// redactedArgs lists tool arguments whose values are never written to the log.
+var redactedArgs = map[string]bool{
+	"content":       true,
+	"yaml":          true,
+	"api_key":       true,
+	"confirm_token": true,
+}
+
+// Entry is a single audit record.
+type Entry struct {
+	Time       time.Time         `json:"time"`
+	User       string            `json:"user"`
+	Tool       string            `json:"tool"`
+	Args       map[string]any    `json:"args,omitempty"`
+	Status     string            `json:"status"`
+	Error      string            `json:"error,omitempty"`
+	Refs       map[string]string `json:"refs,omitempty"`
+	DurationMS int64             `json:"duration_ms"`
+}
+
+// Filter selects entries in Query. Zero-valued fields match everything.
+type Filter struct {
+	Tool   string
+	User   string
+	Status string
+	Since  time.Time
+	Limit  int
+}
+
+// Log is an append-only audit log backed by a JSONL file.
+type Log struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// Open opens or creates the audit log at path. The file and its directory
+// are created with owner-only permissions.
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &Log{path: path, file: f}, nil
+}
+
+// Path returns the audit log file path.
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Write appends an entry to the log.
+func (l *Log) Write(e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(data); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// Query returns entries matching f, newest first. Lines that cannot be
+// parsed are skipped.
+func (l *Log) Query(f Filter) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.Open(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	var matches []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if f.matches(e) {
+			matches = append(matches, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	// Newest first, trimmed to the limit
+	for i, j := 0, len(matches)-1; i < j; i, j = i+1, j-1 {
+		matches[i], matches[j] = matches[j], matches[i]
+	}
+	if f.Limit > 0 && len(matches) > f.Limit {
+		matches = matches[:f.Limit]
+	}
+	return matches, nil
+}
+
+func (f Filter) matches(e Entry) bool {
+	if f.Tool != "" && e.Tool != f.Tool {
+		return false
+	}
+	if f.User != "" && e.User != f.User {
+		return false
+	}
+	if f.Status != "" && e.Status != f.Status {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	return true
+}
+
+// Redact returns a copy of tool arguments safe for logging. Secret values
+// are dropped and file or settings content is replaced with its size.
+func Redact(args map[string]any) map[string]any {
+	if len(args) == 0 {
+		return nil
+	}
+	out := make(map[string]any, len(args))
+	for k, v := range args {
+		if !redactedArgs[k] {
+			out[k] = v
+			continue
+		}
+		if s, ok := v.(string); ok && (k == "content" || k == "yaml") {
+			out[k] = fmt.Sprintf("[redacted %d bytes]", len(s))
+		} else {
+			out[k] = "[redacted]"
+		}
+	}
+
+	// Batch files embed content inside a JSON string. Anything else cannot
+	// be redacted field by field, so it is dropped.
+	if files, ok := out["files"]; ok {
+		var batch []map[string]any
+		if list, isString := files.(string); isString && json.Unmarshal([]byte(list), &batch) == nil {
+			for i := range batch {
+				batch[i] = Redact(batch[i])
+			}
+			out["files"] = batch
+		} else {
+			out["files"] = "[redacted]"
+		}
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteAndQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	log, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer log.Close()
+
+	base := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Time: base, User: "alice", Tool: "publish", Status: StatusSuccess},
+		{Time: base.Add(time.Hour), User: "bob", Tool: "delete_file", Status: StatusError},
+		{Time: base.Add(2 * time.Hour), User: "alice", Tool: "delete_file", Status: StatusSuccess},
+	}
+	for _, e := range entries {
+		if err := log.Write(e); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("audit log mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"all newest first", Filter{}, []string{"delete_file", "delete_file", "publish"}},
+		{"by user", Filter{User: "alice"}, []string{"delete_file", "publish"}},
+		{"by status", Filter{Status: StatusError}, []string{"delete_file"}},
+		{"since", Filter{Since: base.Add(30 * time.Minute)}, []string{"delete_file", "delete_file"}},
+		{"limit", Filter{Limit: 1}, []string{"delete_file"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := log.Query(tt.filter)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Query() returned %d entries, want %d", len(got), len(tt.want))
+			}
+			for i, e := range got {
+				if e.Tool != tt.want[i] {
+					t.Errorf("Query()[%d].Tool = %s, want %s", i, e.Tool, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	args := map[string]any{
+		"dest_path":     "sensors/a/README.md",
+		"content":       "secret body",
+		"api_key":       "mk_123",
+		"confirm_token": "abc",
+		"files":         `[{"dest_path": "b.md", "content": "hello"}]`,
+	}
+
+	got := Redact(args)
+
+	if got["dest_path"] != "sensors/a/README.md" {
+		t.Errorf("Redact() dest_path = %v", got["dest_path"])
+	}
+	if got["content"] != "[redacted 11 bytes]" {
+		t.Errorf("Redact() content = %v", got["content"])
+	}
+	if got["api_key"] != "[redacted]" {
+		t.Errorf("Redact() api_key = %v", got["api_key"])
+	}
+	files, ok := got["files"].([]map[string]any)
+	if !ok || len(files) != 1 || files[0]["content"] != "[redacted 5 bytes]" {
+		t.Errorf("Redact() files = %v", got["files"])
+	}
+	if args["content"] != "secret body" {
+		t.Error("Redact() modified its input")
+	}
+}
+
+func TestRedact_UnparseableFiles(t *testing.T) {
+	for _, files := range []any{
+		`[{"dest_path": "b.md", "content": "hello"`,
+		[]any{map[string]any{"content": "hello"}},
+	} {
+		if got := Redact(map[string]any{"files": files}); got["files"] != "[redacted]" {
+			t.Errorf("Redact(files: %v) = %v, want [redacted]", files, got["files"])
+		}
+	}
+}
@@ -3,18 +3,23 @@ package client
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strings"
 	"time"
 )
 
 const (
-	// APIVersion is the API version to use.
+	// APIVersion is the newest API version the client is written against.
+	// Use Negotiate to pick the version the server supports.
 	APIVersion = "2025.12"
 )
 
@@ -22,18 +27,23 @@ const (
 type Client struct {
 	baseURL    string
 	apiKey     string
+	version    string
+	missing    map[string]string // route pattern -> feature, see endpoint
+	authScheme string
+	timeouts   Timeouts
+	tlsConfig  *tls.Config
+	proxy      string
+	observer   RequestObserver
+	logger     *slog.Logger
 	httpClient *http.Client
+	// ctx is the parent context of requests (see WithContext).
+	ctx context.Context
 }
 
-// New creates a new API client.
+// New creates a new API client using APIVersion and default options.
 func New(baseURL, apiKey string) *Client {
-	return &Client{
-		baseURL: baseURL,
-		apiKey:  apiKey,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-	}
+	c, _ := NewWithOptions(baseURL, apiKey, Options{})
+	return c
 }
 
 // SearchResult represents a search result.
@@ -137,6 +147,26 @@ type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// APIError is returned when the API responds with an error status.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error (%d): %s", e.StatusCode, e.Message)
+}
+
+// apiError builds an APIError from an error response body.
+func apiError(resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	var errResp ErrorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil {
+		return &APIError{StatusCode: resp.StatusCode, Message: string(body)}
+	}
+	return &APIError{StatusCode: resp.StatusCode, Message: errResp.Error}
+}
+
 // User represents the current authenticated user.
 type User struct {
 	ID           string   `json:"id"`
@@ -145,6 +175,8 @@ type User struct {
 	CreatedAt    string   `json:"created_at"`
 	LastSeenAt   string   `json:"last_seen_at"`
 	IsActive     bool     `json:"is_active"`
+	KeyCreatedAt string   `json:"key_created_at,omitempty"`
+	KeyExpiresAt string   `json:"key_expires_at,omitempty"`
 }
 
 // HasCapability checks if the user has a specific capability.
@@ -207,6 +239,29 @@ func (u *User) CapabilitiesString() string {
 	return strings.Join(u.Capabilities, ", ")
 }
 
+// capabilityPattern matches "*" or a scoped capability such as "read:*"
+// or "write:publish".
+var capabilityPattern = regexp.MustCompile(`^(\*|(read|write|admin):(\*|[a-z][a-z_]*))$`)
+
+// ParseCapabilities splits a comma-separated capability list, rejecting
+// malformed entries so typos are not silently stored.
+func ParseCapabilities(list string) ([]string, error) {
+	var caps []string
+	seen := make(map[string]bool)
+	for _, c := range strings.Split(list, ",") {
+		c = strings.TrimSpace(c)
+		if c == "" || seen[c] {
+			continue
+		}
+		if !capabilityPattern.MatchString(c) {
+			return nil, fmt.Errorf("invalid capability %q (use '*' or scope:name with scope read, write or admin, e.g. 'read:*' or 'write:publish')", c)
+		}
+		seen[c] = true
+		caps = append(caps, c)
+	}
+	return caps, nil
+}
+
 // MeResponse is the response from the /me endpoint.
 type MeResponse struct {
 	User User `json:"user"`
@@ -241,8 +296,9 @@ type UsersResponse struct {
 
 // CreateUserRequest is the request to create a new user.
 type CreateUserRequest struct {
-	Name string `json:"name"`
-	Role string `json:"role"`
+	Name      string `json:"name"`
+	Role      string `json:"role"`
+	ExpiresAt string `json:"expires_at,omitempty"`
 }
 
 // CreateUserResponse is the response from creating a user.
@@ -281,10 +337,22 @@ type SyncResponse struct {
 	Error        string `json:"error,omitempty"`
 }
 
+// SyncRequest is the request to trigger a git sync.
+// All fields are optional; the zero value syncs and pushes everything
+// with the server's default commit message and author.
+type SyncRequest struct {
+	Message     string   `json:"message,omitempty"`
+	AuthorName  string   `json:"author_name,omitempty"`
+	AuthorEmail string   `json:"author_email,omitempty"`
+	Paths       []string `json:"paths,omitempty"`
+	Push        *bool    `json:"push,omitempty"`
+}
+
 // RotateKeyResponse is the response from rotating an API key.
 type RotateKeyResponse struct {
-	APIKey  string `json:"api_key"`
-	Message string `json:"message"`
+	APIKey    string `json:"api_key"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+	Message   string `json:"message"`
 }
 
 // Setting represents a configuration setting.
@@ -299,6 +367,22 @@ type SettingsResponse struct {
 	Settings []Setting `json:"settings"`
 }
 
+// SettingSchema describes the type and constraints of a setting.
+type SettingSchema struct {
+	Key         string   `json:"key"`
+	Type        string   `json:"type"` // string, int, float, bool, duration, url, or enum
+	Allowed     []string `json:"allowed,omitempty"`
+	Min         *float64 `json:"min,omitempty"`
+	Max         *float64 `json:"max,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// SettingsSchemaResponse is the response from the settings schema endpoint.
+type SettingsSchemaResponse struct {
+	Settings []SettingSchema `json:"settings"`
+}
+
 // Reference represents a device reference (related device or external link).
 type Reference struct {
 	Type  string `json:"type"`
@@ -349,6 +433,33 @@ type SemanticSearchResponse struct {
 	Results []SemanticSearchResult `json:"results"`
 }
 
+// Commit represents a git commit in the documentation repository.
+type Commit struct {
+	Hash        string `json:"hash"`
+	ShortHash   string `json:"short_hash"`
+	Author      string `json:"author"`
+	AuthorEmail string `json:"author_email"`
+	Date        string `json:"date"`
+	Message     string `json:"message"`
+}
+
+// HistoryResponse is the response from the docs history endpoint.
+type HistoryResponse struct {
+	Path    string   `json:"path"`
+	Commits []Commit `json:"commits"`
+	Count   int      `json:"count"`
+}
+
+// DiffResponse is the response from the docs diff endpoint.
+type DiffResponse struct {
+	Path      string `json:"path"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Diff      string `json:"diff"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+}
+
 // Search searches for devices using keyword/FTS5 search.
 func (c *Client) Search(query string, limit int, domain, deviceType string) (*SearchResponse, error) {
 	params := url.Values{}
@@ -528,11 +639,11 @@ func (c *Client) GetReindexStatus() (*ReindexStatusResponse, error) {
 	return &resp, nil
 }
 
-// TriggerSync triggers a git sync to push documentation changes.
+// TriggerSync triggers a git sync to commit and push documentation changes.
 // Requires RW or Admin role.
-func (c *Client) TriggerSync() (*SyncResponse, error) {
+func (c *Client) TriggerSync(req SyncRequest) (*SyncResponse, error) {
 	var resp SyncResponse
-	if err := c.post("/rw/sync", nil, &resp); err != nil {
+	if err := c.post("/rw/sync", req, &resp); err != nil {
 		return nil, err
 	}
 	return &resp, nil
@@ -564,17 +675,18 @@ func (c *Client) UploadFile(destPath string, filename string, content []byte) (*
 	}
 
 	// Create request
-	req, err := http.NewRequest("POST", c.baseURL+"/api/"+APIVersion+"/rw/upload", &buf)
+	endpoint, err := c.endpoint("/rw/upload")
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", endpoint, &buf)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", writer.FormDataContentType())
-	if c.apiKey != "" {
-		req.Header.Set("X-API-Key", c.apiKey)
-	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
@@ -608,20 +720,19 @@ func (c *Client) DeleteFile(path string, reindex bool) (*DeleteResponse, error)
 	}
 
 	// Build URL
-	endpoint := fmt.Sprintf("/api/%s/rw/delete?%s", APIVersion, params.Encode())
+	endpoint, err := c.endpoint("/rw/delete?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
 
 	// Create request
-	req, err := http.NewRequest("DELETE", c.baseURL+endpoint, nil)
+	req, err := http.NewRequest("DELETE", endpoint, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	if c.apiKey != "" {
-		req.Header.Set("X-API-Key", c.apiKey)
-	}
-
 	// Execute request
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
@@ -656,10 +767,11 @@ func (c *Client) ListUsers() (*UsersResponse, error) {
 	return &resp, nil
 }
 
-// CreateUser creates a new user.
+// CreateUser creates a new user. A non-zero expiresAt sets when the
+// generated API key stops working.
 // Requires Admin role.
-func (c *Client) CreateUser(name, role string) (*CreateUserResponse, error) {
-	req := CreateUserRequest{Name: name, Role: role}
+func (c *Client) CreateUser(name, role string, expiresAt time.Time) (*CreateUserResponse, error) {
+	req := CreateUserRequest{Name: name, Role: role, ExpiresAt: formatExpiry(expiresAt)}
 	var resp CreateUserResponse
 	if err := c.post("/admin/users", req, &resp); err != nil {
 		return nil, err
@@ -684,16 +796,52 @@ func (c *Client) UpdateUserRole(id, role string) error {
 	return c.put("/admin/users/"+id+"/role", req)
 }
 
-// RotateAPIKey rotates a user's API key and returns the new key.
+// SetUserActive deactivates or reactivates a user. Inactive users keep
+// their account and capabilities but their API key is rejected.
 // Requires Admin role.
-func (c *Client) RotateAPIKey(id string) (*RotateKeyResponse, error) {
+func (c *Client) SetUserActive(id string, active bool) error {
+	req := map[string]bool{"is_active": active}
+	return c.put("/admin/users/"+id+"/active", req)
+}
+
+// RenameUser changes a user's name.
+// Requires Admin role.
+func (c *Client) RenameUser(id, name string) error {
+	req := map[string]string{"name": name}
+	return c.put("/admin/users/"+id+"/name", req)
+}
+
+// UpdateUserCapabilities replaces a user's capability list, e.g.
+// []string{"read:*", "write:publish"}.
+// Requires Admin role.
+func (c *Client) UpdateUserCapabilities(id string, capabilities []string) error {
+	req := map[string][]string{"capabilities": capabilities}
+	return c.put("/admin/users/"+id+"/capabilities", req)
+}
+
+// RotateAPIKey rotates a user's API key and returns the new key. A non-zero
+// expiresAt sets when the new key stops working.
+// Requires Admin role.
+func (c *Client) RotateAPIKey(id string, expiresAt time.Time) (*RotateKeyResponse, error) {
+	var req interface{}
+	if !expiresAt.IsZero() {
+		req = map[string]string{"expires_at": formatExpiry(expiresAt)}
+	}
 	var resp RotateKeyResponse
-	if err := c.post("/admin/users/"+id+"/rotate-key", nil, &resp); err != nil {
+	if err := c.post("/admin/users/"+id+"/rotate-key", req, &resp); err != nil {
 		return nil, err
 	}
 	return &resp, nil
 }
 
+// formatExpiry formats a key expiry for the API, or "" for no expiry.
+func formatExpiry(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
 // ListSettings lists all settings.
 // Requires Admin role.
 func (c *Client) ListSettings() (*SettingsResponse, error) {
@@ -704,6 +852,16 @@ func (c *Client) ListSettings() (*SettingsResponse, error) {
 	return &resp, nil
 }
 
+// SettingsSchema gets the type and constraints of every known setting.
+// Requires Admin role.
+func (c *Client) SettingsSchema() (*SettingsSchemaResponse, error) {
+	var resp SettingsSchemaResponse
+	if err := c.get("/admin/settings/schema", &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
 // UpdateSetting updates a setting value.
 // Requires Admin role.
 func (c *Client) UpdateSetting(key, value string) error {
@@ -762,15 +920,16 @@ func (c *Client) GetDocument(id string) (*Document, error) {
 
 // DownloadDocument downloads a document's content by ID.
 func (c *Client) DownloadDocument(id string) ([]byte, string, error) {
-	req, err := http.NewRequest("GET", c.baseURL+"/api/"+APIVersion+"/documents/"+id+"/download", nil)
+	endpoint, err := c.endpoint("/documents/" + id + "/download")
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to create request: %w", err)
+		return nil, "", err
 	}
-	if c.apiKey != "" {
-		req.Header.Set("X-API-Key", c.apiKey)
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, "", fmt.Errorf("request failed: %w", err)
 	}
@@ -794,30 +953,69 @@ func (c *Client) DownloadDocument(id string) ([]byte, string, error) {
 	return content, contentType, nil
 }
 
+// DocHistory lists git commits that touched a documentation path, newest first.
+// since is an optional date (e.g. "2025-12-01") limiting how far back to look.
+func (c *Client) DocHistory(path string, limit int, since string) (*HistoryResponse, error) {
+	params := url.Values{}
+	params.Set("path", path)
+	if limit > 0 {
+		params.Set("limit", fmt.Sprintf("%d", limit))
+	}
+	if since != "" {
+		params.Set("since", since)
+	}
+
+	var resp HistoryResponse
+	if err := c.get("/docs/history?"+params.Encode(), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DocDiff gets a unified diff of a documentation path between two revisions.
+// Empty from/to use the server defaults (the previous commit and HEAD).
+func (c *Client) DocDiff(path, from, to string) (*DiffResponse, error) {
+	params := url.Values{}
+	params.Set("path", path)
+	if from != "" {
+		params.Set("from", from)
+	}
+	if to != "" {
+		params.Set("to", to)
+	}
+
+	var resp DiffResponse
+	if err := c.get("/docs/diff?"+params.Encode(), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
 // get performs a GET request and decodes the JSON response.
 func (c *Client) get(path string, result interface{}) error {
-	req, err := http.NewRequest("GET", c.baseURL+"/api/"+APIVersion+path, nil)
+	endpoint, err := c.endpoint(path)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return err
 	}
-	// Only add API key header if configured (allows anonymous access)
-	if c.apiKey != "" {
-		req.Header.Set("X-API-Key", c.apiKey)
+	return c.getURL(endpoint, result)
+}
+
+// getURL performs a GET request to an absolute URL and decodes the JSON
+// response.
+func (c *Client) getURL(endpoint string, result interface{}) error {
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		var errResp ErrorResponse
-		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
-			body, _ := io.ReadAll(resp.Body)
-			return fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
-		}
-		return fmt.Errorf("API error (%d): %s", resp.StatusCode, errResp.Error)
+		return apiError(resp)
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
@@ -853,19 +1051,20 @@ func (c *Client) doJSON(method, path string, body interface{}, result interface{
 		reqBody = bytes.NewReader(data)
 	}
 
-	req, err := http.NewRequest(method, c.baseURL+"/api/"+APIVersion+path, reqBody)
+	endpoint, err := c.endpoint(path)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(method, endpoint, reqBody)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	if c.apiKey != "" {
-		req.Header.Set("X-API-Key", c.apiKey)
-	}
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("request failed: %w", err)
 	}
@@ -873,12 +1072,7 @@ func (c *Client) doJSON(method, path string, body interface{}, result interface{
 
 	// Accept success status codes
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		var errResp ErrorResponse
-		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
-			respBody, _ := io.ReadAll(resp.Body)
-			return fmt.Errorf("API error (%d): %s", resp.StatusCode, string(respBody))
-		}
-		return fmt.Errorf("API error (%d): %s", resp.StatusCode, errResp.Error)
+		return apiError(resp)
 	}
 
 	if result != nil {
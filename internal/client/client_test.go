@@ -2,10 +2,12 @@ package client
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNew(t *testing.T) {
@@ -431,7 +433,7 @@ func TestTriggerSync(t *testing.T) {
 	defer server.Close()
 
 	client := New(server.URL, "test-key")
-	resp, err := client.TriggerSync()
+	resp, err := client.TriggerSync(SyncRequest{})
 
 	if err != nil {
 		t.Errorf("TriggerSync() error = %v", err)
@@ -444,6 +446,46 @@ func TestTriggerSync(t *testing.T) {
 	}
 }
 
+func TestTriggerSync_WithOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req SyncRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if req.Message != "Add BME280 docs" {
+			t.Errorf("message = %s, want Add BME280 docs", req.Message)
+		}
+		if req.AuthorName != "alice" || req.AuthorEmail != "alice@example.com" {
+			t.Errorf("author = %s <%s>, want alice <alice@example.com>", req.AuthorName, req.AuthorEmail)
+		}
+		if len(req.Paths) != 1 || req.Paths[0] != "sensors/bme280" {
+			t.Errorf("paths = %v, want [sensors/bme280]", req.Paths)
+		}
+		if req.Push == nil || *req.Push {
+			t.Errorf("push = %v, want false", req.Push)
+		}
+		json.NewEncoder(w).Encode(SyncResponse{Status: "success", Commit: "abc123"})
+	}))
+	defer server.Close()
+
+	push := false
+	client := New(server.URL, "test-key")
+	resp, err := client.TriggerSync(SyncRequest{
+		Message:     "Add BME280 docs",
+		AuthorName:  "alice",
+		AuthorEmail: "alice@example.com",
+		Paths:       []string{"sensors/bme280"},
+		Push:        &push,
+	})
+
+	if err != nil {
+		t.Errorf("TriggerSync() error = %v", err)
+	}
+	if resp.Commit != "abc123" {
+		t.Errorf("TriggerSync() commit = %s, want abc123", resp.Commit)
+	}
+}
+
 func TestListUsers(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/api/"+APIVersion+"/admin/users" {
@@ -495,7 +537,7 @@ func TestCreateUser(t *testing.T) {
 	defer server.Close()
 
 	client := New(server.URL, "admin-key")
-	resp, err := client.CreateUser("newuser", "ro")
+	resp, err := client.CreateUser("newuser", "ro", time.Time{})
 
 	if err != nil {
 		t.Errorf("CreateUser() error = %v", err)
@@ -677,6 +719,97 @@ func TestUpdateUserRole(t *testing.T) {
 	}
 }
 
+func TestSetUserActive(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" {
+			t.Errorf("method = %s, want PUT", r.Method)
+		}
+		if r.URL.Path != "/api/"+APIVersion+"/admin/users/user-123/active" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+
+		var req map[string]bool
+		json.NewDecoder(r.Body).Decode(&req)
+		if active, ok := req["is_active"]; !ok || active {
+			t.Errorf("is_active = %v (present %v), want false", active, ok)
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "admin-key")
+	if err := client.SetUserActive("user-123", false); err != nil {
+		t.Errorf("SetUserActive() error = %v", err)
+	}
+}
+
+func TestRenameUser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" {
+			t.Errorf("method = %s, want PUT", r.Method)
+		}
+		if r.URL.Path != "/api/"+APIVersion+"/admin/users/user-123/name" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["name"] != "ci-bot" {
+			t.Errorf("name = %s, want ci-bot", req["name"])
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "admin-key")
+	if err := client.RenameUser("user-123", "ci-bot"); err != nil {
+		t.Errorf("RenameUser() error = %v", err)
+	}
+}
+
+func TestParseCapabilities(t *testing.T) {
+	caps, err := ParseCapabilities(" read:* , write:publish,read:*,")
+	if err != nil {
+		t.Fatalf("ParseCapabilities() error = %v", err)
+	}
+	if got := strings.Join(caps, ","); got != "read:*,write:publish" {
+		t.Errorf("ParseCapabilities() = %s, want read:*,write:publish", got)
+	}
+
+	for _, bad := range []string{"publish", "write:", "delete:files", "READ:*", "read:devices;admin:*"} {
+		if _, err := ParseCapabilities(bad); err == nil {
+			t.Errorf("ParseCapabilities(%q) error = nil, want error", bad)
+		}
+	}
+}
+
+func TestUpdateUserCapabilities(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" {
+			t.Errorf("method = %s, want PUT", r.Method)
+		}
+		if r.URL.Path != "/api/"+APIVersion+"/admin/users/user-123/capabilities" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+
+		var req map[string][]string
+		json.NewDecoder(r.Body).Decode(&req)
+		if got := strings.Join(req["capabilities"], ","); got != "read:*,write:publish" {
+			t.Errorf("capabilities = %s, want read:*,write:publish", got)
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "admin-key")
+	if err := client.UpdateUserCapabilities("user-123", []string{"read:*", "write:publish"}); err != nil {
+		t.Errorf("UpdateUserCapabilities() error = %v", err)
+	}
+}
+
 func TestRotateAPIKey(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
@@ -694,7 +827,7 @@ func TestRotateAPIKey(t *testing.T) {
 	defer server.Close()
 
 	client := New(server.URL, "admin-key")
-	resp, err := client.RotateAPIKey("user-123")
+	resp, err := client.RotateAPIKey("user-123", time.Time{})
 
 	if err != nil {
 		t.Errorf("RotateAPIKey() error = %v", err)
@@ -707,6 +840,29 @@ func TestRotateAPIKey(t *testing.T) {
 	}
 }
 
+func TestRotateAPIKey_WithExpiry(t *testing.T) {
+	expires := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["expires_at"] != "2026-03-01T12:00:00Z" {
+			t.Errorf("expires_at = %s, want 2026-03-01T12:00:00Z", req["expires_at"])
+		}
+
+		json.NewEncoder(w).Encode(RotateKeyResponse{APIKey: "mapi_newkey123", ExpiresAt: req["expires_at"]})
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "admin-key")
+	resp, err := client.RotateAPIKey("user-123", expires)
+	if err != nil {
+		t.Fatalf("RotateAPIKey() error = %v", err)
+	}
+	if resp.ExpiresAt != "2026-03-01T12:00:00Z" {
+		t.Errorf("RotateAPIKey() ExpiresAt = %s, want 2026-03-01T12:00:00Z", resp.ExpiresAt)
+	}
+}
+
 func TestListSettings(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/api/"+APIVersion+"/admin/settings" {
@@ -735,6 +891,28 @@ func TestListSettings(t *testing.T) {
 	}
 }
 
+func TestSettingsSchema(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/"+APIVersion+"/admin/settings/schema" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"settings":[{"key":"reindex.workers","type":"int","min":1,"max":16,"description":"Indexer workers"}]}`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "admin-key")
+	resp, err := client.SettingsSchema()
+	if err != nil {
+		t.Fatalf("SettingsSchema() error = %v", err)
+	}
+	if len(resp.Settings) != 1 {
+		t.Fatalf("SettingsSchema() returned %d settings, want 1", len(resp.Settings))
+	}
+	if got := resp.Settings[0]; got.Type != "int" || got.Min == nil || *got.Min != 1 || got.Max == nil || *got.Max != 16 {
+		t.Errorf("SettingsSchema() setting = %+v, want int with min 1 max 16", got)
+	}
+}
+
 func TestUpdateSetting(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "PUT" {
@@ -949,3 +1127,239 @@ func TestDownloadDocument_NetworkError(t *testing.T) {
 		t.Error("DownloadDocument() should return error on network failure")
 	}
 }
+
+func TestDocHistory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/"+APIVersion+"/docs/history" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("path") != "sensors/bme280/README.md" {
+			t.Errorf("path = %s, want sensors/bme280/README.md", r.URL.Query().Get("path"))
+		}
+		if r.URL.Query().Get("since") != "2025-12-01" {
+			t.Errorf("since = %s, want 2025-12-01", r.URL.Query().Get("since"))
+		}
+		json.NewEncoder(w).Encode(HistoryResponse{
+			Path:    "sensors/bme280/README.md",
+			Commits: []Commit{{Hash: "abc123", Author: "alice", Message: "Fix pinout"}},
+			Count:   1,
+		})
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "")
+	resp, err := client.DocHistory("sensors/bme280/README.md", 20, "2025-12-01")
+
+	if err != nil {
+		t.Errorf("DocHistory() error = %v", err)
+	}
+	if resp == nil {
+		t.Fatal("DocHistory() returned nil")
+	}
+	if len(resp.Commits) != 1 || resp.Commits[0].Hash != "abc123" {
+		t.Errorf("DocHistory() commits = %v, want [abc123]", resp.Commits)
+	}
+}
+
+func TestDocDiff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/"+APIVersion+"/docs/diff" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("from") != "abc123" {
+			t.Errorf("from = %s, want abc123", r.URL.Query().Get("from"))
+		}
+		if _, ok := r.URL.Query()["to"]; ok {
+			t.Error("to should be omitted when empty")
+		}
+		json.NewEncoder(w).Encode(DiffResponse{Path: "sensors/bme280/README.md", Diff: "-old\n+new\n", Additions: 1, Deletions: 1})
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "")
+	resp, err := client.DocDiff("sensors/bme280/README.md", "abc123", "")
+
+	if err != nil {
+		t.Errorf("DocDiff() error = %v", err)
+	}
+	if resp == nil {
+		t.Fatal("DocDiff() returned nil")
+	}
+	if resp.Additions != 1 {
+		t.Errorf("DocDiff() additions = %d, want 1", resp.Additions)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name        string
+		handler     http.HandlerFunc
+		wantVersion string
+		wantNewer   string
+		wantMissing bool
+		wantErr     error
+	}{
+		{
+			name: "newest mutual version",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(VersionsResponse{Versions: []string{"2024.01", APIVersion}})
+			},
+			wantVersion: APIVersion,
+		},
+		{
+			name: "server also offers a newer version",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(VersionsResponse{Versions: []string{APIVersion, "2099.01"}})
+			},
+			wantVersion: APIVersion,
+			wantNewer:   "2099.01",
+		},
+		{
+			name: "server lacking optional features",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(VersionsResponse{
+					Current:  APIVersion,
+					Features: map[string][]string{APIVersion: {"user_active"}},
+				})
+			},
+			wantVersion: APIVersion,
+			wantMissing: true,
+		},
+		{
+			name: "server without feature list",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(VersionsResponse{Current: APIVersion})
+			},
+			wantVersion: APIVersion,
+		},
+		{
+			name: "legacy server without discovery",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/"+APIVersion+"/status" {
+					w.WriteHeader(http.StatusNotFound)
+					json.NewEncoder(w).Encode(ErrorResponse{Error: "not found"})
+					return
+				}
+				json.NewEncoder(w).Encode(StatusResponse{Status: "ok"})
+			},
+			wantVersion: APIVersion,
+		},
+		{
+			name: "incompatible",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(VersionsResponse{Versions: []string{"2024.01"}})
+			},
+			wantErr: ErrIncompatible,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			client := New(server.URL, "")
+			n, err := client.Negotiate()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Negotiate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Negotiate() error = %v", err)
+			}
+			if n.Version != tt.wantVersion || client.Version() != tt.wantVersion {
+				t.Errorf("Negotiate() version = %s, client.Version() = %s, want %s", n.Version, client.Version(), tt.wantVersion)
+			}
+			if n.Newer != tt.wantNewer {
+				t.Errorf("Negotiate() Newer = %q, want %q", n.Newer, tt.wantNewer)
+			}
+			if (len(n.Missing) > 0) != tt.wantMissing {
+				t.Errorf("Negotiate() Missing = %v, want missing features: %v", n.Missing, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestFeatureGating(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/versions" {
+			json.NewEncoder(w).Encode(VersionsResponse{
+				Versions: []string{APIVersion},
+				Features: map[string][]string{APIVersion: {"user_rename", "user_capabilities", "doc_history"}},
+			})
+			return
+		}
+		paths = append(paths, r.URL.Path)
+		json.NewEncoder(w).Encode(UsersResponse{})
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "test-key")
+	n, err := client.Negotiate()
+	if err != nil {
+		t.Fatalf("Negotiate() error = %v", err)
+	}
+	if got := strings.Join(n.Missing, ", "); got != "setting validation, user deactivation" {
+		t.Errorf("Negotiate() Missing = %s, want setting validation, user deactivation", got)
+	}
+
+	if _, err := client.ListUsers(); err != nil {
+		t.Fatalf("ListUsers() error = %v", err)
+	}
+	if want := "/api/" + APIVersion + "/admin/users"; len(paths) != 1 || paths[0] != want {
+		t.Errorf("ListUsers() requested %v, want %s", paths, want)
+	}
+
+	var unsupported *UnsupportedError
+	if _, err := client.SettingsSchema(); !errors.As(err, &unsupported) {
+		t.Errorf("SettingsSchema() error = %v, want UnsupportedError", err)
+	}
+	if err := client.SetUserActive("u1", false); !errors.As(err, &unsupported) {
+		t.Errorf("SetUserActive() error = %v, want UnsupportedError", err)
+	}
+	if len(paths) != 1 {
+		t.Errorf("unsupported requests reached the server: %v", paths)
+	}
+
+	if err := client.SetVersion(APIVersion); err != nil {
+		t.Fatalf("SetVersion() error = %v", err)
+	}
+	if _, err := client.SettingsSchema(); errors.As(err, &unsupported) {
+		t.Errorf("SettingsSchema() after SetVersion() = %v, want the request sent", err)
+	}
+	if err := client.SetVersion("1999.01"); err == nil {
+		t.Error("SetVersion() accepted an unknown version")
+	}
+}
+
+func TestPin(t *testing.T) {
+	offered := "2099.01"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(VersionsResponse{
+			Versions: []string{offered},
+			Features: map[string][]string{offered: {}},
+		})
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "")
+	if _, err := client.Pin(APIVersion); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Pin() error = %v, want ErrIncompatible for a version the server does not offer", err)
+	}
+	if _, err := client.Pin("1999.01"); err == nil || errors.Is(err, ErrIncompatible) {
+		t.Errorf("Pin(1999.01) error = %v, want unsupported version", err)
+	}
+
+	offered = APIVersion
+
+	n, err := client.Pin(APIVersion)
+	if err != nil {
+		t.Fatalf("Pin() error = %v", err)
+	}
+	if n.Version != APIVersion || client.Version() != APIVersion || len(n.Missing) == 0 {
+		t.Errorf("Pin() = %+v, want %s with missing features", n, APIVersion)
+	}
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"time"
+)
+
+// ReindexWatch describes how to wait for a reindex job.
+type ReindexWatch struct {
+	// Triggered marks a wait for a reindex the caller just triggered.
+	// Without it an idle status completes immediately.
+	Triggered bool
+
+	// Previous is the LastCompleted value read before the trigger, or
+	// empty if the server did not report one.
+	Previous string
+
+	// Timeout bounds the total wait.
+	Timeout time.Duration
+
+	// Interval is the delay between status checks.
+	Interval time.Duration
+
+	// Status fetches the current reindex status.
+	Status func() (*ReindexStatusResponse, error)
+
+	// OnPoll, if set, is called with every status that does not complete
+	// the wait.
+	OnPoll func(poll int, status *ReindexStatusResponse)
+}
+
+// ReindexWait is the outcome of waiting for a reindex job.
+type ReindexWait struct {
+	Status    *ReindexStatusResponse
+	Completed bool
+	Waited    time.Duration
+	Timeout   time.Duration
+}
+
+// WaitForReindex polls the reindex status until the triggered job has
+// finished, the timeout elapses or ctx is cancelled.
+//
+// Right after a trigger the server may still report the previous run as
+// idle, so an idle status only counts once the job has been seen running,
+// LastCompleted differs from watch.Previous, or — when the server reports
+// no completion time — at least one interval has passed. Server timestamps
+// are only compared with each other, never with the local clock.
+func WaitForReindex(ctx context.Context, watch ReindexWatch) (*ReindexWait, error) {
+	start := time.Now()
+	deadline := start.Add(watch.Timeout)
+	result := &ReindexWait{Timeout: watch.Timeout}
+	busy := false
+
+	for poll := 1; ; poll++ {
+		status, err := watch.Status()
+		if err != nil {
+			return nil, err
+		}
+		result.Status = status
+		result.Waited = time.Since(start)
+
+		if status.Status == "idle" {
+			if busy || reindexFinished(status, watch, time.Since(start)) {
+				result.Completed = true
+				return result, nil
+			}
+		} else {
+			busy = true
+		}
+
+		if watch.OnPoll != nil {
+			watch.OnPoll(poll, status)
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return result, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(min(watch.Interval, remaining)):
+		}
+	}
+}
+
+// reindexFinished reports whether an idle status reflects a run that
+// finished after the watched trigger.
+func reindexFinished(status *ReindexStatusResponse, watch ReindexWatch, waited time.Duration) bool {
+	if !watch.Triggered {
+		return true
+	}
+	if status.LastCompleted == "" {
+		return waited >= watch.Interval
+	}
+	return status.LastCompleted != watch.Previous
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// reindexSequence returns a status func that replays statuses, repeating
+// the last one.
+func reindexSequence(statuses ...ReindexStatusResponse) func() (*ReindexStatusResponse, error) {
+	return func() (*ReindexStatusResponse, error) {
+		status := statuses[0]
+		if len(statuses) > 1 {
+			statuses = statuses[1:]
+		}
+		return &status, nil
+	}
+}
+
+func TestWaitForReindex_IgnoresPreviousRun(t *testing.T) {
+	old := "2026-10-18T10:00:00Z"
+	done := "2026-10-18T10:05:00Z"
+
+	var polls []string
+	wait, err := WaitForReindex(context.Background(), ReindexWatch{
+		Triggered: true,
+		Previous:  old,
+		Timeout:   time.Second,
+		Interval:  time.Millisecond,
+		Status: reindexSequence(
+			ReindexStatusResponse{Status: "idle", LastCompleted: old},
+			ReindexStatusResponse{Status: "running"},
+			ReindexStatusResponse{Status: "idle", LastCompleted: done},
+		),
+		OnPoll: func(poll int, status *ReindexStatusResponse) { polls = append(polls, status.Status) },
+	})
+	if err != nil {
+		t.Fatalf("WaitForReindex() error = %v", err)
+	}
+	if !wait.Completed || wait.Status.LastCompleted != done {
+		t.Errorf("WaitForReindex() = %+v, want completed with LastCompleted %s", wait, done)
+	}
+	if len(polls) != 2 {
+		t.Errorf("OnPoll saw %v, want the stale idle and running statuses", polls)
+	}
+}
+
+func TestWaitForReindex_CompletedSameSecond(t *testing.T) {
+	// A job that starts and finishes within the second it was triggered
+	// must still count, whatever the local clock says.
+	wait, err := WaitForReindex(context.Background(), ReindexWatch{
+		Triggered: true,
+		Previous:  "2026-10-18T10:00:00Z",
+		Timeout:   time.Second,
+		Interval:  time.Hour,
+		Status:    reindexSequence(ReindexStatusResponse{Status: "idle", LastCompleted: "2026-10-18T10:00:00.400Z"}),
+	})
+	if err != nil {
+		t.Fatalf("WaitForReindex() error = %v", err)
+	}
+	if !wait.Completed {
+		t.Error("WaitForReindex() Completed = false, want true for a run finished after the trigger")
+	}
+}
+
+func TestWaitForReindex_FirstRun(t *testing.T) {
+	wait, err := WaitForReindex(context.Background(), ReindexWatch{
+		Triggered: true,
+		Timeout:   time.Second,
+		Interval:  time.Hour,
+		Status:    reindexSequence(ReindexStatusResponse{Status: "idle", LastCompleted: "2026-10-18T10:00:00Z"}),
+	})
+	if err != nil {
+		t.Fatalf("WaitForReindex() error = %v", err)
+	}
+	if !wait.Completed {
+		t.Error("WaitForReindex() Completed = false, want true for the first completed run")
+	}
+}
+
+func TestWaitForReindex_StaleIdleTimesOut(t *testing.T) {
+	old := "2026-10-18T10:00:00Z"
+
+	wait, err := WaitForReindex(context.Background(), ReindexWatch{
+		Triggered: true,
+		Previous:  old,
+		Timeout:   20 * time.Millisecond,
+		Interval:  5 * time.Millisecond,
+		Status:    reindexSequence(ReindexStatusResponse{Status: "idle", LastCompleted: old}),
+	})
+	if err != nil {
+		t.Fatalf("WaitForReindex() error = %v", err)
+	}
+	if wait.Completed {
+		t.Error("WaitForReindex() Completed = true for the run that finished before the trigger")
+	}
+}
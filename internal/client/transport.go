@@ -0,0 +1,363 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName names the tracer of request spans. Spans go to the global
+// tracer provider, which is a no-op unless tracing is set up.
+const tracerName = "github.com/rmrfslashbin/manuals-mcp/internal/client"
+
+// Authentication schemes for sending the API key.
+const (
+	// AuthAPIKey sends the key in the X-API-Key header (default).
+	AuthAPIKey = "api-key"
+	// AuthBearer sends the key as an Authorization: Bearer token, for
+	// deployments behind gateways that expect one.
+	AuthBearer = "bearer"
+)
+
+// Default request timeouts.
+const (
+	DefaultTimeout         = 30 * time.Second
+	DefaultSearchTimeout   = 15 * time.Second
+	DefaultTransferTimeout = 5 * time.Minute
+)
+
+// Timeouts bounds each request by kind. Zero values use the defaults.
+type Timeouts struct {
+	// Default applies to requests that are neither searches nor transfers.
+	Default time.Duration
+	// Search applies to full-text and semantic search.
+	Search time.Duration
+	// Transfer applies to file uploads and document downloads.
+	Transfer time.Duration
+}
+
+// Options configures the client's transport and authentication.
+type Options struct {
+	// CAFile is a PEM bundle trusted in addition to the system roots.
+	CAFile string
+	// CertFile and KeyFile are a PEM client certificate and key for
+	// mutual TLS. Both must be set together.
+	CertFile string
+	KeyFile  string
+	// InsecureSkipVerify disables server certificate verification. Only
+	// for lab hosts with self-signed certificates.
+	InsecureSkipVerify bool
+	// Proxy is an http://, https://, or socks5:// proxy URL. Empty uses
+	// the HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment variables.
+	Proxy string
+	// AuthScheme is AuthAPIKey (default) or AuthBearer.
+	AuthScheme string
+	// Timeouts bounds requests by kind.
+	Timeouts Timeouts
+	// Observer, if set, is called after every request.
+	Observer RequestObserver
+	// Logger, if set, logs every request at debug level. The API key is
+	// never logged.
+	Logger *slog.Logger
+}
+
+// RequestObserver receives the method, route (see Route), HTTP status and
+// latency of an API request. The status is 0 if no response was received,
+// and the latency ends when the response headers arrive.
+type RequestObserver func(method, route string, status int, latency time.Duration)
+
+// NewWithOptions creates a new API client using APIVersion and opts.
+func NewWithOptions(baseURL, apiKey string, opts Options) (*Client, error) {
+	switch opts.AuthScheme {
+	case "":
+		opts.AuthScheme = AuthAPIKey
+	case AuthAPIKey, AuthBearer:
+	default:
+		return nil, fmt.Errorf("invalid auth scheme %q (must be %s or %s)", opts.AuthScheme, AuthAPIKey, AuthBearer)
+	}
+
+	tlsConfig, err := buildTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	if opts.Proxy != "" {
+		proxyURL, err := url.Parse(opts.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", opts.Proxy)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q (use http, https, or socks5)", proxyURL.Scheme)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	return &Client{
+		baseURL:    baseURL,
+		apiKey:     apiKey,
+		version:    APIVersion,
+		authScheme: opts.AuthScheme,
+		timeouts:   opts.Timeouts.withDefaults(),
+		tlsConfig:  tlsConfig,
+		proxy:      opts.Proxy,
+		observer:   opts.Observer,
+		logger:     opts.Logger,
+		httpClient: &http.Client{Transport: transport},
+	}, nil
+}
+
+// buildTLSConfig loads the CA bundle and client certificate from opts.
+func buildTLSConfig(opts Options) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", opts.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return nil, fmt.Errorf("client certificate and key must be set together")
+	}
+	if opts.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// withDefaults fills zero timeouts with the defaults.
+func (t Timeouts) withDefaults() Timeouts {
+	if t.Default <= 0 {
+		t.Default = DefaultTimeout
+	}
+	if t.Search <= 0 {
+		t.Search = DefaultSearchTimeout
+	}
+	if t.Transfer <= 0 {
+		t.Transfer = DefaultTransferTimeout
+	}
+	return t
+}
+
+// TLSConfig returns a copy of the TLS configuration used for requests.
+func (c *Client) TLSConfig() *tls.Config {
+	return c.tlsConfig.Clone()
+}
+
+// Proxy returns the configured proxy URL, or "" when the environment
+// decides.
+func (c *Client) Proxy() string {
+	return c.proxy
+}
+
+// timeoutFor picks the timeout for a request path.
+func (c *Client) timeoutFor(path string) time.Duration {
+	switch {
+	case strings.Contains(path, "/search"):
+		return c.timeouts.Search
+	case strings.HasSuffix(path, "/rw/upload"), strings.HasSuffix(path, "/download"):
+		return c.timeouts.Transfer
+	default:
+		return c.timeouts.Default
+	}
+}
+
+// CloseIdleConnections closes the client's idle keep-alive connections.
+// Call it when the client is replaced; connections still in use close once
+// idle, after the transport's idle timeout.
+func (c *Client) CloseIdleConnections() {
+	c.httpClient.CloseIdleConnections()
+}
+
+// WithContext returns a copy of c whose requests are made within ctx: they
+// are cancelled with it, and their trace spans are children of its span.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	c2 := *c
+	c2.ctx = ctx
+	return &c2
+}
+
+// do authenticates and sends req with the timeout for its kind, in a client
+// trace span whose context is propagated in the traceparent header. The
+// request carries the correlation ID of its context, or a new one. The
+// timeout and span cover reading the body, so they end, and the request is
+// logged, when the body is closed.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.apiKey != "" {
+		if c.authScheme == AuthBearer {
+			req.Header.Set("Authorization", "Bearer "+c.apiKey)
+		} else {
+			req.Header.Set("X-API-Key", c.apiKey)
+		}
+	}
+
+	parent := req.Context()
+	if c.ctx != nil {
+		parent = c.ctx
+	}
+	route := Route(req.URL.Path)
+	ctx, span := otel.Tracer(tracerName).Start(parent, req.Method+" "+route,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.HTTPRoute(route),
+			semconv.URLFull(req.URL.Redacted()),
+			semconv.ServerAddress(req.URL.Hostname()),
+		),
+	)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	requestID := RequestID(parent)
+	if requestID == "" {
+		requestID = NewRequestID()
+	}
+	req.Header.Set(RequestIDHeader, requestID)
+	logAttrs := []any{
+		"request_id", requestID,
+		"method", req.Method,
+		"path", req.URL.Path,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeoutFor(req.URL.Path))
+	start := time.Now()
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if c.observer != nil {
+		status := 0
+		if err == nil {
+			status = resp.StatusCode
+		}
+		c.observer(req.Method, route, status, time.Since(start))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		cancel()
+		if c.logger != nil {
+			c.logger.DebugContext(parent, "API request failed", append(logAttrs,
+				"duration_ms", time.Since(start).Milliseconds(),
+				"bytes_sent", max(req.ContentLength, 0),
+				"error", err,
+			)...)
+		}
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	resp.Body = &responseBody{ReadCloser: resp.Body, done: func(received int64) {
+		cancel()
+		span.End()
+		if c.logger != nil {
+			c.logger.DebugContext(parent, "API request", append(logAttrs,
+				"status", resp.StatusCode,
+				"duration_ms", time.Since(start).Milliseconds(),
+				"bytes_sent", max(req.ContentLength, 0),
+				"bytes_received", received,
+			)...)
+		}
+	}}
+	return resp, nil
+}
+
+// responseBody counts the bytes read from a response and calls done with
+// the count when it is closed, to release the request's timeout, end its
+// span and log it.
+type responseBody struct {
+	io.ReadCloser
+	n      int64
+	done   func(received int64)
+	closed bool
+}
+
+func (b *responseBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func (b *responseBody) Close() error {
+	err := b.ReadCloser.Close()
+	if !b.closed {
+		b.closed = true
+		b.done(b.n)
+	}
+	return err
+}
+
+// routes are the API paths with identifiers, most specific first. The
+// identifiers are replaced by the names in braces.
+var routes = []struct{ pattern, route string }{
+	{"/devices/*/pinout", "/devices/{id}/pinout"},
+	{"/devices/*/specs", "/devices/{id}/specs"},
+	{"/devices/*/refs", "/devices/{id}/refs"},
+	{"/devices/*", "/devices/{id}"},
+	{"/documents/*/download", "/documents/{id}/download"},
+	{"/documents/*", "/documents/{id}"},
+	{"/guides/*", "/guides/{id}"},
+	{"/admin/users/*/role", "/admin/users/{id}/role"},
+	{"/admin/users/*/active", "/admin/users/{id}/active"},
+	{"/admin/users/*/name", "/admin/users/{id}/name"},
+	{"/admin/users/*/capabilities", "/admin/users/{id}/capabilities"},
+	{"/admin/users/*/rotate-key", "/admin/users/{id}/rotate-key"},
+	{"/admin/users/*", "/admin/users/{id}"},
+	{"/admin/settings/schema", "/admin/settings/schema"},
+	{"/admin/settings/*", "/admin/settings/{key}"},
+}
+
+// Route returns the API route of a request path, without the /api/<version>
+// prefix and with identifiers replaced by placeholders, e.g.
+// "/api/2025.12/devices/esp32/pinout" becomes "/devices/{id}/pinout". It is
+// suitable as a low-cardinality metrics label.
+func Route(p string) string {
+	if rest, ok := strings.CutPrefix(p, "/api/"); ok {
+		if _, after, ok := strings.Cut(rest, "/"); ok {
+			p = "/" + after
+		} else {
+			p = "/" + rest
+		}
+	}
+	for _, r := range routes {
+		if ok, _ := path.Match(r.pattern, p); ok {
+			return r.route
+		}
+	}
+	return p
+}
@@ -0,0 +1,330 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// writeCert writes a certificate in PEM form and returns its path.
+func writeCert(t *testing.T, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cert.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newClientCert creates a self-signed client certificate and returns the
+// certificate and key file paths and the parsed certificate.
+func newClientCert(t *testing.T) (string, string, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "manuals-mcp-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return writeCert(t, der), keyPath, cert
+}
+
+// statusHandler answers every request with an OK status.
+func statusHandler(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(StatusResponse{Status: "ok"})
+}
+
+func TestNewWithOptions_CustomCA(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(statusHandler))
+	defer server.Close()
+
+	if _, err := New(server.URL, "").GetStatus(); err == nil {
+		t.Fatal("GetStatus() succeeded without trusting the test CA")
+	}
+
+	client, err := NewWithOptions(server.URL, "", Options{CAFile: writeCert(t, server.Certificate().Raw)})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+	if _, err := client.GetStatus(); err != nil {
+		t.Errorf("GetStatus() with CA bundle error = %v", err)
+	}
+
+	insecure, err := NewWithOptions(server.URL, "", Options{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+	if _, err := insecure.GetStatus(); err != nil {
+		t.Errorf("GetStatus() with InsecureSkipVerify error = %v", err)
+	}
+}
+
+func TestNewWithOptions_MutualTLS(t *testing.T) {
+	certFile, keyFile, cert := newClientCert(t)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(statusHandler))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	server.StartTLS()
+	defer server.Close()
+
+	without, _ := NewWithOptions(server.URL, "", Options{InsecureSkipVerify: true})
+	if _, err := without.GetStatus(); err == nil {
+		t.Error("GetStatus() succeeded without a client certificate")
+	}
+
+	with, err := NewWithOptions(server.URL, "", Options{InsecureSkipVerify: true, CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+	if _, err := with.GetStatus(); err != nil {
+		t.Errorf("GetStatus() with client certificate error = %v", err)
+	}
+}
+
+func TestNewWithOptions_Invalid(t *testing.T) {
+	certFile, _, _ := newClientCert(t)
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	os.WriteFile(empty, []byte("not a certificate"), 0600)
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"unknown auth scheme", Options{AuthScheme: "basic"}, "invalid auth scheme"},
+		{"missing CA file", Options{CAFile: "/nonexistent/ca.pem"}, "failed to read CA bundle"},
+		{"CA file without certificates", Options{CAFile: empty}, "no certificates found"},
+		{"certificate without key", Options{CertFile: certFile}, "must be set together"},
+		{"bad proxy scheme", Options{Proxy: "ftp://proxy:21"}, "unsupported proxy scheme"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewWithOptions("http://example.com", "", tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewWithOptions() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBearerAuth(t *testing.T) {
+	var header, apiKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header, apiKey = r.Header.Get("Authorization"), r.Header.Get("X-API-Key")
+		json.NewEncoder(w).Encode(MeResponse{User: User{ID: "u1"}})
+	}))
+	defer server.Close()
+
+	client, err := NewWithOptions(server.URL, "secret", Options{AuthScheme: AuthBearer})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+	if _, err := client.GetMe(); err != nil {
+		t.Fatalf("GetMe() error = %v", err)
+	}
+	if header != "Bearer secret" || apiKey != "" {
+		t.Errorf("Authorization = %q, X-API-Key = %q, want bearer token only", header, apiKey)
+	}
+}
+
+func TestProxy(t *testing.T) {
+	var target string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target = r.URL.String()
+		statusHandler(w, r)
+	}))
+	defer proxy.Close()
+
+	client, err := NewWithOptions("http://manuals.internal", "", Options{Proxy: proxy.URL})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+	if _, err := client.GetStatus(); err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+	if want := "http://manuals.internal/api/" + APIVersion + "/status"; target != want {
+		t.Errorf("proxy received %q, want %q", target, want)
+	}
+}
+
+func TestPerRequestTimeouts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		if strings.Contains(r.URL.Path, "/search") {
+			json.NewEncoder(w).Encode(SearchResponse{})
+			return
+		}
+		statusHandler(w, r)
+	}))
+	defer server.Close()
+
+	client, err := NewWithOptions(server.URL, "", Options{Timeouts: Timeouts{Search: 20 * time.Millisecond}})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+	if _, err := client.Search("esp32", 1, "", ""); err == nil {
+		t.Error("Search() should exceed the search timeout")
+	}
+	if _, err := client.GetStatus(); err != nil {
+		t.Errorf("GetStatus() should use the default timeout, error = %v", err)
+	}
+}
+
+func TestObserver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "not found"})
+	}))
+	defer server.Close()
+
+	var method, route string
+	var status int
+	client, err := NewWithOptions(server.URL, "", Options{Observer: func(m, r string, s int, _ time.Duration) {
+		method, route, status = m, r, s
+	}})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+	client.GetDevicePinout("esp32")
+	if method != http.MethodGet || route != "/devices/{id}/pinout" || status != http.StatusNotFound {
+		t.Errorf("observed %s %s %d, want GET /devices/{id}/pinout 404", method, route, status)
+	}
+}
+
+func TestRoute(t *testing.T) {
+	tests := map[string]string{
+		"/api/2025.12/search":                    "/search",
+		"/api/versions":                          "/versions",
+		"/api/2025.12/devices":                   "/devices",
+		"/api/2025.12/devices/esp32":             "/devices/{id}",
+		"/api/2025.12/devices/esp32/specs":       "/devices/{id}/specs",
+		"/api/2025.12/documents/d1/download":     "/documents/{id}/download",
+		"/api/2025.12/admin/users/u1/rotate-key": "/admin/users/{id}/rotate-key",
+		"/api/2025.12/admin/settings/schema":     "/admin/settings/schema",
+		"/api/2025.12/admin/settings/sync.mode":  "/admin/settings/{key}",
+	}
+	for in, want := range tests {
+		if got := Route(in); got != want {
+			t.Errorf("Route(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		statusHandler(w, r)
+	}))
+	defer server.Close()
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "tool")
+	if _, err := New(server.URL, "").WithContext(ctx).GetStatus(); err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+	parent.End()
+
+	traceID := parent.SpanContext().TraceID().String()
+	if !strings.Contains(traceparent, traceID) {
+		t.Errorf("traceparent = %q, want trace %s", traceparent, traceID)
+	}
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	if spans[0].Name() != "GET /status" || spans[0].Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("span %q has parent %s, want GET /status under the tool span", spans[0].Name(), spans[0].Parent().SpanID())
+	}
+}
+
+func TestRequestLogging(t *testing.T) {
+	var requestID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID = r.Header.Get(RequestIDHeader)
+		statusHandler(w, r)
+	}))
+	defer server.Close()
+
+	var logs strings.Builder
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client, err := NewWithOptions(server.URL, "secret-key", Options{Logger: logger})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+
+	ctx := WithRequestID(context.Background(), "call-1")
+	if _, err := client.WithContext(ctx).GetStatus(); err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+	if requestID != "call-1" {
+		t.Errorf("X-Request-ID = %q, want call-1", requestID)
+	}
+	for _, want := range []string{"request_id=call-1", "method=GET", "path=/api/" + APIVersion + "/status", "status=200", "bytes_received="} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("log missing %q:\n%s", want, logs.String())
+		}
+	}
+	if strings.Contains(logs.String(), "secret-key") {
+		t.Errorf("log contains the API key:\n%s", logs.String())
+	}
+
+	// Requests outside a tool call get their own ID
+	if _, err := client.GetStatus(); err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+	if requestID == "" || requestID == "call-1" {
+		t.Errorf("X-Request-ID = %q, want a new ID", requestID)
+	}
+}
@@ -0,0 +1,233 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+)
+
+// SupportedVersions lists the API versions the client can speak, newest
+// first. Versions are "YYYY.MM" strings, so they sort lexically.
+//
+// The client implements a single payload shape. Older servers are handled
+// by feature gating (see optionalFeatures), not by per-version payload
+// adapters, so a version belongs here only once its payloads are handled.
+var SupportedVersions = []string{APIVersion}
+
+// feature is an optional part of the API that a server may lack.
+type feature struct {
+	// Name is the feature name used in VersionsResponse.Features.
+	Name string
+	// Description names the feature in errors and warnings.
+	Description string
+	// Routes are the path patterns (path.Match syntax, without the query
+	// string) the feature provides.
+	Routes []string
+}
+
+// optionalFeatures lists the parts of the API a server reports per version
+// in /api/versions. Everything else is assumed to exist in every version.
+var optionalFeatures = []feature{
+	{"settings_schema", "setting validation", []string{"/admin/settings/schema"}},
+	{"user_active", "user deactivation", []string{"/admin/users/*/active"}},
+	{"user_rename", "user rename", []string{"/admin/users/*/name"}},
+	{"user_capabilities", "fine-grained capabilities", []string{"/admin/users/*/capabilities"}},
+	{"doc_history", "document history", []string{"/docs/history", "/docs/diff"}},
+}
+
+// ErrIncompatible is returned by Negotiate when the client and server have
+// no API version in common.
+var ErrIncompatible = errors.New("no mutually supported API version")
+
+// UnsupportedError is returned for requests the negotiated API version
+// cannot serve.
+type UnsupportedError struct {
+	Feature string
+	Version string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("%s is not available in API version %s on this server", e.Feature, e.Version)
+}
+
+// VersionsResponse is the response from the version discovery endpoint.
+type VersionsResponse struct {
+	Versions []string `json:"versions"`
+	Current  string   `json:"current"`
+	// Features lists the optional features each version provides. Servers
+	// that omit a version here are assumed to provide all of them.
+	Features map[string][]string `json:"features,omitempty"`
+}
+
+// Negotiation is the outcome of Negotiate.
+type Negotiation struct {
+	// Version is the API version the client now uses.
+	Version string `json:"version"`
+	// ServerVersions are the versions the server offered. Empty when the
+	// server predates version discovery.
+	ServerVersions []string `json:"server_versions,omitempty"`
+	// Newer is the newest server version this client does not know, if any.
+	Newer string `json:"newer,omitempty"`
+	// Missing lists features the server reports as unavailable in Version.
+	Missing []string `json:"missing,omitempty"`
+
+	// missing maps the routes of Missing to their feature description.
+	missing map[string]string
+}
+
+// Version returns the API version used for requests.
+func (c *Client) Version() string {
+	return c.version
+}
+
+// SetVersion pins the API version instead of negotiating it. The server
+// is not contacted, so no features are known to be missing; use Pin to
+// check the version against the server.
+func (c *Client) SetVersion(version string) error {
+	if !slices.Contains(SupportedVersions, version) {
+		return fmt.Errorf("unsupported API version %q (supported: %s)", version, strings.Join(SupportedVersions, ", "))
+	}
+	c.version = version
+	c.missing = nil
+	return nil
+}
+
+// Pin pins the API version like SetVersion and checks that the server
+// offers it, recording the features it lacks. It returns an error wrapping
+// ErrIncompatible if the server does not serve version.
+func (c *Client) Pin(version string) (*Negotiation, error) {
+	if err := c.SetVersion(version); err != nil {
+		return nil, err
+	}
+	n, err := c.discover(func(resp VersionsResponse) (*Negotiation, error) {
+		offered := offeredVersions(resp)
+		if !slices.Contains(offered, version) {
+			return nil, fmt.Errorf("%w: API version %s is pinned but the server offers %s",
+				ErrIncompatible, version, strings.Join(offered, ", "))
+		}
+		return &Negotiation{Version: version, ServerVersions: offered}, nil
+	}, []string{version})
+	if err != nil {
+		return nil, err
+	}
+	c.missing = n.missing
+	return n, nil
+}
+
+// Negotiate discovers the API versions the server supports and switches
+// the client to the newest one both sides support. Servers without the
+// discovery endpoint are probed with each supported version, newest first.
+// It returns an error wrapping ErrIncompatible if no version matches.
+func (c *Client) Negotiate() (*Negotiation, error) {
+	n, err := c.discover(negotiate, SupportedVersions)
+	if err != nil {
+		return nil, err
+	}
+	c.version = n.Version
+	c.missing = n.missing
+	return n, nil
+}
+
+// discover fetches the server's versions and passes them to choose. Servers
+// without the discovery endpoint are probed with candidates instead, and
+// report no missing features.
+func (c *Client) discover(choose func(VersionsResponse) (*Negotiation, error), candidates []string) (*Negotiation, error) {
+	var resp VersionsResponse
+	err := c.getURL(c.baseURL+"/api/versions", &resp)
+
+	var apiErr *APIError
+	switch {
+	case err == nil:
+		n, err := choose(resp)
+		if err != nil {
+			return nil, err
+		}
+		if features, ok := resp.Features[n.Version]; ok {
+			n.Missing, n.missing = missingFeatures(features)
+		}
+		return n, nil
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+		return c.probe(candidates)
+	}
+	return nil, err
+}
+
+// missingFeatures returns the optional features absent from provided, as
+// sorted descriptions and as a route lookup for endpoint.
+func missingFeatures(provided []string) ([]string, map[string]string) {
+	var names []string
+	routes := make(map[string]string)
+	for _, f := range optionalFeatures {
+		if slices.Contains(provided, f.Name) {
+			continue
+		}
+		names = append(names, f.Description)
+		for _, r := range f.Routes {
+			routes[r] = f.Description
+		}
+	}
+	slices.Sort(names)
+	return names, routes
+}
+
+// offeredVersions returns the versions in resp, falling back to Current
+// for servers that list only one.
+func offeredVersions(resp VersionsResponse) []string {
+	if len(resp.Versions) == 0 && resp.Current != "" {
+		return []string{resp.Current}
+	}
+	return resp.Versions
+}
+
+// negotiate picks the newest version in both resp and SupportedVersions.
+func negotiate(resp VersionsResponse) (*Negotiation, error) {
+	offered := offeredVersions(resp)
+
+	n := &Negotiation{ServerVersions: offered}
+	for _, v := range offered {
+		if v > SupportedVersions[0] && v > n.Newer {
+			n.Newer = v
+		}
+	}
+	for _, v := range SupportedVersions {
+		if slices.Contains(offered, v) {
+			n.Version = v
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: server offers %s, client supports %s",
+		ErrIncompatible, strings.Join(offered, ", "), strings.Join(SupportedVersions, ", "))
+}
+
+// probe finds the first of candidates whose status endpoint answers, for
+// servers that predate version discovery.
+func (c *Client) probe(candidates []string) (*Negotiation, error) {
+	for _, v := range candidates {
+		var status StatusResponse
+		err := c.getURL(c.baseURL+"/api/"+v+"/status", &status)
+		if err == nil {
+			return &Negotiation{Version: v}, nil
+		}
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("%w: server answers none of %s", ErrIncompatible, strings.Join(candidates, ", "))
+}
+
+// endpoint returns the absolute URL of an API path for the negotiated
+// version, or an UnsupportedError if the server reported that version
+// lacks it.
+func (c *Client) endpoint(p string) (string, error) {
+	route, _, _ := strings.Cut(p, "?")
+	for pattern, feature := range c.missing {
+		if ok, _ := path.Match(pattern, route); ok {
+			return "", &UnsupportedError{Feature: feature, Version: c.version}
+		}
+	}
+	return c.baseURL + "/api/" + c.version + p, nil
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/mcp"
+	"github.com/rmrfslashbin/manuals-mcp/internal/secrets"
+	"github.com/spf13/viper"
+)
+
+// backendConfig is one entry of the "backends" list in the config file.
+type backendConfig struct {
+	Name       string `mapstructure:"name"`
+	URL        string `mapstructure:"url"`
+	Key        string `mapstructure:"key"`
+	KeyFile    string `mapstructure:"key_file"`
+	KeyCommand string `mapstructure:"key_command"`
+	KeyKeyring string `mapstructure:"key_keyring"`
+}
+
+// newBackends connects to the backends listed under "backends" in the
+// config file, or to the single api.url backend when none are listed.
+// Transport settings (api.tls, api.proxy, api.timeout, ...) apply to all.
+func newBackends() ([]mcp.Backend, error) {
+	var configs []backendConfig
+	if err := viper.UnmarshalKey("backends", &configs); err != nil {
+		return nil, fmt.Errorf("invalid backends config: %w", err)
+	}
+	if len(configs) == 0 {
+		c, err := newAPIClient()
+		if err != nil {
+			return nil, err
+		}
+		return []mcp.Backend{{Name: "default", Client: c}}, nil
+	}
+
+	seen := make(map[string]bool)
+	backends := make([]mcp.Backend, 0, len(configs))
+	for i, bc := range configs {
+		switch {
+		case bc.Name == "":
+			return nil, fmt.Errorf("backends[%d]: name is required", i)
+		case strings.ContainsAny(bc.Name, ": "):
+			return nil, fmt.Errorf("backend %q: name may not contain spaces or \":\"", bc.Name)
+		case seen[bc.Name]:
+			return nil, fmt.Errorf("backend %q is configured twice", bc.Name)
+		case bc.URL == "":
+			return nil, fmt.Errorf("backend %q: url is required", bc.Name)
+		}
+		seen[bc.Name] = true
+
+		key, err := resolveAPIKey(bc.Key, secrets.Ref{File: bc.KeyFile, Command: bc.KeyCommand, Keyring: bc.KeyKeyring})
+		if err != nil {
+			return nil, fmt.Errorf("backend %q: %w", bc.Name, err)
+		}
+		c, err := connectAPIClient(bc.URL, key)
+		if err != nil {
+			return nil, fmt.Errorf("backend %q: %w", bc.Name, err)
+		}
+		backends = append(backends, mcp.Backend{Name: bc.Name, Client: c})
+	}
+	return backends, nil
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rmrfslashbin/manuals-mcp/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+// browseCmd starts the interactive terminal browser.
+var browseCmd = &cobra.Command{
+	Use:   "browse",
+	Short: "Browse the manuals library in an interactive terminal UI",
+	Long: `Browse devices page by page, filter by domain and type, search, and read each
+device's README, pinout, specifications, and references without an MCP client.
+
+Keys: / search, d cycle domain, t filter by type, n/p next/previous page,
+enter open, tab or 1-4 switch section, esc back, q quit.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+		pageSize, _ := cmd.Flags().GetInt("page-size")
+		style, _ := cmd.Flags().GetString("style")
+
+		m := tui.New(c, tui.Options{PageSize: pageSize, Style: style})
+		_, err = tea.NewProgram(m, tea.WithAltScreen(), tea.WithContext(cmd.Context())).Run()
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(browseCmd)
+	browseCmd.Flags().Int("page-size", tui.DefaultPageSize, "devices per page")
+	browseCmd.Flags().String("style", "auto", "README style (auto, dark, light, notty)")
+}
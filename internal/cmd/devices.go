@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// searchCmd searches the documentation.
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search documentation",
+	Long:  `Search devices and documentation using full-text search, or semantic search with --semantic.`,
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+		query := strings.Join(args, " ")
+		limit, _ := cmd.Flags().GetInt("limit")
+		domain, _ := cmd.Flags().GetString("domain")
+		deviceType, _ := cmd.Flags().GetString("type")
+
+		if semantic, _ := cmd.Flags().GetBool("semantic"); semantic {
+			resp, err := c.SemanticSearch(query, limit, domain, deviceType)
+			if err != nil {
+				return err
+			}
+			return printResult(cmd, resp, func(w io.Writer) {
+				fmt.Fprintln(w, "SCORE\tDEVICE\tNAME\tHEADING")
+				for _, r := range resp.Results {
+					fmt.Fprintf(w, "%.3f\t%s\t%s\t%s\n", r.Score, r.DeviceID, r.Name, orDash(r.Heading))
+				}
+			})
+		}
+
+		resp, err := c.Search(query, limit, domain, deviceType)
+		if err != nil {
+			return err
+		}
+		return printResult(cmd, resp, func(w io.Writer) {
+			fmt.Fprintln(w, "SCORE\tDEVICE\tNAME\tDOMAIN\tTYPE\tPATH")
+			for _, r := range resp.Results {
+				fmt.Fprintf(w, "%.2f\t%s\t%s\t%s\t%s\t%s\n", r.Score, r.DeviceID, r.Name, r.Domain, r.Type, r.Path)
+			}
+		})
+	},
+}
+
+// deviceCmd groups device subcommands.
+var deviceCmd = &cobra.Command{
+	Use:   "device",
+	Short: "List and inspect devices",
+}
+
+// deviceGetCmd shows a single device.
+var deviceGetCmd = &cobra.Command{
+	Use:   "get <device-id>",
+	Short: "Show device details",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+		content, _ := cmd.Flags().GetBool("content")
+		device, err := c.GetDevice(args[0], content)
+		if err != nil {
+			return err
+		}
+		return printResult(cmd, device, func(w io.Writer) {
+			fmt.Fprintf(w, "ID:\t%s\n", device.ID)
+			fmt.Fprintf(w, "Name:\t%s\n", device.Name)
+			fmt.Fprintf(w, "Domain:\t%s\n", device.Domain)
+			fmt.Fprintf(w, "Type:\t%s\n", device.Type)
+			fmt.Fprintf(w, "Path:\t%s\n", device.Path)
+			fmt.Fprintf(w, "Indexed:\t%s\n", device.IndexedAt)
+			if device.Content != "" {
+				fmt.Fprintf(w, "\n%s\n", device.Content)
+			}
+		})
+	},
+}
+
+// deviceListCmd lists devices.
+var deviceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List devices",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+		limit, _ := cmd.Flags().GetInt("limit")
+		offset, _ := cmd.Flags().GetInt("offset")
+		domain, _ := cmd.Flags().GetString("domain")
+		deviceType, _ := cmd.Flags().GetString("type")
+
+		resp, err := c.ListDevices(limit, offset, domain, deviceType)
+		if err != nil {
+			return err
+		}
+		return printResult(cmd, resp, func(w io.Writer) {
+			fmt.Fprintln(w, "ID\tNAME\tDOMAIN\tTYPE\tPATH")
+			for _, d := range resp.Data {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", d.ID, d.Name, d.Domain, d.Type, d.Path)
+			}
+			fmt.Fprintf(w, "\nShowing %d-%d of %d\n", resp.Offset+1, resp.Offset+len(resp.Data), resp.Total)
+		})
+	},
+}
+
+// pinoutCmd shows a device pinout.
+var pinoutCmd = &cobra.Command{
+	Use:   "pinout <device-id>",
+	Short: "Show a device's GPIO pinout",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+		resp, err := c.GetDevicePinout(args[0])
+		if err != nil {
+			return err
+		}
+		return printResult(cmd, resp, func(w io.Writer) {
+			fmt.Fprintln(w, "PIN\tGPIO\tNAME\tPULL\tALT FUNCTIONS")
+			for _, p := range resp.Pins {
+				gpio := "-"
+				if p.GPIONum != nil {
+					gpio = fmt.Sprintf("%d", *p.GPIONum)
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", p.PhysicalPin, gpio, p.Name, orDash(p.DefaultPull), orDash(strings.Join(p.AltFunctions, ", ")))
+			}
+		})
+	},
+}
+
+// specsCmd shows device specifications.
+var specsCmd = &cobra.Command{
+	Use:   "specs <device-id>",
+	Short: "Show a device's specifications",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+		resp, err := c.GetDeviceSpecs(args[0])
+		if err != nil {
+			return err
+		}
+		return printResult(cmd, resp, func(w io.Writer) {
+			fmt.Fprintln(w, "SPEC\tVALUE")
+			for _, k := range slices.Sorted(maps.Keys(resp.Specs)) {
+				fmt.Fprintf(w, "%s\t%s\n", k, resp.Specs[k])
+			}
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd, deviceCmd, pinoutCmd, specsCmd)
+	deviceCmd.AddCommand(deviceGetCmd, deviceListCmd)
+
+	for _, c := range []*cobra.Command{searchCmd, deviceCmd, pinoutCmd, specsCmd} {
+		addOutputFlag(c)
+	}
+
+	searchCmd.Flags().Int("limit", 10, "maximum results")
+	searchCmd.Flags().String("domain", "", "filter by domain (hardware, software, protocol)")
+	searchCmd.Flags().String("type", "", "filter by device type")
+	searchCmd.Flags().Bool("semantic", false, "use semantic (embedding) search")
+
+	deviceGetCmd.Flags().Bool("content", false, "include the full documentation content")
+
+	deviceListCmd.Flags().Int("limit", 50, "maximum devices")
+	deviceListCmd.Flags().Int("offset", 0, "pagination offset")
+	deviceListCmd.Flags().String("domain", "", "filter by domain")
+	deviceListCmd.Flags().String("type", "", "filter by device type")
+}
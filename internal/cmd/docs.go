@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+	"github.com/spf13/cobra"
+)
+
+// docsCmd groups document subcommands.
+var docsCmd = &cobra.Command{
+	Use:   "docs",
+	Short: "List and download documents",
+}
+
+// docsListCmd lists documents.
+var docsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List documents",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+		limit, _ := cmd.Flags().GetInt("limit")
+		offset, _ := cmd.Flags().GetInt("offset")
+		deviceID, _ := cmd.Flags().GetString("device")
+
+		resp, err := c.ListDocuments(limit, offset, deviceID)
+		if err != nil {
+			return err
+		}
+		return printResult(cmd, resp, func(w io.Writer) {
+			fmt.Fprintln(w, "ID\tDEVICE\tFILENAME\tTYPE\tSIZE\tPATH")
+			for _, d := range resp.Data {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", d.ID, d.DeviceID, d.Filename, d.MimeType, d.SizeBytes, d.Path)
+			}
+			fmt.Fprintf(w, "\nShowing %d-%d of %d\n", resp.Offset+1, resp.Offset+len(resp.Data), resp.Total)
+		})
+	},
+}
+
+// docsDownloadCmd downloads a document.
+var docsDownloadCmd = &cobra.Command{
+	Use:   "download <document-id>",
+	Short: "Download a document",
+	Long:  `Download a document's content. By default it is saved under its original filename in the current directory, refusing to overwrite an existing file unless --force is given; use --out - to write to stdout.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+
+		out, _ := cmd.Flags().GetString("out")
+		if out == "" {
+			doc, err := c.GetDocument(args[0])
+			if err != nil {
+				return err
+			}
+			out = filepath.Base(doc.Filename)
+			// The name comes from the server, so never replace a local file
+			// with it unless asked to
+			if force, _ := cmd.Flags().GetBool("force"); !force {
+				if _, err := os.Lstat(out); err == nil {
+					return fmt.Errorf("%s already exists (use --force to overwrite it or --out to choose another file)", out)
+				}
+			}
+		}
+
+		data, _, err := c.DownloadDocument(args[0])
+		if err != nil {
+			return err
+		}
+		return writeOutputFile(cmd, out, data)
+	},
+}
+
+// publishCmd uploads a file and triggers a reindex.
+var publishCmd = &cobra.Command{
+	Use:   "publish <local-file> <dest-path>",
+	Short: "Upload a file and reindex",
+	Long: `Upload a local file to documentation storage and trigger a reindex so it becomes
+searchable. Use --no-reindex to only upload, and --wait to block until the reindex finishes.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", args[0], err)
+		}
+
+		upload, err := c.UploadFile(args[1], filepath.Base(args[0]), data)
+		if err != nil {
+			return fmt.Errorf("failed to upload file: %w", err)
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Uploaded %s (%d bytes)\n", upload.Path, upload.Size)
+
+		result := struct {
+			Upload  *client.UploadResponse        `json:"upload"`
+			Reindex *client.ReindexStatusResponse `json:"reindex,omitempty"`
+		}{Upload: upload}
+
+		if noReindex, _ := cmd.Flags().GetBool("no-reindex"); !noReindex {
+			if result.Reindex, err = runReindex(cmd, c); err != nil {
+				return err
+			}
+		}
+
+		return printResult(cmd, result, func(w io.Writer) {
+			fmt.Fprintf(w, "Path:\t%s\n", upload.Path)
+			fmt.Fprintf(w, "Size:\t%d bytes\n", upload.Size)
+			if result.Reindex != nil {
+				writeReindexStatus(w, result.Reindex)
+			}
+		})
+	},
+}
+
+// reindexCmd triggers a reindex.
+var reindexCmd = &cobra.Command{
+	Use:   "reindex",
+	Short: "Trigger a documentation reindex",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+		status, err := runReindex(cmd, c)
+		if err != nil {
+			return err
+		}
+		return printResult(cmd, status, func(w io.Writer) {
+			writeReindexStatus(w, status)
+		})
+	},
+}
+
+// reindexStatusCmd shows the reindex status.
+var reindexStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show reindex status",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+		status, err := c.GetReindexStatus()
+		if err != nil {
+			return err
+		}
+		return printResult(cmd, status, func(w io.Writer) {
+			writeReindexStatus(w, status)
+		})
+	},
+}
+
+// reindexPollInterval is the delay between reindex status checks.
+const reindexPollInterval = 2 * time.Second
+
+// runReindex triggers a reindex and, with --wait, polls until it finishes
+// or --timeout elapses.
+func runReindex(cmd *cobra.Command, c *client.Client) (*client.ReindexStatusResponse, error) {
+	// The previous run's completion time tells the triggered run apart
+	// from it; without one, any reported completion counts.
+	var previous string
+	if before, err := c.GetReindexStatus(); err == nil && before != nil {
+		previous = before.LastCompleted
+	}
+	if _, err := c.TriggerReindex(); err != nil {
+		return nil, fmt.Errorf("failed to trigger reindex: %w", err)
+	}
+
+	wait, _ := cmd.Flags().GetBool("wait")
+	if !wait {
+		return c.GetReindexStatus()
+	}
+
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	result, err := client.WaitForReindex(cmd.Context(), client.ReindexWatch{
+		Triggered: true,
+		Previous:  previous,
+		Timeout:   timeout,
+		Interval:  reindexPollInterval,
+		Status:    c.GetReindexStatus,
+	})
+	if err != nil {
+		if cmd.Context().Err() != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get reindex status: %w", err)
+	}
+	if !result.Completed {
+		return result.Status, fmt.Errorf("reindex still running after %s", timeout)
+	}
+	return result.Status, nil
+}
+
+// writeReindexStatus writes reindex status rows for table output.
+func writeReindexStatus(w io.Writer, status *client.ReindexStatusResponse) {
+	fmt.Fprintf(w, "Reindex:\t%s\n", status.Status)
+	if status.Elapsed != "" {
+		fmt.Fprintf(w, "Elapsed:\t%s\n", status.Elapsed)
+	}
+	if status.LastCompleted != "" {
+		fmt.Fprintf(w, "Last Completed:\t%s\n", status.LastCompleted)
+	}
+	if run := status.LastRun; run != nil {
+		fmt.Fprintf(w, "Devices:\t%d\n", run.DevicesIndexed)
+		fmt.Fprintf(w, "Documents:\t%d\n", run.DocumentsIndexed)
+		fmt.Fprintf(w, "Guides:\t%d\n", run.GuidesIndexed)
+		fmt.Fprintf(w, "Errors:\t%d\n", run.Errors)
+		fmt.Fprintf(w, "Duration:\t%s\n", run.Duration)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(docsCmd, publishCmd, reindexCmd)
+	docsCmd.AddCommand(docsListCmd, docsDownloadCmd)
+	reindexCmd.AddCommand(reindexStatusCmd)
+
+	for _, c := range []*cobra.Command{docsCmd, publishCmd, reindexCmd} {
+		addOutputFlag(c)
+	}
+
+	docsListCmd.Flags().Int("limit", 50, "maximum documents")
+	docsListCmd.Flags().Int("offset", 0, "pagination offset")
+	docsListCmd.Flags().String("device", "", "only documents for this device ID")
+
+	docsDownloadCmd.Flags().String("out", "", "output file (default original filename, \"-\" for stdout)")
+	docsDownloadCmd.Flags().Bool("force", false, "overwrite an existing file at the default output path")
+
+	publishCmd.Flags().Bool("no-reindex", false, "upload only, without triggering a reindex")
+	for _, c := range []*cobra.Command{publishCmd, reindexCmd} {
+		c.Flags().Bool("wait", false, "wait for the reindex to finish")
+		c.Flags().Duration("timeout", 60*time.Second, "how long --wait waits for the reindex")
+	}
+}
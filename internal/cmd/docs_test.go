@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+	"github.com/spf13/viper"
+)
+
+func TestDocsDownload_KeepsExistingFile(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/versions":
+			json.NewEncoder(w).Encode(client.VersionsResponse{Versions: []string{client.APIVersion}})
+		case strings.HasSuffix(r.URL.Path, "/download"):
+			w.Write([]byte("new content"))
+		default:
+			json.NewEncoder(w).Encode(client.Document{ID: "doc1", Filename: "../datasheet.pdf"})
+		}
+	}))
+	t.Cleanup(api.Close)
+
+	viper.SetConfigType("yaml")
+	viper.ReadConfig(strings.NewReader("api:\n  url: \"" + api.URL + "\"\n"))
+	t.Cleanup(func() {
+		viper.SetConfigType("yaml")
+		viper.ReadConfig(strings.NewReader(""))
+		docsDownloadCmd.Flags().Set("force", "false")
+		docsDownloadCmd.SetErr(nil)
+	})
+	docsDownloadCmd.SetErr(io.Discard)
+
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("datasheet.pdf", []byte("local"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := docsDownloadCmd.RunE(docsDownloadCmd, []string{"doc1"}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("docs download error = %v, want refusal to overwrite", err)
+	}
+	if data, _ := os.ReadFile("datasheet.pdf"); string(data) != "local" {
+		t.Errorf("datasheet.pdf = %q, want the local file kept", data)
+	}
+
+	docsDownloadCmd.Flags().Set("force", "true")
+	if err := docsDownloadCmd.RunE(docsDownloadCmd, []string{"doc1"}); err != nil {
+		t.Fatalf("docs download --force error = %v", err)
+	}
+	if data, _ := os.ReadFile("datasheet.pdf"); string(data) != "new content" {
+		t.Errorf("datasheet.pdf = %q, want the downloaded content", data)
+	}
+}
@@ -0,0 +1,428 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Doctor check results.
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+// doctorTimeout bounds each network check.
+const doctorTimeout = 5 * time.Second
+
+// doctorCheck is the result of one diagnostic.
+type doctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	Fix    string `json:"fix,omitempty"`
+}
+
+// doctorReport is the full diagnostic result.
+type doctorReport struct {
+	Checks   []doctorCheck `json:"checks"`
+	Failed   int           `json:"failed"`
+	Warnings int           `json:"warnings"`
+}
+
+// add records a check and updates the counters.
+func (r *doctorReport) add(c doctorCheck) {
+	r.Checks = append(r.Checks, c)
+	switch c.Status {
+	case checkFail:
+		r.Failed++
+	case checkWarn:
+		r.Warnings++
+	}
+}
+
+// skipRest records the remaining checks as skipped after a failure.
+func (r *doctorReport) skipRest(names ...string) {
+	for _, name := range names {
+		r.add(doctorCheck{Name: name, Status: checkSkip, Detail: "skipped after earlier failure"})
+	}
+}
+
+// doctorCmd diagnoses configuration and connectivity problems.
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose configuration and API connectivity",
+	Long: `Check where the configuration comes from (flag, environment, .env file, or config
+file), then resolve, connect to, and authenticate with the Manuals API step by step:
+DNS, TCP, TLS, API version, API key, semantic search, and upload permission.
+Each problem is reported with a suggested fix. Exits non-zero if any check fails.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		report := &doctorReport{}
+		apiKey := configChecks(cmd, report)
+
+		if apiURL := viper.GetString("api.url"); apiURL != "" {
+			if c, err := buildAPIClient(apiURL, apiKey); err != nil {
+				report.add(doctorCheck{Name: "client settings", Status: checkFail, Detail: err.Error(),
+					Fix: "check the api.tls, api.proxy, and api.auth_scheme settings"})
+			} else {
+				diagnose(cmd.Context(), c, report)
+			}
+		}
+
+		err := printResult(cmd, report, func(w io.Writer) {
+			fmt.Fprintln(w, "STATUS\tCHECK\tDETAIL")
+			for _, c := range report.Checks {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", strings.ToUpper(c.Status), c.Name, c.Detail)
+			}
+			var fixes []doctorCheck
+			for _, c := range report.Checks {
+				if c.Fix != "" {
+					fixes = append(fixes, c)
+				}
+			}
+			if len(fixes) > 0 {
+				fmt.Fprintln(w, "\nSuggested fixes:")
+				for _, c := range fixes {
+					fmt.Fprintf(w, "  - %s: %s\n", c.Name, c.Fix)
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if report.Failed > 0 {
+			return fmt.Errorf("%d of %d checks failed", report.Failed, len(report.Checks))
+		}
+		return nil
+	},
+}
+
+// configChecks reports the config file, .env files, and where the API URL
+// and key came from. It returns the resolved API key.
+func configChecks(cmd *cobra.Command, report *doctorReport) string {
+	if file := viper.ConfigFileUsed(); file != "" {
+		if _, err := os.Stat(file); err != nil {
+			report.add(doctorCheck{Name: "config file", Status: checkFail, Detail: err.Error(),
+				Fix: "fix the --config path or remove the flag"})
+		} else {
+			report.add(doctorCheck{Name: "config file", Status: checkOK, Detail: file})
+		}
+	} else {
+		report.add(doctorCheck{Name: "config file", Status: checkOK, Detail: "none found ($HOME/.manuals-mcp.yaml, ./.manuals-mcp.yaml)"})
+	}
+
+	if activeProfile != "" {
+		report.add(doctorCheck{Name: "profile", Status: checkOK, Detail: fmt.Sprintf("%s (from %s)", activeProfile, configSource(cmd, "profile", "profile"))})
+	}
+
+	files := map[string]bool{}
+	for _, f := range dotenvSources {
+		files[f] = true
+	}
+	if len(files) > 0 {
+		list := slices.Sorted(maps.Keys(files))
+		report.add(doctorCheck{Name: ".env files", Status: checkOK, Detail: strings.Join(list, ", ")})
+	}
+	if !viper.GetBool("dotenv.cwd") {
+		if _, err := os.Stat(".env"); err == nil {
+			report.add(doctorCheck{Name: ".env in current directory", Status: checkWarn, Detail: "found but not loaded",
+				Fix: "pass --dotenv or set MANUALS_DOTENV_CWD=true to load it"})
+		}
+	}
+
+	if u := viper.GetString("api.url"); u != "" {
+		report.add(doctorCheck{Name: "api url", Status: checkOK, Detail: fmt.Sprintf("%s (from %s)", u, configSource(cmd, "api.url", "api-url"))})
+	} else {
+		report.add(doctorCheck{Name: "api url", Status: checkFail, Detail: "not set",
+			Fix: "set MANUALS_API_URL, api.url in the config file, or pass --api-url"})
+		report.skipRest("url", "dns", "tcp", "tls", "api version", "authentication", "semantic search", "upload permission")
+		return ""
+	}
+
+	ref := apiKeyRef()
+	key, err := resolveAPIKey(viper.GetString("api.key"), ref)
+	switch {
+	case err != nil:
+		report.add(doctorCheck{Name: "api key", Status: checkFail, Detail: err.Error(),
+			Fix: "check api.key_file, api.key_command, or api.key_keyring; run the key command by hand to see its output"})
+	case key == "":
+		report.add(doctorCheck{Name: "api key", Status: checkWarn, Detail: "not set, using anonymous read-only access",
+			Fix: "set MANUALS_API_KEY, or api.key_command in the config file, for write and admin tools"})
+	case !ref.IsZero():
+		var source string
+		for _, k := range []string{"api.key_file", "api.key_command", "api.key_keyring"} {
+			if viper.GetString(k) != "" {
+				source = configSource(cmd, k, "")
+			}
+		}
+		report.add(doctorCheck{Name: "api key", Status: checkOK, Detail: fmt.Sprintf("%s (read from %s, set by %s)", maskKey(key), ref, source)})
+	default:
+		report.add(doctorCheck{Name: "api key", Status: checkOK, Detail: fmt.Sprintf("%s (from %s)", maskKey(key), configSource(cmd, "api.key", "api-key"))})
+	}
+	return key
+}
+
+// configSource reports which source supplied a viper key, in viper's
+// precedence order: flag, environment (or .env file), profile, config file,
+// default.
+func configSource(cmd *cobra.Command, key, flag string) string {
+	if f := cmd.Flags().Lookup(flag); f != nil && f.Changed {
+		return "flag --" + flag
+	}
+	env := "MANUALS_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+	if file, ok := dotenvSources[env]; ok {
+		return fmt.Sprintf("%s in %s", env, file)
+	}
+	if _, ok := os.LookupEnv(env); ok {
+		return "environment " + env
+	}
+	if activeProfile != "" && viper.IsSet("profiles."+activeProfile+"."+key) {
+		return fmt.Sprintf("profile %s in %s", activeProfile, viper.ConfigFileUsed())
+	}
+	if viper.InConfig(key) {
+		return "config file " + viper.ConfigFileUsed()
+	}
+	return "default"
+}
+
+// maskKey hides all but the last four characters of an API key.
+func maskKey(key string) string {
+	if len(key) <= 8 {
+		return "****"
+	}
+	return "****" + key[len(key)-4:]
+}
+
+// diagnose checks the network path to the API and what the configured key
+// may do, stopping at the first failure that makes later checks pointless.
+func diagnose(ctx context.Context, c *client.Client, report *doctorReport) {
+	u, err := url.Parse(c.GetAPIURL())
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		report.add(doctorCheck{Name: "url", Status: checkFail, Detail: fmt.Sprintf("invalid API URL %q", c.GetAPIURL()),
+			Fix: "use the server root, e.g. https://manuals.example.com"})
+		report.skipRest("dns", "tcp", "tls", "api version", "authentication", "semantic search", "upload permission")
+		return
+	}
+	if strings.Contains(u.Path, "/api/") || strings.HasSuffix(u.Path, "/api") {
+		report.add(doctorCheck{Name: "url", Status: checkWarn, Detail: u.String() + " includes an /api path",
+			Fix: "use the server root; the client adds /api/" + client.APIVersion})
+	} else {
+		report.add(doctorCheck{Name: "url", Status: checkOK, Detail: u.String()})
+	}
+
+	host, port := u.Hostname(), u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 4*doctorTimeout)
+	defer cancel()
+
+	if proxy := c.Proxy(); proxy != "" {
+		report.add(doctorCheck{Name: "proxy", Status: checkOK, Detail: proxy + " (dns, tcp, and tls are checked by the proxy)"})
+		apiChecks(c, report)
+		return
+	}
+
+	if net.ParseIP(host) != nil {
+		report.add(doctorCheck{Name: "dns", Status: checkOK, Detail: "IP address, no lookup needed"})
+	} else {
+		lookupCtx, cancelLookup := context.WithTimeout(ctx, doctorTimeout)
+		addrs, err := net.DefaultResolver.LookupHost(lookupCtx, host)
+		cancelLookup()
+		if err != nil {
+			report.add(doctorCheck{Name: "dns", Status: checkFail, Detail: err.Error(),
+				Fix: fmt.Sprintf("check the hostname %q and your DNS or VPN connection", host)})
+			report.skipRest("tcp", "tls", "api version", "authentication", "semantic search", "upload permission")
+			return
+		}
+		report.add(doctorCheck{Name: "dns", Status: checkOK, Detail: fmt.Sprintf("%s -> %s", host, strings.Join(addrs, ", "))})
+	}
+
+	addr := net.JoinHostPort(host, port)
+	dialer := &net.Dialer{Timeout: doctorTimeout}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		report.add(doctorCheck{Name: "tcp", Status: checkFail, Detail: err.Error(),
+			Fix: fmt.Sprintf("make sure the API is running and %s is reachable (firewall, proxy, port)", addr)})
+		report.skipRest("tls", "api version", "authentication", "semantic search", "upload permission")
+		return
+	}
+	conn.Close()
+	report.add(doctorCheck{Name: "tcp", Status: checkOK, Detail: fmt.Sprintf("connected to %s in %s", addr, time.Since(start).Round(time.Millisecond))})
+
+	if u.Scheme == "https" {
+		if !tlsCheck(ctx, c, dialer, addr, host, report) {
+			report.skipRest("api version", "authentication", "semantic search", "upload permission")
+			return
+		}
+	} else if c.HasAPIKey() && !isLoopback(host) {
+		report.add(doctorCheck{Name: "tls", Status: checkWarn, Detail: "plain HTTP, the API key is sent unencrypted",
+			Fix: "use an https:// API URL"})
+	} else {
+		report.add(doctorCheck{Name: "tls", Status: checkSkip, Detail: "plain HTTP"})
+	}
+
+	apiChecks(c, report)
+}
+
+// apiChecks negotiates the API version, or checks the one api.version
+// pins, authenticates, and checks what the configured key may do.
+func apiChecks(c *client.Client, report *doctorReport) {
+	pinned := viper.GetString("api.version")
+	var n *client.Negotiation
+	var err error
+	if pinned != "" {
+		n, err = c.Pin(pinned)
+	} else {
+		n, err = c.Negotiate()
+	}
+	if err != nil {
+		fix := "check the API server logs"
+		var apiErr *client.APIError
+		switch {
+		case errors.Is(err, client.ErrIncompatible) && pinned != "":
+			fix = "pin a version the server offers with --api-version, or unset api.version to negotiate"
+		case errors.Is(err, client.ErrIncompatible):
+			fix = "upgrade manuals-mcp or the Manuals API server so they share a version"
+		case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+			fix = "the URL does not serve the Manuals API; use the server root without a path"
+		}
+		report.add(doctorCheck{Name: "api version", Status: checkFail, Detail: err.Error(), Fix: fix})
+		report.skipRest("authentication", "semantic search", "upload permission")
+		return
+	}
+	detail := n.Version
+	if pinned != "" {
+		detail += " (pinned)"
+	}
+	if len(n.ServerVersions) > 0 {
+		detail += fmt.Sprintf(" (server offers %s)", strings.Join(n.ServerVersions, ", "))
+	}
+	if status, err := c.GetStatus(); err == nil {
+		detail += fmt.Sprintf(", server %s, %d devices, %d documents", status.Version, status.Counts.Devices, status.Counts.Documents)
+	}
+	switch {
+	case len(n.Missing) > 0:
+		report.add(doctorCheck{Name: "api version", Status: checkWarn, Detail: detail + "; unavailable: " + strings.Join(n.Missing, ", "),
+			Fix: "upgrade the Manuals API server to enable them"})
+	case n.Newer != "":
+		report.add(doctorCheck{Name: "api version", Status: checkWarn, Detail: detail,
+			Fix: fmt.Sprintf("upgrade manuals-mcp to use API version %s", n.Newer)})
+	default:
+		report.add(doctorCheck{Name: "api version", Status: checkOK, Detail: detail})
+	}
+
+	user := authCheck(c, report)
+
+	if _, err := c.SemanticSearch("doctor", 1, "", ""); err != nil {
+		report.add(doctorCheck{Name: "semantic search", Status: checkWarn, Detail: err.Error(),
+			Fix: "enable embeddings on the API server; full-text search still works"})
+	} else {
+		report.add(doctorCheck{Name: "semantic search", Status: checkOK, Detail: "available"})
+	}
+
+	switch {
+	case user == nil:
+		report.add(doctorCheck{Name: "upload permission", Status: checkSkip, Detail: "not authenticated"})
+	case user.CanWrite():
+		report.add(doctorCheck{Name: "upload permission", Status: checkOK, Detail: "publish and upload allowed"})
+	default:
+		report.add(doctorCheck{Name: "upload permission", Status: checkWarn, Detail: fmt.Sprintf("role %s cannot publish or upload", user.Role()),
+			Fix: fmt.Sprintf("ask an admin to run: manuals-mcp users role %s rw", user.ID)})
+	}
+}
+
+// tlsCheck performs a TLS handshake and reports the negotiated version and
+// certificate expiry. It returns false if the handshake failed.
+func tlsCheck(ctx context.Context, c *client.Client, dialer *net.Dialer, addr, host string, report *doctorReport) bool {
+	cfg := c.TLSConfig()
+	cfg.ServerName = host
+	td := &tls.Dialer{NetDialer: dialer, Config: cfg}
+	conn, err := td.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		fix := "if the server uses a private CA, set api.tls.ca_file (--ca-file); check the certificate matches " + host
+		if strings.Contains(err.Error(), "certificate required") || strings.Contains(err.Error(), "bad certificate") {
+			fix = "the server requires a client certificate; set api.tls.cert_file and api.tls.key_file"
+		}
+		report.add(doctorCheck{Name: "tls", Status: checkFail, Detail: err.Error(), Fix: fix})
+		return false
+	}
+	defer conn.Close()
+
+	state := conn.(*tls.Conn).ConnectionState()
+	cert := state.PeerCertificates[0]
+	left := time.Until(cert.NotAfter)
+	detail := fmt.Sprintf("%s, certificate for %s expires %s", tls.VersionName(state.Version), cert.Subject.CommonName, cert.NotAfter.Format("2006-01-02"))
+	switch {
+	case cfg.InsecureSkipVerify:
+		report.add(doctorCheck{Name: "tls", Status: checkWarn, Detail: detail + ", not verified",
+			Fix: "set api.tls.ca_file instead of api.tls.insecure_skip_verify outside lab hosts"})
+	case left < 14*24*time.Hour:
+		report.add(doctorCheck{Name: "tls", Status: checkWarn, Detail: fmt.Sprintf("%s (in %d days)", detail, int(left.Hours()/24)),
+			Fix: "renew the server certificate"})
+	default:
+		report.add(doctorCheck{Name: "tls", Status: checkOK, Detail: detail})
+	}
+	return true
+}
+
+// authCheck authenticates with the API key and reports the user, or nil in
+// anonymous mode or on failure.
+func authCheck(c *client.Client, report *doctorReport) *client.User {
+	if !c.HasAPIKey() {
+		report.add(doctorCheck{Name: "authentication", Status: checkSkip, Detail: "no API key, anonymous read-only access"})
+		return nil
+	}
+	user, err := c.GetMe()
+	if err != nil {
+		report.add(doctorCheck{Name: "authentication", Status: checkFail, Detail: err.Error(),
+			Fix: "the API key is invalid, expired, or deactivated; ask an admin to rotate it (manuals-mcp users rotate-key)"})
+		return nil
+	}
+
+	detail := fmt.Sprintf("%s (%s, role %s)", user.Name, user.ID, user.Role())
+	if exp, err := time.Parse(time.RFC3339, user.KeyExpiresAt); err == nil && time.Until(exp) < 7*24*time.Hour {
+		report.add(doctorCheck{Name: "authentication", Status: checkWarn, Detail: fmt.Sprintf("%s, key expires %s", detail, exp.Format("2006-01-02")),
+			Fix: "ask an admin to rotate the API key before it expires"})
+		return user
+	}
+	report.add(doctorCheck{Name: "authentication", Status: checkOK, Detail: detail})
+	return user
+}
+
+// isLoopback reports whether host is localhost or a loopback address.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	addOutputFlag(doctorCmd)
+}
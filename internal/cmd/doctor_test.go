@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+	"github.com/spf13/viper"
+)
+
+// doctorServer fakes the API endpoints doctor calls.
+func doctorServer(t *testing.T, apiVersion string, caps []string) *httptest.Server {
+	t.Helper()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/versions" {
+			json.NewEncoder(w).Encode(client.VersionsResponse{Versions: []string{apiVersion}})
+			return
+		}
+		switch strings.TrimPrefix(r.URL.Path, "/api/"+client.APIVersion) {
+		case "/status":
+			json.NewEncoder(w).Encode(client.StatusResponse{Status: "ok", APIVersion: apiVersion})
+		case "/me":
+			if r.Header.Get("X-API-Key") != "good-key" {
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(client.ErrorResponse{Error: "invalid API key"})
+				return
+			}
+			json.NewEncoder(w).Encode(client.MeResponse{User: client.User{ID: "u1", Name: "ci", Capabilities: caps}})
+		case "/search/semantic":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(client.ErrorResponse{Error: "embeddings not configured"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(api.Close)
+	return api
+}
+
+// checkStatus returns the status of the named check.
+func checkStatus(t *testing.T, r *doctorReport, name string) doctorCheck {
+	t.Helper()
+	for _, c := range r.Checks {
+		if c.Name == name {
+			return c
+		}
+	}
+	t.Fatalf("no %q check in %+v", name, r.Checks)
+	return doctorCheck{}
+}
+
+func TestDiagnose(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiVersion string
+		key        string
+		caps       []string
+		want       map[string]string
+	}{
+		{
+			name:       "healthy read-only key",
+			apiVersion: client.APIVersion,
+			key:        "good-key",
+			caps:       []string{"read:*"},
+			want: map[string]string{
+				"dns": checkOK, "tcp": checkOK, "tls": checkSkip, "api version": checkOK,
+				"authentication": checkOK, "semantic search": checkWarn, "upload permission": checkWarn,
+			},
+		},
+		{
+			name:       "incompatible version",
+			apiVersion: "2024.01",
+			key:        "good-key",
+			want: map[string]string{
+				"api version": checkFail, "authentication": checkSkip,
+			},
+		},
+		{
+			name:       "bad key",
+			apiVersion: client.APIVersion,
+			key:        "bad-key",
+			want: map[string]string{
+				"api version": checkOK, "authentication": checkFail, "upload permission": checkSkip,
+			},
+		},
+		{
+			name:       "anonymous",
+			apiVersion: client.APIVersion,
+			want: map[string]string{
+				"authentication": checkSkip, "upload permission": checkSkip,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := doctorServer(t, tt.apiVersion, tt.caps)
+			report := &doctorReport{}
+			diagnose(context.Background(), client.New(api.URL, tt.key), report)
+
+			for name, want := range tt.want {
+				if got := checkStatus(t, report, name); got.Status != want {
+					t.Errorf("%s = %s (%s), want %s", name, got.Status, got.Detail, want)
+				}
+			}
+			for _, c := range report.Checks {
+				if c.Status == checkFail && c.Fix == "" {
+					t.Errorf("failed check %s has no fix", c.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestDiagnose_Unreachable(t *testing.T) {
+	api := doctorServer(t, client.APIVersion, nil)
+	url := api.URL
+	api.Close()
+
+	report := &doctorReport{}
+	diagnose(context.Background(), client.New(url, ""), report)
+
+	if c := checkStatus(t, report, "tcp"); c.Status != checkFail {
+		t.Errorf("tcp = %s, want fail", c.Status)
+	}
+	if c := checkStatus(t, report, "api version"); c.Status != checkSkip {
+		t.Errorf("api version = %s, want skip after tcp failure", c.Status)
+	}
+	if report.Failed != 1 {
+		t.Errorf("Failed = %d, want 1", report.Failed)
+	}
+}
+
+func TestDiagnose_PinnedVersion(t *testing.T) {
+	api := doctorServer(t, client.APIVersion, []string{"read:*"})
+	pin := func(version string) {
+		viper.SetConfigType("yaml")
+		viper.ReadConfig(strings.NewReader("api:\n  version: \"" + version + "\"\n"))
+	}
+	t.Cleanup(func() {
+		viper.SetConfigType("yaml")
+		viper.ReadConfig(strings.NewReader(""))
+	})
+
+	pin(client.APIVersion)
+	report := &doctorReport{}
+	diagnose(context.Background(), client.New(doctorServer(t, "2099.01", nil).URL, "good-key"), report)
+	if c := checkStatus(t, report, "api version"); c.Status != checkFail || !strings.Contains(c.Fix, "api.version") {
+		t.Errorf("api version = %s (%s), want fail for a pin the server does not offer", c.Status, c.Fix)
+	}
+
+	pin(client.APIVersion)
+	report = &doctorReport{}
+	diagnose(context.Background(), client.New(api.URL, "good-key"), report)
+	if c := checkStatus(t, report, "api version"); c.Status != checkOK || !strings.Contains(c.Detail, "pinned") {
+		t.Errorf("api version = %s (%s), want ok and pinned", c.Status, c.Detail)
+	}
+}
@@ -5,68 +5,128 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
-	"time"
+	"syscall"
 
 	"github.com/spf13/viper"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-// setupLogger creates and configures a structured logger based on viper settings.
-func setupLogger() error {
-	level := viper.GetString("log.level")
-	format := viper.GetString("log.format")
-	output := viper.GetString("log.output")
+// levelVar holds the log level so it can change while the server runs.
+var levelVar slog.LevelVar
 
-	// Parse log level
-	var logLevel slog.Level
+// redactedAttrs lists log attribute keys (lowercase) whose values are never
+// written, in case an API key, header or upload ends up in a log call.
+var redactedAttrs = map[string]bool{
+	"api_key":       true,
+	"apikey":        true,
+	"x-api-key":     true,
+	"authorization": true,
+	"confirm_token": true,
+	"content":       true,
+}
+
+// redactAttr replaces the value of sensitive attributes, at any group
+// depth.
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if redactedAttrs[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, "[redacted]")
+	}
+	return a
+}
+
+// openLogFile opens a log file that is rotated by size and age according to
+// the log.* settings. Rotated files are named after the file with a
+// timestamp and are gzipped if log.compress is set.
+func openLogFile(path string) (*lumberjack.Logger, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	f := &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    viper.GetInt("log.max_size_mb"),
+		MaxAge:     viper.GetInt("log.max_age_days"),
+		MaxBackups: viper.GetInt("log.max_files"),
+		Compress:   viper.GetBool("log.compress"),
+		LocalTime:  true,
+	}
+	if f.MaxSize < 0 || f.MaxAge < 0 || f.MaxBackups < 0 {
+		return nil, fmt.Errorf("log rotation settings must not be negative")
+	}
+	// Open now so a bad path fails at startup rather than on the first log
+	if _, err := f.Write(nil); err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	return f, nil
+}
+
+// reopenOnSIGHUP closes the log file on SIGHUP so the next write reopens
+// it, for log rotation by an external tool such as logrotate.
+func reopenOnSIGHUP(f *lumberjack.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := f.Close(); err != nil {
+				slog.Warn("failed to reopen log file", "file", f.Filename, "error", err)
+				continue
+			}
+			slog.Info("log file reopened", "file", f.Filename)
+		}
+	}()
+}
+
+// parseLevel parses a log level name.
+func parseLevel(level string) (slog.Level, error) {
 	switch strings.ToLower(level) {
 	case "debug":
-		logLevel = slog.LevelDebug
+		return slog.LevelDebug, nil
 	case "info":
-		logLevel = slog.LevelInfo
+		return slog.LevelInfo, nil
 	case "warn":
-		logLevel = slog.LevelWarn
+		return slog.LevelWarn, nil
 	case "error":
-		logLevel = slog.LevelError
-	default:
-		return fmt.Errorf("invalid log level: %s (must be debug, info, warn, or error)", level)
+		return slog.LevelError, nil
 	}
+	return 0, fmt.Errorf("invalid log level: %s (must be debug, info, warn, or error)", level)
+}
+
+// setupLogger creates and configures a structured logger based on viper settings.
+func setupLogger() error {
+	format := viper.GetString("log.format")
+	output := viper.GetString("log.output")
+
+	// Parse log level
+	logLevel, err := parseLevel(viper.GetString("log.level"))
+	if err != nil {
+		return err
+	}
+	levelVar.Set(logLevel)
 
 	// Setup output writer
 	var writer io.Writer
 	switch {
 	case output == "" || output == "stderr":
 		writer = os.Stderr
-	case strings.HasSuffix(output, "/"):
-		// Directory - create dated log file
-		if err := os.MkdirAll(output, 0755); err != nil {
-			return fmt.Errorf("failed to create log directory: %w", err)
-		}
-		logFile := filepath.Join(output,
-			fmt.Sprintf("manuals-mcp-%s.log", time.Now().Format("2006-01-02")))
-		f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-		if err != nil {
-			return fmt.Errorf("failed to open log file: %w", err)
-		}
-		// Write to both stderr and file
-		writer = io.MultiWriter(os.Stderr, f)
 	default:
-		// Specific file path
-		dir := filepath.Dir(output)
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return fmt.Errorf("failed to create log directory: %w", err)
+		// A directory gets manuals-mcp.log, a path is used as is
+		logPath := output
+		if strings.HasSuffix(output, "/") {
+			logPath = filepath.Join(output, "manuals-mcp.log")
 		}
-		f, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		f, err := openLogFile(logPath)
 		if err != nil {
-			return fmt.Errorf("failed to open log file: %w", err)
+			return err
 		}
+		reopenOnSIGHUP(f)
 		// Write to both stderr and file
 		writer = io.MultiWriter(os.Stderr, f)
 	}
 
 	// Create handler based on format
-	opts := &slog.HandlerOptions{Level: logLevel}
+	opts := &slog.HandlerOptions{Level: &levelVar, ReplaceAttr: redactAttr}
 	var handler slog.Handler
 
 	if format == "json" {
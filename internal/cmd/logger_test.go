@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestRedactAttr(t *testing.T) {
+	var logs strings.Builder
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{ReplaceAttr: redactAttr}))
+	logger.Info("request",
+		"path", "/api/2025.12/status",
+		slog.Group("headers", "X-API-Key", "secret-1", "Authorization", "Bearer secret-2"),
+		"api_key", "secret-3",
+	)
+
+	if strings.Contains(logs.String(), "secret") {
+		t.Errorf("log contains a secret:\n%s", logs.String())
+	}
+	if !strings.Contains(logs.String(), "path=/api/2025.12/status") || !strings.Contains(logs.String(), "headers.X-API-Key=[redacted]") {
+		t.Errorf("log = %s", logs.String())
+	}
+}
+
+func TestOpenLogFile_Rotates(t *testing.T) {
+	viper.Set("log.max_size_mb", 1)
+	viper.Set("log.max_files", 1)
+	viper.Set("log.compress", false)
+	t.Cleanup(func() {
+		viper.Set("log.max_size_mb", nil)
+		viper.Set("log.max_files", nil)
+		viper.Set("log.compress", nil)
+	})
+
+	dir := filepath.Join(t.TempDir(), "logs")
+	f, err := openLogFile(filepath.Join(dir, "manuals-mcp.log"))
+	if err != nil {
+		t.Fatalf("openLogFile() error = %v", err)
+	}
+	defer f.Close()
+
+	line := append(bytes.Repeat([]byte("x"), 1023), '\n')
+	for range 3 * 1024 {
+		if _, err := f.Write(line); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	// 3 MB at 1 MB per file, keeping one rotated file. Old files are
+	// removed in the background.
+	var names []string
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = names[:0]
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		if len(names) == 2 {
+			return
+		}
+	}
+	t.Errorf("log directory has %v, want the log and one rotated file", names)
+}
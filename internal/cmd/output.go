@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+	"github.com/rmrfslashbin/manuals-mcp/internal/secrets"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats accepted by --output.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// newAPIClient creates a client from the api.url and api.key settings and
+// negotiates the API version with the server, unless api.version pins it.
+func newAPIClient() (*client.Client, error) {
+	apiURL := viper.GetString("api.url")
+	if apiURL == "" {
+		return nil, fmt.Errorf("MANUALS_API_URL is required (or pass --api-url)")
+	}
+	apiKey, err := resolveAPIKey(viper.GetString("api.key"), apiKeyRef())
+	if err != nil {
+		return nil, err
+	}
+	return connectAPIClient(apiURL, apiKey)
+}
+
+// apiKeyRef returns the secret reference in api.key_file, api.key_command
+// or api.key_keyring.
+func apiKeyRef() secrets.Ref {
+	return secrets.Ref{
+		File:    viper.GetString("api.key_file"),
+		Command: viper.GetString("api.key_command"),
+		Keyring: viper.GetString("api.key_keyring"),
+	}
+}
+
+// resolveAPIKey returns key, or the key read from ref. Setting both is an
+// error, so a forgotten MANUALS_API_KEY cannot silently win.
+func resolveAPIKey(key string, ref secrets.Ref) (string, error) {
+	if ref.IsZero() {
+		return key, nil
+	}
+	if key != "" {
+		return "", fmt.Errorf("both an API key and a key reference (%s) are set; remove one", ref)
+	}
+	key, err := secrets.Resolve(context.Background(), ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve API key: %w", err)
+	}
+	return key, nil
+}
+
+// connectAPIClient creates a client for apiURL and apiKey and negotiates
+// the API version with the server, or checks the version pinned by
+// api.version against it.
+func connectAPIClient(apiURL, apiKey string) (*client.Client, error) {
+	c, err := buildAPIClient(apiURL, apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	hint := "upgrade manuals-mcp or the API server, or pin a version with --api-version"
+	var n *client.Negotiation
+	if v := viper.GetString("api.version"); v != "" {
+		if err := c.SetVersion(v); err != nil {
+			return nil, err
+		}
+		hint = "pin a version the server offers with --api-version, or unset it to negotiate"
+		n, err = c.Pin(v)
+	} else {
+		n, err = c.Negotiate()
+	}
+	if err != nil {
+		if errors.Is(err, client.ErrIncompatible) {
+			return nil, fmt.Errorf("%w (%s)", err, hint)
+		}
+		return nil, fmt.Errorf("failed to connect to API (run \"manuals-mcp doctor\" to diagnose): %w", err)
+	}
+	if n.Newer != "" {
+		slog.Warn("API server supports a newer API version, upgrade manuals-mcp to use it", "api_url", apiURL, "server_version", n.Newer, "using", n.Version)
+	}
+	if len(n.Missing) > 0 {
+		slog.Warn("API server uses an older API version, some features are unavailable", "api_url", apiURL, "version", n.Version, "unavailable", n.Missing)
+	}
+	slog.Debug("negotiated API version", "version", n.Version, "server_versions", n.ServerVersions)
+	return c, nil
+}
+
+// apiObserver, if set, is called after every request of the API clients
+// built by buildAPIClient. serve sets it to record metrics.
+var apiObserver client.RequestObserver
+
+// buildAPIClient creates a client for apiURL and apiKey with the api.*
+// transport and authentication settings, without contacting the server.
+func buildAPIClient(apiURL, apiKey string) (*client.Client, error) {
+	opts := client.Options{
+		CAFile:             viper.GetString("api.tls.ca_file"),
+		CertFile:           viper.GetString("api.tls.cert_file"),
+		KeyFile:            viper.GetString("api.tls.key_file"),
+		InsecureSkipVerify: viper.GetBool("api.tls.insecure_skip_verify"),
+		Proxy:              viper.GetString("api.proxy"),
+		AuthScheme:         viper.GetString("api.auth_scheme"),
+		Observer:           apiObserver,
+		Logger:             slog.Default(),
+		Timeouts: client.Timeouts{
+			Default:  viper.GetDuration("api.timeout"),
+			Search:   viper.GetDuration("api.search_timeout"),
+			Transfer: viper.GetDuration("api.transfer_timeout"),
+		},
+	}
+	c, err := client.NewWithOptions(apiURL, apiKey, opts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API client settings: %w", err)
+	}
+	if opts.InsecureSkipVerify {
+		slog.Warn("TLS certificate verification is disabled for the API connection")
+	}
+	return c, nil
+}
+
+// addOutputFlag adds the --output flag to cmd and its subcommands.
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringP("output", "o", outputTable, "output format (table, json, yaml)")
+}
+
+// printResult writes v to the command's output in the --output format.
+// For table output, table is called with a tabwriter that aligns
+// tab-separated columns.
+func printResult(cmd *cobra.Command, v any, table func(w io.Writer)) error {
+	format, _ := cmd.Flags().GetString("output")
+	return render(cmd.OutOrStdout(), format, v, table)
+}
+
+// render writes v in the given format.
+func render(out io.Writer, format string, v any, table func(w io.Writer)) error {
+	switch format {
+	case "", outputTable:
+		tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		table(tw)
+		return tw.Flush()
+	case outputJSON:
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case outputYAML:
+		data, err := toYAML(v)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(data)
+		return err
+	default:
+		return fmt.Errorf("invalid output format: %s (must be table, json, or yaml)", format)
+	}
+}
+
+// toYAML converts v to YAML using its JSON field names and order. JSON is
+// valid YAML, so the JSON encoding is parsed into a node tree and re-emitted
+// in block style.
+func toYAML(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode output: %w", err)
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("failed to encode output: %w", err)
+	}
+	blockStyle(&node)
+	return yaml.Marshal(&node)
+}
+
+// blockStyle clears the flow and quoting styles inherited from JSON.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
+
+// confirmPrompt asks the user to confirm a destructive action on stdin.
+// It returns true immediately when --yes was given.
+func confirmPrompt(cmd *cobra.Command, prompt string) (bool, error) {
+	if yes, _ := cmd.Flags().GetBool("yes"); yes {
+		return true, nil
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "%s\nType 'yes' to continue: ", prompt)
+	answer, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	return strings.TrimSpace(answer) == "yes", nil
+}
+
+// errCancelled is returned when the user declines a confirmation prompt.
+var errCancelled = fmt.Errorf("cancelled, nothing was changed")
+
+// orDash returns s, or "-" when it is empty, for table cells.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// writeOutputFile writes data to path, or to stdout when path is "-".
+func writeOutputFile(cmd *cobra.Command, path string, data []byte) error {
+	if path == "-" {
+		_, err := cmd.OutOrStdout().Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Wrote %d bytes to %s\n", len(data), path)
+	return nil
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+	"github.com/spf13/viper"
+)
+
+func TestRender(t *testing.T) {
+	v := struct {
+		Name  string   `json:"name"`
+		Count int      `json:"count"`
+		Tags  []string `json:"tags"`
+	}{Name: "esp32", Count: 2, Tags: []string{"wifi", "ble"}}
+	table := func(w io.Writer) { fmt.Fprintf(w, "NAME\tCOUNT\n%s\t%d\n", v.Name, v.Count) }
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"table", "NAME   COUNT\nesp32  2\n"},
+		{"json", "{\n  \"name\": \"esp32\",\n  \"count\": 2,\n  \"tags\": [\n    \"wifi\",\n    \"ble\"\n  ]\n}\n"},
+		{"yaml", "name: esp32\ncount: 2\ntags:\n    - wifi\n    - ble\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := render(&buf, tt.format, v, table); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.format, err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.format, buf.String(), tt.want)
+		}
+	}
+
+	if err := render(io.Discard, "xml", v, table); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestConnectAPIClient_PinnedVersion(t *testing.T) {
+	offered := client.APIVersion
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(client.VersionsResponse{
+			Versions: []string{offered},
+			Features: map[string][]string{offered: {"settings_schema"}},
+		})
+	}))
+	t.Cleanup(api.Close)
+
+	viper.SetConfigType("yaml")
+	viper.ReadConfig(strings.NewReader("api:\n  version: \"" + client.APIVersion + "\"\n"))
+	t.Cleanup(func() {
+		viper.SetConfigType("yaml")
+		viper.ReadConfig(strings.NewReader(""))
+	})
+
+	c, err := connectAPIClient(api.URL, "key")
+	if err != nil {
+		t.Fatalf("connectAPIClient() error = %v", err)
+	}
+	var unsupported *client.UnsupportedError
+	if err := c.RenameUser("u1", "new"); !errors.As(err, &unsupported) {
+		t.Errorf("RenameUser() error = %v, want UnsupportedError for a feature the pinned version lacks", err)
+	}
+
+	offered = "2099.01"
+	if _, err := connectAPIClient(api.URL, "key"); !errors.Is(err, client.ErrIncompatible) || !strings.Contains(err.Error(), "--api-version") {
+		t.Errorf("connectAPIClient() error = %v, want ErrIncompatible with a hint", err)
+	}
+}
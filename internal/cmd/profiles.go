@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/mcp"
+	"github.com/spf13/viper"
+)
+
+// activeProfile is the config profile applied by applyProfile, or "".
+var activeProfile string
+
+// profileNames returns the profiles defined under "profiles" in the config
+// file, sorted.
+func profileNames() []string {
+	return slices.Sorted(maps.Keys(viper.GetStringMap("profiles")))
+}
+
+// applyProfile overlays the settings of the named profile on the config
+// file. Flags and environment variables still take precedence. An empty
+// name selects no profile.
+func applyProfile(name string) error {
+	if name == "" {
+		activeProfile = ""
+		return nil
+	}
+	if !slices.Contains(profileNames(), name) {
+		if len(profileNames()) == 0 {
+			return fmt.Errorf("unknown profile %q (no profiles in the config file)", name)
+		}
+		return fmt.Errorf("unknown profile %q (configured: %s)", name, strings.Join(profileNames(), ", "))
+	}
+	if err := viper.MergeConfigMap(viper.GetStringMap("profiles." + name)); err != nil {
+		return fmt.Errorf("failed to apply profile %q: %w", name, err)
+	}
+	activeProfile = name
+	return nil
+}
+
+// switchProfile re-reads the config file with the named profile applied,
+// connects to its backends and applies its log level. It returns the
+// settings the server applies itself. The previous profile is restored if
+// the new one cannot be loaded.
+func switchProfile(name string) (*mcp.Profile, error) {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	previous := activeProfile
+	load := func(name string) error {
+		if err := viper.ReadInConfig(); err != nil {
+			return fmt.Errorf("failed to read config file: %w", err)
+		}
+		return applyProfile(name)
+	}
+
+	if err := load(name); err != nil {
+		load(previous)
+		return nil, err
+	}
+	level, err := parseLevel(viper.GetString("log.level"))
+	if err != nil {
+		load(previous)
+		return nil, fmt.Errorf("profile %q: %w", name, err)
+	}
+	backends, err := newBackends()
+	if err != nil {
+		load(previous)
+		return nil, fmt.Errorf("profile %q: %w", name, err)
+	}
+	levelVar.Set(level)
+	return &mcp.Profile{Backends: backends, FilePolicy: localFilePolicy()}, nil
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestProfiles(t *testing.T) {
+	api := doctorServer(t, "2025.12", []string{"*"})
+	cfg := filepath.Join(t.TempDir(), "manuals.yaml")
+	os.WriteFile(cfg, []byte(`api:
+  url: https://manuals.example.com
+  timeout: 10s
+profiles:
+  lab:
+    api:
+      url: `+api.URL+`
+      key: good-key
+    log:
+      level: debug
+    files:
+      allowed_roots: [/srv/lab]
+  down:
+    api:
+      url: http://127.0.0.1:1
+`), 0o600)
+
+	viper.SetConfigFile(cfg)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatalf("ReadInConfig() error = %v", err)
+	}
+	t.Cleanup(func() {
+		activeProfile = ""
+		levelVar.Set(slog.LevelInfo)
+		viper.SetConfigType("yaml")
+		viper.ReadConfig(strings.NewReader(""))
+	})
+
+	if got := strings.Join(profileNames(), ","); got != "down,lab" {
+		t.Errorf("profileNames() = %q", got)
+	}
+	if err := applyProfile("prod"); err == nil || !strings.Contains(err.Error(), "down, lab") {
+		t.Errorf("applyProfile(prod) error = %v, want unknown profile", err)
+	}
+
+	if err := applyProfile("lab"); err != nil {
+		t.Fatalf("applyProfile(lab) error = %v", err)
+	}
+	if viper.GetString("api.url") != api.URL || viper.GetString("api.timeout") != "10s" {
+		t.Errorf("profile should override api.url and keep api.timeout: url = %q, timeout = %q",
+			viper.GetString("api.url"), viper.GetString("api.timeout"))
+	}
+
+	if _, err := switchProfile("down"); err == nil {
+		t.Fatal("switchProfile(down) should fail")
+	}
+	if activeProfile != "lab" || viper.GetString("api.url") != api.URL {
+		t.Errorf("failed switch should restore lab: profile = %q, url = %q", activeProfile, viper.GetString("api.url"))
+	}
+
+	levelVar.Set(slog.LevelInfo)
+	profile, err := switchProfile("lab")
+	if err != nil {
+		t.Fatalf("switchProfile(lab) error = %v", err)
+	}
+	if len(profile.Backends) != 1 || profile.Backends[0].Client.GetAPIURL() != api.URL {
+		t.Errorf("switchProfile(lab) backends = %+v", profile.Backends)
+	}
+	if roots := profile.FilePolicy.Roots; len(roots) != 1 || roots[0] != "/srv/lab" {
+		t.Errorf("switchProfile(lab) file roots = %v, want /srv/lab", roots)
+	}
+	if levelVar.Level() != slog.LevelDebug {
+		t.Errorf("switchProfile(lab) log level = %v, want debug", levelVar.Level())
+	}
+}
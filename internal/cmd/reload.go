@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rmrfslashbin/manuals-mcp/internal/mcp"
+	"github.com/spf13/viper"
+)
+
+// configMu serializes re-reading the config file, which the watcher and
+// switch_profile both do while the server runs.
+var configMu sync.Mutex
+
+// reloader applies config file changes to a running server.
+type reloader struct {
+	server *mcp.Server
+	logger *slog.Logger
+	// api is the fingerprint of the settings the backends were built from.
+	api string
+}
+
+// reloadDelay lets a burst of file events from one save settle before the
+// config file is read.
+const reloadDelay = 100 * time.Millisecond
+
+// watchConfig reloads the config file whenever it changes, until ctx is
+// cancelled. The log level, API connection (URL, key, backends, TLS, proxy,
+// timeouts) and local file limits take effect without a restart; other
+// settings need one.
+//
+// The directory is watched rather than the file, so saves that replace the
+// file are seen. viper's own watcher is not used because it re-reads the
+// file outside configMu.
+func watchConfig(ctx context.Context, s *mcp.Server, logger *slog.Logger) error {
+	file := filepath.Clean(viper.ConfigFileUsed())
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch config file: %w", err)
+	}
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch config file: %w", err)
+	}
+
+	r := &reloader{server: s, logger: logger, api: apiFingerprint()}
+	go func() {
+		defer watcher.Close()
+		var pending *time.Timer
+		for {
+			select {
+			case <-ctx.Done():
+				if pending != nil {
+					pending.Stop()
+				}
+				return
+			case e, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(e.Name) != file || !e.Has(fsnotify.Write|fsnotify.Create) {
+					continue
+				}
+				if pending != nil {
+					pending.Stop()
+				}
+				pending = time.AfterFunc(reloadDelay, r.reload)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Warn("config file watcher error", "error", err)
+			}
+		}
+	}()
+	logger.Info("watching config file for changes", "file", file)
+	return nil
+}
+
+// apiFingerprint summarizes the config file settings the API clients are
+// built from, so unrelated edits do not reconnect.
+func apiFingerprint() string {
+	data, _ := json.Marshal([]any{viper.Get("api"), viper.Get("backends")})
+	return string(data)
+}
+
+// reload re-reads the config file and applies what changed. Invalid
+// settings are logged and the previous ones kept.
+func (r *reloader) reload() {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	if err := viper.ReadInConfig(); err != nil {
+		r.logger.Warn("failed to reload config file, keeping previous settings", "error", err)
+		return
+	}
+	if err := applyProfile(activeProfile); err != nil {
+		r.logger.Warn("failed to reload config file, keeping previous settings", "error", err)
+		return
+	}
+
+	if level, err := parseLevel(viper.GetString("log.level")); err != nil {
+		r.logger.Warn("ignoring invalid log level", "error", err)
+	} else if level != levelVar.Level() {
+		levelVar.Set(level)
+		r.logger.Info("log level changed", "level", level)
+	}
+
+	if api := apiFingerprint(); api != r.api {
+		backends, err := newBackends()
+		if err != nil {
+			r.logger.Warn("failed to apply API settings, keeping previous connection", "error", err)
+		} else {
+			r.server.SetBackends(backends)
+			r.api = api
+			for _, b := range backends {
+				r.logger.Info("API connection reloaded", "backend", b.Name, "api_url", b.Client.GetAPIURL(), "api_version", b.Client.Version())
+			}
+		}
+	}
+
+	policy := localFilePolicy()
+	r.server.SetLocalFilePolicy(policy)
+	r.logger.Debug("config file reloaded", "file", viper.ConfigFileUsed(), "allowed_roots", policy.Roots, "max_size_mb", viper.GetInt64("files.max_size_mb"))
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+	"github.com/rmrfslashbin/manuals-mcp/internal/mcp"
+	"github.com/spf13/viper"
+)
+
+func TestReload(t *testing.T) {
+	first := doctorServer(t, client.APIVersion, nil)
+	second := doctorServer(t, client.APIVersion, nil)
+	cfg := filepath.Join(t.TempDir(), "manuals.yaml")
+	write := func(level, url string) {
+		t.Helper()
+		if err := os.WriteFile(cfg, []byte("log:\n  level: "+level+"\napi:\n  url: "+url+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("info", first.URL)
+	viper.SetConfigFile(cfg)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatalf("ReadInConfig() error = %v", err)
+	}
+	t.Cleanup(func() {
+		levelVar.Set(slog.LevelInfo)
+		viper.SetConfigType("yaml")
+		viper.ReadConfig(strings.NewReader(""))
+	})
+	levelVar.Set(slog.LevelInfo)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := mcp.NewServer(client.New(first.URL, ""), "test", "none", "now", logger)
+	r := &reloader{server: s, logger: logger, api: apiFingerprint()}
+
+	write("debug", second.URL)
+	r.reload()
+	if levelVar.Level() != slog.LevelDebug {
+		t.Errorf("log level = %v, want debug", levelVar.Level())
+	}
+	if got := serverAPIURL(t, s); !strings.Contains(got, second.URL) {
+		t.Errorf("server uses %s, want the new backend %s", got, second.URL)
+	}
+
+	// Invalid settings keep the previous ones
+	write("loud", "http://127.0.0.1:1")
+	r.reload()
+	if levelVar.Level() != slog.LevelDebug {
+		t.Errorf("invalid level should be ignored, got %v", levelVar.Level())
+	}
+	if got := serverAPIURL(t, s); !strings.Contains(got, second.URL) {
+		t.Errorf("unreachable API should keep the previous connection, server uses %s", got)
+	}
+}
+
+// serverAPIURL returns the API URL of the server's primary backend.
+func serverAPIURL(t *testing.T, s *mcp.Server) string {
+	t.Helper()
+	return s.Backends()[0].Client.GetAPIURL()
+}
+
+func TestWatchConfig(t *testing.T) {
+	api := doctorServer(t, client.APIVersion, nil)
+	cfg := filepath.Join(t.TempDir(), "manuals.yaml")
+	write := func(level string) {
+		t.Helper()
+		if err := os.WriteFile(cfg, []byte("log:\n  level: "+level+"\napi:\n  url: "+api.URL+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("info")
+	viper.SetConfigFile(cfg)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatalf("ReadInConfig() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		configMu.Lock()
+		defer configMu.Unlock()
+		levelVar.Set(slog.LevelInfo)
+		viper.SetConfigType("yaml")
+		viper.ReadConfig(strings.NewReader(""))
+	})
+	levelVar.Set(slog.LevelInfo)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := mcp.NewServer(client.New(api.URL, ""), "test", "none", "now", logger)
+	if err := watchConfig(ctx, s, logger); err != nil {
+		t.Fatalf("watchConfig() error = %v", err)
+	}
+
+	write("debug")
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		if levelVar.Level() == slog.LevelDebug {
+			return
+		}
+	}
+	t.Error("log level was not reloaded after the config file changed")
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/joho/godotenv"
@@ -23,6 +24,12 @@ var (
 	logLevel  string
 	logFormat string
 	logOutput string
+	apiURL    string
+	apiKey    string
+
+	// dotenvSources maps environment variables set from a .env file to
+	// that file, so "doctor" can report where a setting came from.
+	dotenvSources = map[string]string{}
 )
 
 // rootCmd represents the base command when called without any subcommands.
@@ -36,8 +43,19 @@ Features:
   - Search across documentation using full-text search
   - Device information, pinouts, and specifications
   - Document listings
-  - Connects to Manuals REST API for data`,
+  - Connects to Manuals REST API for data
+
+Run "manuals-mcp serve" to start the MCP server, or use the search, device,
+pinout, specs, docs, publish, reindex, users, and settings commands to work
+with the API directly from the shell. "manuals-mcp browse" opens an
+interactive terminal browser.`,
+	// main prints the returned error
+	SilenceErrors: true,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Apply the selected profile before anything reads the config
+		if err := applyProfile(viper.GetString("profile")); err != nil {
+			return err
+		}
 		// Setup logger for all commands
 		return setupLogger()
 	},
@@ -61,14 +79,42 @@ func init() {
 
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.manuals-mcp.yaml)")
+	rootCmd.PersistentFlags().String("profile", "", "config file profile to use (see \"profiles\" in the config file)")
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "log level (debug, info, warn, error)")
 	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "log format (json, text)")
 	rootCmd.PersistentFlags().StringVar(&logOutput, "log-output", "stderr", "log output (stderr, /path/to/file, or /path/to/dir/)")
+	rootCmd.PersistentFlags().Int("log-max-size", 100, "rotate the log file when it reaches this size, in MB")
+	rootCmd.PersistentFlags().Int("log-max-age", 30, "delete rotated log files older than this many days (0 keeps them)")
+	rootCmd.PersistentFlags().Int("log-max-files", 10, "number of rotated log files to keep (0 keeps all)")
+	rootCmd.PersistentFlags().Bool("log-compress", true, "gzip rotated log files")
+	rootCmd.PersistentFlags().StringVar(&apiURL, "api-url", "", "URL of the Manuals REST API")
+	rootCmd.PersistentFlags().StringVar(&apiKey, "api-key", "", "API key for authentication")
+	rootCmd.PersistentFlags().Bool("dotenv", false, "also load .env from the current directory")
+	rootCmd.PersistentFlags().String("api-version", "", "pin the API version instead of negotiating it with the server")
+	rootCmd.PersistentFlags().String("ca-file", "", "PEM CA bundle to trust for the API in addition to the system roots")
+	rootCmd.PersistentFlags().String("client-cert", "", "PEM client certificate for mutual TLS")
+	rootCmd.PersistentFlags().String("client-key", "", "PEM client key for mutual TLS")
+	rootCmd.PersistentFlags().Bool("insecure-skip-verify", false, "do not verify the API server certificate (lab hosts only)")
+	rootCmd.PersistentFlags().String("proxy", "", "http, https, or socks5 proxy URL for the API (default from HTTPS_PROXY/HTTP_PROXY)")
 
 	// Bind flags to viper
+	viper.BindPFlag("profile", rootCmd.PersistentFlags().Lookup("profile"))
 	viper.BindPFlag("log.level", rootCmd.PersistentFlags().Lookup("log-level"))
 	viper.BindPFlag("log.format", rootCmd.PersistentFlags().Lookup("log-format"))
 	viper.BindPFlag("log.output", rootCmd.PersistentFlags().Lookup("log-output"))
+	viper.BindPFlag("log.max_size_mb", rootCmd.PersistentFlags().Lookup("log-max-size"))
+	viper.BindPFlag("log.max_age_days", rootCmd.PersistentFlags().Lookup("log-max-age"))
+	viper.BindPFlag("log.max_files", rootCmd.PersistentFlags().Lookup("log-max-files"))
+	viper.BindPFlag("log.compress", rootCmd.PersistentFlags().Lookup("log-compress"))
+	viper.BindPFlag("api.url", rootCmd.PersistentFlags().Lookup("api-url"))
+	viper.BindPFlag("api.key", rootCmd.PersistentFlags().Lookup("api-key"))
+	viper.BindPFlag("dotenv.cwd", rootCmd.PersistentFlags().Lookup("dotenv"))
+	viper.BindPFlag("api.version", rootCmd.PersistentFlags().Lookup("api-version"))
+	viper.BindPFlag("api.tls.ca_file", rootCmd.PersistentFlags().Lookup("ca-file"))
+	viper.BindPFlag("api.tls.cert_file", rootCmd.PersistentFlags().Lookup("client-cert"))
+	viper.BindPFlag("api.tls.key_file", rootCmd.PersistentFlags().Lookup("client-key"))
+	viper.BindPFlag("api.tls.insecure_skip_verify", rootCmd.PersistentFlags().Lookup("insecure-skip-verify"))
+	viper.BindPFlag("api.proxy", rootCmd.PersistentFlags().Lookup("proxy"))
 
 	// Set environment variable prefix and key replacer
 	// Maps viper keys like "log.level" to env vars like "MANUALS_LOG_LEVEL"
@@ -79,13 +125,6 @@ func init() {
 
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
-	// Load .env file if it exists (silently ignore if not found)
-	// Priority: .env in current directory, then .env in home directory
-	_ = godotenv.Load() // Current directory
-	if home, err := os.UserHomeDir(); err == nil {
-		_ = godotenv.Load(home + "/.env") // Home directory
-	}
-
 	if cfgFile != "" {
 		// Use config file from the flag
 		viper.SetConfigFile(cfgFile)
@@ -108,4 +147,33 @@ func initConfig() {
 	if err := viper.ReadInConfig(); err == nil {
 		slog.Debug("using config file", "file", viper.ConfigFileUsed())
 	}
+
+	// Load .env files if they exist (silently ignore if not found).
+	// The current directory is opt-in since it may hold another project's
+	// secrets; it takes priority over .env in the home directory.
+	if viper.GetBool("dotenv.cwd") {
+		loadDotEnv(".env") // Current directory
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		loadDotEnv(filepath.Join(home, ".env")) // Home directory
+	}
+}
+
+// loadDotEnv sets environment variables from a .env file without overriding
+// variables that are already set, recording each one in dotenvSources.
+func loadDotEnv(path string) {
+	values, err := godotenv.Read(path)
+	if err != nil {
+		return
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	for k, v := range values {
+		if _, ok := os.LookupEnv(k); ok {
+			continue
+		}
+		os.Setenv(k, v)
+		dotenvSources[k] = path
+	}
 }
@@ -1,75 +1,196 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
-	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+	"github.com/rmrfslashbin/manuals-mcp/internal/audit"
 	"github.com/rmrfslashbin/manuals-mcp/internal/mcp"
+	"github.com/rmrfslashbin/manuals-mcp/internal/metrics"
+	"github.com/rmrfslashbin/manuals-mcp/internal/secrets"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
-var (
-	apiURL string
-	apiKey string
-)
-
 // serveCmd represents the serve command.
 var serveCmd = &cobra.Command{
 	Use:   "serve",
 	Short: "Start the MCP server",
 	Long: `Start the MCP server and listen for requests via stdio.
 
-The server connects to the Manuals REST API to serve documentation.
+The server connects to the Manuals REST API to serve documentation. To serve
+several API instances, list them under "backends" in the config file; read
+tools then query all of them and write tools take a "backend" argument.
 
 Environment Variables:
-  MANUALS_API_URL    - URL of the Manuals REST API (required)
-  MANUALS_API_KEY    - API key for authentication (optional, enables admin features)
-  MANUALS_LOG_LEVEL  - Log level (debug, info, warn, error)
-  MANUALS_LOG_FORMAT - Log format (json, text)
-  MANUALS_LOG_OUTPUT - Log output (stderr, /path/to/file, /path/to/dir/)`,
+  MANUALS_PROFILE                      - Config file profile to use (or pass --profile)
+  MANUALS_API_URL                      - URL of the Manuals REST API (required)
+  MANUALS_API_KEY                      - API key for authentication (optional, enables admin features)
+  MANUALS_API_KEY_FILE                 - Read the API key from this file instead
+  MANUALS_API_KEY_COMMAND              - Read the API key from this command's output instead (e.g. pass show manuals)
+  MANUALS_API_KEY_KEYRING              - Read the API key from this OS keyring entry instead (account or service/account)
+  MANUALS_API_VERSION                  - Pin the API version instead of negotiating it (e.g. 2025.12)
+  MANUALS_API_AUTH_SCHEME              - How the API key is sent: api-key (X-API-Key header) or bearer
+  MANUALS_API_TLS_CA_FILE              - PEM CA bundle trusted in addition to the system roots
+  MANUALS_API_TLS_CERT_FILE            - PEM client certificate for mutual TLS
+  MANUALS_API_TLS_KEY_FILE             - PEM client key for mutual TLS
+  MANUALS_API_TLS_INSECURE_SKIP_VERIFY - Skip server certificate verification (lab hosts only)
+  MANUALS_API_PROXY                    - http, https, or socks5 proxy URL (default from HTTPS_PROXY/HTTP_PROXY)
+  MANUALS_API_TIMEOUT                  - Request timeout (default 30s)
+  MANUALS_API_SEARCH_TIMEOUT           - Search request timeout (default 15s)
+  MANUALS_API_TRANSFER_TIMEOUT         - Upload and download timeout (default 5m)
+  MANUALS_DOTENV_CWD                   - Also load .env from the current directory (or pass --dotenv)
+  MANUALS_CONFIG_WATCH                 - Reload settings when the config file changes (default true)
+  MANUALS_LOG_LEVEL                    - Log level (debug, info, warn, error)
+  MANUALS_LOG_FORMAT                   - Log format (json, text)
+  MANUALS_LOG_OUTPUT                   - Log output (stderr, /path/to/file, /path/to/dir/)
+  MANUALS_LOG_MAX_SIZE_MB              - Rotate the log file at this size (default 100)
+  MANUALS_LOG_MAX_AGE_DAYS             - Delete rotated log files older than this (default 30, 0 keeps them)
+  MANUALS_LOG_MAX_FILES                - Number of rotated log files to keep (default 10, 0 keeps all)
+  MANUALS_LOG_COMPRESS                 - Gzip rotated log files (default true)
+  MANUALS_GIT_COMMIT_URL               - Commit link template, e.g. https://github.com/org/docs/commit/{commit}
+  MANUALS_GIT_EMAIL_DOMAIN             - Domain for commit author emails (default: manuals-mcp.local)
+  MANUALS_CONFIRM_DISABLED             - Skip confirmation of destructive operations (true/false)
+  MANUALS_AUDIT_FILE                   - Audit log path (default ~/.manuals-mcp/audit.jsonl, "off" to disable)
+  MANUALS_SECRETS_SINK                 - Where new API keys go: file or keyring (default: shown inline)
+  MANUALS_SECRETS_DIR                  - Directory for key files (default ~/.manuals-mcp/keys)
+  MANUALS_FILES_ALLOWED_ROOTS          - Directories local_path may read from, separated by ":" (";" on Windows; default: system temp dir, "none" to disable)
+  MANUALS_FILES_DENY_PATTERNS          - Extra comma-separated path patterns local_path may never read
+  MANUALS_FILES_MAX_SIZE_MB            - Largest file local_path may read, in MB (default 25)
+  MANUALS_TRACING_EXPORTER             - Export traces of tool calls and API requests: none, otlp, or stdout (written to stderr; default none)
+  MANUALS_TRACING_ENDPOINT             - OTLP/HTTP collector URL (default http://localhost:4318, or OTEL_EXPORTER_OTLP_ENDPOINT)
+  MANUALS_METRICS_LISTEN               - Serve Prometheus metrics at http://<address>/metrics (e.g. 127.0.0.1:9464; default off)`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		logger := slog.Default()
 
-		apiURL := viper.GetString("api.url")
-		apiKey := viper.GetString("api.key")
+		// Serve Prometheus metrics if requested. This comes first so the
+		// API clients record their requests.
+		var m *metrics.Metrics
+		if addr := viper.GetString("metrics.listen"); addr != "" {
+			m = metrics.New()
+			apiObserver = m.ObserveRequest
+			listening, err := m.Serve(cmd.Context(), addr)
+			if err != nil {
+				return err
+			}
+			logger.Info("serving metrics", "url", "http://"+listening.String()+"/metrics")
+		}
 
-		if apiURL == "" {
-			return fmt.Errorf("MANUALS_API_URL is required")
+		// Export traces of tool calls and API requests if requested
+		shutdownTracing, err := setupTracing(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdownTracing(ctx); err != nil {
+				logger.Warn("failed to flush traces", "error", err)
+			}
+		}()
+		if exporter := viper.GetString("tracing.exporter"); exporter != "" && exporter != traceNone {
+			logger.Info("tracing enabled", "exporter", exporter, "endpoint", viper.GetString("tracing.endpoint"))
 		}
 
-		// API key is now optional - allows anonymous read-only access
-		anonymousMode := apiKey == ""
-		if anonymousMode {
-			logger.Info("running in anonymous mode (read-only access)")
+		// Connect to the API backends
+		backends, err := newBackends()
+		if err != nil {
+			return err
 		}
 
 		logger.Info("starting MCP server",
 			"version", version,
 			"commit", gitCommit,
-			"api_url", apiURL,
-			"anonymous_mode", anonymousMode,
+			"backends", len(backends),
 		)
 
-		// Create API client
-		apiClient := client.New(apiURL, apiKey)
+		for _, b := range backends {
+			// API key is optional - allows anonymous read-only access
+			anonymousMode := !b.Client.HasAPIKey()
 
-		// Test connection by getting status
-		status, err := apiClient.GetStatus()
+			// Test connection by getting status
+			status, err := b.Client.GetStatus()
+			if err != nil {
+				logger.Error("failed to connect to API", "backend", b.Name, "api_url", b.Client.GetAPIURL(), "error", err)
+				return fmt.Errorf("backend %q: failed to connect to API (run \"manuals-mcp doctor\" to diagnose): %w", b.Name, err)
+			}
+
+			logger.Info("connected to Manuals API",
+				"backend", b.Name,
+				"api_url", b.Client.GetAPIURL(),
+				"api_version", b.Client.Version(),
+				"anonymous_mode", anonymousMode,
+				"devices", status.Counts.Devices,
+				"documents", status.Counts.Documents,
+			)
+		}
+
+		opts := []mcp.Option{
+			mcp.WithCommitURLTemplate(viper.GetString("git.commit_url")),
+			mcp.WithGitEmailDomain(viper.GetString("git.email_domain")),
+			mcp.WithConfirmations(!viper.GetBool("confirm.disabled")),
+			mcp.WithBackends(backends),
+			mcp.WithMetrics(m),
+		}
+
+		// Open audit log unless disabled
+		if auditPath := viper.GetString("audit.file"); auditPath != "off" {
+			if auditPath == "" {
+				auditPath, err = dataPath("audit.jsonl")
+				if err != nil {
+					return err
+				}
+			}
+			auditLog, err := audit.Open(auditPath)
+			if err != nil {
+				return err
+			}
+			defer auditLog.Close()
+			logger.Info("audit log enabled", "file", auditPath)
+			opts = append(opts, mcp.WithAuditLog(auditLog))
+		}
+
+		// Store generated API keys outside the transcript if configured
+		keyDir := viper.GetString("secrets.dir")
+		if keyDir == "" {
+			if keyDir, err = dataPath("keys"); err != nil {
+				return err
+			}
+		}
+		keySink, err := secrets.New(viper.GetString("secrets.sink"), keyDir)
 		if err != nil {
-			logger.Error("failed to connect to API", "error", err)
-			return fmt.Errorf("failed to connect to API: %w", err)
+			return err
+		}
+		if keySink != nil {
+			logger.Info("new API keys will be stored outside the transcript", "sink", viper.GetString("secrets.sink"))
+			opts = append(opts, mcp.WithSecretSink(keySink))
 		}
 
-		logger.Info("connected to Manuals API",
-			"api_version", status.APIVersion,
-			"devices", status.Counts.Devices,
-			"documents", status.Counts.Documents,
-		)
+		// Restrict which local files upload tools may read
+		policy := localFilePolicy()
+		logger.Info("local file access", "allowed_roots", policy.Roots, "max_size_mb", viper.GetInt64("files.max_size_mb"))
+		opts = append(opts, mcp.WithLocalFilePolicy(policy))
+
+		// Allow switching between config profiles
+		if names := profileNames(); len(names) > 0 && viper.ConfigFileUsed() != "" {
+			logger.Info("config profiles available", "profiles", names, "active", activeProfile)
+			opts = append(opts, mcp.WithProfiles(names, activeProfile, switchProfile))
+		}
 
 		// Create MCP server
-		mcpServer := mcp.NewServer(apiClient, version, gitCommit, buildTime, logger)
+		mcpServer := mcp.NewServer(backends[0].Client, version, gitCommit, buildTime, logger, opts...)
+
+		// Apply config file edits without a restart
+		if viper.ConfigFileUsed() != "" && viper.GetBool("config.watch") {
+			if err := watchConfig(cmd.Context(), mcpServer, logger); err != nil {
+				logger.Warn("config file changes will need a restart", "error", err)
+			}
+		}
 
 		logger.Info("MCP server ready, listening on stdio")
 
@@ -78,14 +199,80 @@ Environment Variables:
 	},
 }
 
+// localFilePolicy builds the local_path restrictions from the files.*
+// settings.
+func localFilePolicy() mcp.LocalFilePolicy {
+	policy := mcp.LocalFilePolicy{
+		Roots:        stringList("files.allowed_roots", filepath.SplitList),
+		DenyPatterns: stringList("files.deny_patterns", func(v string) []string { return strings.Split(v, ",") }),
+		MaxSize:      viper.GetInt64("files.max_size_mb") << 20,
+	}
+	switch {
+	case len(policy.Roots) == 1 && policy.Roots[0] == "none":
+		policy.Roots = nil
+	case len(policy.Roots) == 0:
+		policy.Roots = []string{os.TempDir()}
+	}
+	return policy
+}
+
+// dataPath returns the path of name inside the manuals-mcp data directory
+// ($HOME/.manuals-mcp).
+func dataPath(name string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(home, ".manuals-mcp", name), nil
+}
+
+// stringList reads a list setting that may be given as a YAML list or as a
+// single string, which is split with split. Empty entries are dropped.
+func stringList(key string, split func(string) []string) []string {
+	var values []string
+	if v, ok := viper.Get(key).(string); ok {
+		values = split(v)
+	} else {
+		values = viper.GetStringSlice(key)
+	}
+
+	var list []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
 func init() {
 	rootCmd.AddCommand(serveCmd)
 
 	// Serve-specific flags
-	serveCmd.Flags().StringVar(&apiURL, "api-url", "", "URL of the Manuals REST API")
-	serveCmd.Flags().StringVar(&apiKey, "api-key", "", "API key for authentication")
+	serveCmd.Flags().Bool("no-confirm", false, "execute destructive operations without human confirmation (for automation)")
+	serveCmd.Flags().String("key-sink", "", "store new API keys outside the transcript: file or keyring (default shows keys inline)")
+	serveCmd.Flags().String("key-dir", "", "directory for new API key files when --key-sink=file (default $HOME/.manuals-mcp/keys)")
+	serveCmd.Flags().String("allow-roots", "", "directories local_path may read from, separated by \":\" (\";\" on Windows; default system temp dir, \"none\" to disable)")
+	serveCmd.Flags().String("deny-patterns", "", "extra comma-separated path patterns local_path may never read (added to built-in key and credential patterns)")
+	serveCmd.Flags().Int64("max-file-size", 25, "largest file local_path may read, in MB")
+	serveCmd.Flags().Bool("watch-config", true, "reload the log level, API connection and file limits when the config file changes")
+	serveCmd.Flags().String("metrics-listen", "", "serve Prometheus metrics on this address, e.g. 127.0.0.1:9464 (default off)")
+	serveCmd.Flags().String("trace-exporter", "", "export traces of tool calls and API requests: none, otlp, or stdout (written to stderr)")
+	serveCmd.Flags().String("trace-endpoint", "", "OTLP/HTTP collector URL for --trace-exporter=otlp (default http://localhost:4318)")
+	serveCmd.Flags().String("audit-log", "", "audit log file for mutating tool calls (default $HOME/.manuals-mcp/audit.jsonl, \"off\" to disable)")
 
 	// Bind flags to viper
-	viper.BindPFlag("api.url", serveCmd.Flags().Lookup("api-url"))
-	viper.BindPFlag("api.key", serveCmd.Flags().Lookup("api-key"))
+	viper.BindPFlag("confirm.disabled", serveCmd.Flags().Lookup("no-confirm"))
+	viper.BindPFlag("config.watch", serveCmd.Flags().Lookup("watch-config"))
+	viper.BindPFlag("audit.file", serveCmd.Flags().Lookup("audit-log"))
+	viper.BindPFlag("tracing.exporter", serveCmd.Flags().Lookup("trace-exporter"))
+	viper.BindPFlag("tracing.endpoint", serveCmd.Flags().Lookup("trace-endpoint"))
+	viper.BindPFlag("metrics.listen", serveCmd.Flags().Lookup("metrics-listen"))
+	viper.BindPFlag("secrets.sink", serveCmd.Flags().Lookup("key-sink"))
+	viper.BindPFlag("secrets.dir", serveCmd.Flags().Lookup("key-dir"))
+	viper.BindPFlag("files.allowed_roots", serveCmd.Flags().Lookup("allow-roots"))
+	viper.BindPFlag("files.deny_patterns", serveCmd.Flags().Lookup("deny-patterns"))
+	viper.BindPFlag("files.max_size_mb", serveCmd.Flags().Lookup("max-file-size"))
+
+	viper.SetDefault("git.email_domain", "manuals-mcp.local")
 }
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/settings"
+	"github.com/spf13/cobra"
+)
+
+// settingsCmd groups API settings subcommands. All require Admin role.
+var settingsCmd = &cobra.Command{
+	Use:   "settings",
+	Short: "View and change API settings (requires Admin role)",
+}
+
+// settingsListCmd lists settings.
+var settingsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List settings",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+		resp, err := c.ListSettings()
+		if err != nil {
+			return err
+		}
+		return printResult(cmd, resp, func(w io.Writer) {
+			fmt.Fprintln(w, "KEY\tVALUE\tUPDATED")
+			for _, s := range resp.Settings {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", s.Key, s.Value, orDash(s.UpdatedAt))
+			}
+		})
+	},
+}
+
+// settingsSetCmd validates and updates a setting.
+var settingsSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Validate and update a setting",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+		key, value := args[0], args[1]
+
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		var schema settings.Schema
+		if resp, err := c.SettingsSchema(); err != nil {
+			// A dry run changes nothing, so unvalidated values are only a warning
+			if force, _ := cmd.Flags().GetBool("force"); !force && !dryRun {
+				return fmt.Errorf("setting types unavailable, value cannot be validated (use --force to write it anyway): %w", err)
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Warning: setting types unavailable, value not validated: %v\n", err)
+		} else {
+			schema = settings.NewSchema(resp.Settings)
+			if value, err = schema.Validate(key, value); err != nil {
+				return err
+			}
+		}
+
+		current, err := c.ListSettings()
+		if err != nil {
+			return err
+		}
+		changes := settings.Diff(current.Settings, map[string]string{key: value}, schema)
+		if len(changes) == 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s is already %s\n", key, value)
+			return nil
+		}
+
+		ok, err := confirmPrompt(cmd, settings.FormatChanges(changes))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errCancelled
+		}
+		if err := c.UpdateSetting(key, value); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s = %s\n", key, value)
+		return nil
+	},
+}
+
+// settingsExportCmd writes settings as YAML.
+var settingsExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export settings as YAML",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+		resp, err := c.ListSettings()
+		if err != nil {
+			return err
+		}
+		data, err := settings.Export(resp.Settings, c.GetAPIURL(), time.Now())
+		if err != nil {
+			return err
+		}
+		out, _ := cmd.Flags().GetString("file")
+		return writeOutputFile(cmd, out, data)
+	},
+}
+
+// settingsImportCmd restores settings from YAML.
+var settingsImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Validate and restore settings from YAML",
+	Long: `Restore settings exported with "settings export". Every value is validated first,
+the differences are shown, and only changed settings are updated. Use "-" to read stdin.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+
+		var data []byte
+		if args[0] == "-" {
+			data, err = io.ReadAll(cmd.InOrStdin())
+		} else {
+			data, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read settings: %w", err)
+		}
+		desired, err := settings.Import(data)
+		if err != nil {
+			return err
+		}
+
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		var schema settings.Schema
+		if resp, err := c.SettingsSchema(); err != nil {
+			// A dry run changes nothing, so unvalidated values are only a warning
+			if force, _ := cmd.Flags().GetBool("force"); !force && !dryRun {
+				return fmt.Errorf("setting types unavailable, values cannot be validated (use --force to apply them anyway): %w", err)
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Warning: setting types unavailable, values not validated: %v\n", err)
+		} else {
+			schema = settings.NewSchema(resp.Settings)
+			for key, value := range desired {
+				if desired[key], err = schema.Validate(key, value); err != nil {
+					return fmt.Errorf("nothing was changed: %w", err)
+				}
+			}
+		}
+
+		current, err := c.ListSettings()
+		if err != nil {
+			return err
+		}
+		changes := settings.Diff(current.Settings, desired, schema)
+		if len(changes) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "Settings already match")
+			return nil
+		}
+
+		if dryRun {
+			fmt.Fprint(cmd.OutOrStdout(), settings.FormatChanges(changes))
+			return nil
+		}
+		ok, err := confirmPrompt(cmd, settings.FormatChanges(changes))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errCancelled
+		}
+
+		failed := 0
+		for _, ch := range changes {
+			if err := c.UpdateSetting(ch.Key, ch.New); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "failed to update %s: %v\n", ch.Key, err)
+				failed++
+				continue
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s = %s\n", ch.Key, ch.New)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d settings failed to update", failed, len(changes))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(settingsCmd)
+	settingsCmd.AddCommand(settingsListCmd, settingsSetCmd, settingsExportCmd, settingsImportCmd)
+	addOutputFlag(settingsCmd)
+
+	settingsExportCmd.Flags().StringP("file", "f", "-", "output file (\"-\" for stdout)")
+	settingsImportCmd.Flags().Bool("dry-run", false, "only validate and show the differences")
+	for _, c := range []*cobra.Command{settingsSetCmd, settingsImportCmd} {
+		c.Flags().BoolP("yes", "y", false, "skip the confirmation prompt")
+		c.Flags().Bool("force", false, "write values unvalidated if the server cannot provide setting types")
+	}
+}
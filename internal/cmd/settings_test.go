@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+	"github.com/spf13/viper"
+)
+
+func TestSettingsImport_DryRunWithoutSchema(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/versions":
+			json.NewEncoder(w).Encode(client.VersionsResponse{Versions: []string{client.APIVersion}})
+		case strings.HasSuffix(r.URL.Path, "/admin/settings/schema"):
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(client.ErrorResponse{Error: "schema unavailable"})
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/admin/settings"):
+			json.NewEncoder(w).Encode(client.SettingsResponse{Settings: []client.Setting{{Key: "site.name", Value: "old"}}})
+		default:
+			t.Errorf("unexpected API request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	t.Cleanup(api.Close)
+
+	viper.SetConfigType("yaml")
+	viper.ReadConfig(strings.NewReader("api:\n  url: \"" + api.URL + "\"\n"))
+	t.Cleanup(func() {
+		viper.SetConfigType("yaml")
+		viper.ReadConfig(strings.NewReader(""))
+		settingsImportCmd.Flags().Set("dry-run", "false")
+		settingsImportCmd.SetOut(nil)
+		settingsImportCmd.SetErr(nil)
+	})
+
+	file := filepath.Join(t.TempDir(), "settings.yaml")
+	if err := os.WriteFile(file, []byte("settings:\n  site.name: new\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	settingsImportCmd.SetOut(&out)
+	settingsImportCmd.SetErr(&out)
+	settingsImportCmd.Flags().Set("dry-run", "true")
+	if err := settingsImportCmd.RunE(settingsImportCmd, []string{file}); err != nil {
+		t.Fatalf("settings import --dry-run error = %v", err)
+	}
+	if !strings.Contains(out.String(), "+ site.name: new") {
+		t.Errorf("settings import --dry-run output = %q, want the change", out.String())
+	}
+}
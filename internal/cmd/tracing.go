@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// Trace exporters accepted by --trace-exporter.
+const (
+	traceNone   = "none"
+	traceOTLP   = "otlp"
+	traceStdout = "stdout"
+)
+
+// setupTracing installs the global tracer provider for the tracing.*
+// settings and propagates trace context to the API in the W3C traceparent
+// header. The returned function flushes pending spans and stops the
+// exporter.
+func setupTracing(ctx context.Context) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch name := viper.GetString("tracing.exporter"); name {
+	case "", traceNone:
+		return func(context.Context) error { return nil }, nil
+	case traceOTLP:
+		var opts []otlptracehttp.Option
+		if endpoint := viper.GetString("tracing.endpoint"); endpoint != "" {
+			u, err := otlpEndpoint(endpoint)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, otlptracehttp.WithEndpointURL(u))
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	case traceStdout:
+		// stdout carries the MCP stream, so spans are written to stderr
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+	default:
+		return nil, fmt.Errorf("invalid trace exporter: %s (must be none, otlp, or stdout)", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the defaults
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName("manuals-mcp"), semconv.ServiceVersion(version)),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+// otlpEndpoint validates an OTLP/HTTP collector URL and adds the default
+// traces path if it has none.
+func otlpEndpoint(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid trace endpoint: %q (must be an http or https URL, e.g. http://localhost:4318)", endpoint)
+	}
+	if strings.Trim(u.Path, "/") == "" {
+		u.Path = "/v1/traces"
+	}
+	return u.String(), nil
+}
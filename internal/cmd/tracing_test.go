@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestOTLPEndpoint(t *testing.T) {
+	tests := map[string]string{
+		"http://localhost:4318":          "http://localhost:4318/v1/traces",
+		"https://otel.example.com/":      "https://otel.example.com/v1/traces",
+		"http://collector:4318/custom":   "http://collector:4318/custom",
+		"localhost:4318":                 "",
+		"grpc://collector:4317/v1/trace": "",
+	}
+	for in, want := range tests {
+		got, err := otlpEndpoint(in)
+		if want == "" {
+			if err == nil {
+				t.Errorf("otlpEndpoint(%q) should fail, got %q", in, got)
+			}
+			continue
+		}
+		if err != nil || got != want {
+			t.Errorf("otlpEndpoint(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+}
+
+func TestSetupTracing_InvalidExporter(t *testing.T) {
+	viper.Set("tracing.exporter", "jaeger")
+	t.Cleanup(func() { viper.Set("tracing.exporter", "") })
+
+	if _, err := setupTracing(context.Background()); err == nil {
+		t.Error("setupTracing() should reject an unknown exporter")
+	}
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+	"github.com/spf13/cobra"
+)
+
+// usersCmd groups user administration subcommands. All require Admin role.
+var usersCmd = &cobra.Command{
+	Use:   "users",
+	Short: "Manage users and API keys (requires Admin role)",
+}
+
+// usersListCmd lists users.
+var usersListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List users",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+		resp, err := c.ListUsers()
+		if err != nil {
+			return err
+		}
+		return printResult(cmd, resp, func(w io.Writer) {
+			fmt.Fprintln(w, "ID\tNAME\tROLE\tCAPABILITIES\tACTIVE\tLAST SEEN\tKEY EXPIRES")
+			for _, u := range resp.Users {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%s\t%s\n",
+					u.ID, u.Name, u.Role(), u.CapabilitiesString(), u.IsActive, orDash(u.LastSeenAt), orDash(u.KeyExpiresAt))
+			}
+		})
+	},
+}
+
+// usersCreateCmd creates a user.
+var usersCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a user and print its API key",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+		role, _ := cmd.Flags().GetString("role")
+		resp, err := c.CreateUser(args[0], role, expiryFlag(cmd))
+		if err != nil {
+			return err
+		}
+		return printResult(cmd, resp, func(w io.Writer) {
+			fmt.Fprintf(w, "ID:\t%s\n", resp.User.ID)
+			fmt.Fprintf(w, "Name:\t%s\n", resp.User.Name)
+			fmt.Fprintf(w, "Role:\t%s\n", resp.User.Role())
+			fmt.Fprintf(w, "Key Expires:\t%s\n", orDash(resp.User.KeyExpiresAt))
+			fmt.Fprintf(w, "API Key:\t%s\n", resp.APIKey)
+		})
+	},
+}
+
+// usersDeleteCmd deletes a user.
+var usersDeleteCmd = &cobra.Command{
+	Use:   "delete <user-id>",
+	Short: "Delete a user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+		ok, err := confirmPrompt(cmd, fmt.Sprintf("Delete user %s and invalidate their API key? This cannot be undone.", args[0]))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errCancelled
+		}
+		if err := c.DeleteUser(args[0]); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Deleted user %s\n", args[0])
+		return nil
+	},
+}
+
+// usersRoleCmd changes a user's role.
+var usersRoleCmd = &cobra.Command{
+	Use:   "role <user-id> <admin|rw|ro>",
+	Short: "Change a user's role",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+		if err := c.UpdateUserRole(args[0], args[1]); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "User %s role changed to %s\n", args[0], args[1])
+		return nil
+	},
+}
+
+// usersCapabilitiesCmd replaces a user's capabilities.
+var usersCapabilitiesCmd = &cobra.Command{
+	Use:   "capabilities <user-id> <capability>...",
+	Short: "Set a user's capabilities (e.g. read:* write:publish)",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+		caps, err := client.ParseCapabilities(strings.Join(args[1:], ","))
+		if err != nil {
+			return err
+		}
+		if len(caps) == 0 {
+			return fmt.Errorf("at least one capability is required; use 'users disable' to disable an account")
+		}
+		if err := c.UpdateUserCapabilities(args[0], caps); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "User %s capabilities set to %s\n", args[0], strings.Join(caps, ", "))
+		return nil
+	},
+}
+
+// usersActiveCmd returns a command that deactivates or reactivates a user.
+func usersActiveCmd(use string, active bool) *cobra.Command {
+	short := "Reactivate a user"
+	if !active {
+		short = "Deactivate a user without deleting it"
+	}
+	return &cobra.Command{
+		Use:   use + " <user-id>",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newAPIClient()
+			if err != nil {
+				return err
+			}
+			if err := c.SetUserActive(args[0], active); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "User %s %sd\n", args[0], use)
+			return nil
+		},
+	}
+}
+
+// usersRenameCmd renames a user.
+var usersRenameCmd = &cobra.Command{
+	Use:   "rename <user-id> <name>",
+	Short: "Rename a user",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+		if err := c.RenameUser(args[0], args[1]); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "User %s renamed to %s\n", args[0], args[1])
+		return nil
+	},
+}
+
+// usersRotateKeyCmd rotates a user's API key.
+var usersRotateKeyCmd = &cobra.Command{
+	Use:   "rotate-key <user-id>",
+	Short: "Rotate a user's API key and print the new key",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+		ok, err := confirmPrompt(cmd, fmt.Sprintf("Rotate the API key of user %s? The current key stops working immediately.", args[0]))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errCancelled
+		}
+		resp, err := c.RotateAPIKey(args[0], expiryFlag(cmd))
+		if err != nil {
+			return err
+		}
+		return printResult(cmd, resp, func(w io.Writer) {
+			fmt.Fprintf(w, "User:\t%s\n", args[0])
+			fmt.Fprintf(w, "Key Expires:\t%s\n", orDash(resp.ExpiresAt))
+			fmt.Fprintf(w, "API Key:\t%s\n", resp.APIKey)
+		})
+	},
+}
+
+// expiryFlag converts --expires-in into an absolute key expiry, or the zero
+// time when the key should not expire.
+func expiryFlag(cmd *cobra.Command) time.Time {
+	d, _ := cmd.Flags().GetDuration("expires-in")
+	if d <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(d)
+}
+
+func init() {
+	rootCmd.AddCommand(usersCmd)
+	usersCmd.AddCommand(usersListCmd, usersCreateCmd, usersDeleteCmd, usersRoleCmd, usersCapabilitiesCmd,
+		usersActiveCmd("disable", false), usersActiveCmd("enable", true), usersRenameCmd, usersRotateKeyCmd)
+	addOutputFlag(usersCmd)
+
+	usersCreateCmd.Flags().String("role", "ro", "role: admin, rw, or ro")
+	for _, c := range []*cobra.Command{usersCreateCmd, usersRotateKeyCmd} {
+		c.Flags().Duration("expires-in", 0, "key lifetime, e.g. 2160h for 90 days (default never expires)")
+	}
+	for _, c := range []*cobra.Command{usersDeleteCmd, usersRotateKeyCmd} {
+		c.Flags().BoolP("yes", "y", false, "skip the confirmation prompt")
+	}
+}
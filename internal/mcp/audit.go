@@ -0,0 +1,264 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rmrfslashbin/manuals-mcp/internal/audit"
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+)
+
+// mutatingTools lists the RW and admin tools recorded in the audit log.
+var mutatingTools = map[string]bool{
+	"trigger_reindex":       true,
+	"upload_file":           true,
+	"publish":               true,
+	"publish_batch":         true,
+	"delete_file":           true,
+	"sync_to_git":           true,
+	"create_user":           true,
+	"delete_user":           true,
+	"update_user_role":      true,
+	"set_user_capabilities": true,
+	"set_user_active":       true,
+	"rename_user":           true,
+	"rotate_api_key":        true,
+	"rotate_stale_keys":     true,
+	"update_setting":        true,
+	"import_settings":       true,
+	"switch_profile":        true,
+}
+
+// maxAuditError limits the error text stored per audit entry.
+const maxAuditError = 500
+
+type auditRecorderKey struct{}
+
+// auditRecorder collects API response identifiers and status overrides
+// from a handler, and the backend the call was routed to, for the audit
+// entry of the current call.
+type auditRecorder struct {
+	mu      sync.Mutex
+	refs    map[string]string
+	status  string
+	backend *Backend
+}
+
+// auditRef records an API response identifier for the current tool call.
+// It is a no-op when the call is not being audited.
+func auditRef(ctx context.Context, key, value string) {
+	rec, ok := ctx.Value(auditRecorderKey{}).(*auditRecorder)
+	if !ok || value == "" {
+		return
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.refs == nil {
+		rec.refs = make(map[string]string)
+	}
+	rec.refs[key] = value
+}
+
+// auditStatus overrides the status recorded for the current tool call.
+func auditStatus(ctx context.Context, status string) {
+	if rec, ok := ctx.Value(auditRecorderKey{}).(*auditRecorder); ok {
+		rec.mu.Lock()
+		rec.status = status
+		rec.mu.Unlock()
+	}
+}
+
+// auditBackend records the backend the current tool call was routed to, so
+// the audit entry names the user of that backend. It is a no-op when the
+// call is not being audited.
+func auditBackend(ctx context.Context, b Backend) {
+	if rec, ok := ctx.Value(auditRecorderKey{}).(*auditRecorder); ok {
+		rec.mu.Lock()
+		rec.backend = &b
+		rec.mu.Unlock()
+	}
+}
+
+// actingUser returns the name of the user authenticated with the backend
+// of the current call, caching the result after the first successful
+// lookup. The lookup runs without holding userMu so a slow backend does not
+// stall audit entries for the others.
+func (s *Server) actingUser(ctx context.Context) string {
+	c := s.selectedBackend(ctx).Client
+	s.userMu.Lock()
+	name := s.userNames[c]
+	s.userMu.Unlock()
+	if name != "" {
+		return name
+	}
+	if !c.HasAPIKey() {
+		return "anonymous"
+	}
+	user, err := c.WithContext(ctx).GetMe()
+	if err != nil || user == nil {
+		return "unknown"
+	}
+	s.userMu.Lock()
+	if s.userNames == nil {
+		s.userNames = make(map[*client.Client]string)
+	}
+	s.userNames[c] = user.Name
+	s.userMu.Unlock()
+	return user.Name
+}
+
+// forgetUserNames clears the acting user cache, e.g. after a rename, so
+// later audit entries pick up the current names.
+func (s *Server) forgetUserNames() {
+	s.userMu.Lock()
+	clear(s.userNames)
+	s.userMu.Unlock()
+}
+
+// auditMiddleware writes an audit entry for every mutating tool call.
+func (s *Server) auditMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if s.audit == nil || !mutatingTools[request.Params.Name] {
+			return next(ctx, request)
+		}
+
+		rec := &auditRecorder{}
+		ctx = context.WithValue(ctx, auditRecorderKey{}, rec)
+		start := time.Now()
+
+		result, err := next(ctx, request)
+
+		entry := audit.Entry{
+			Time:       start.UTC(),
+			Tool:       request.Params.Name,
+			Args:       audit.Redact(request.GetArguments()),
+			Status:     audit.StatusSuccess,
+			DurationMS: time.Since(start).Milliseconds(),
+		}
+		rec.mu.Lock()
+		entry.Refs = rec.refs
+		if rec.status != "" {
+			entry.Status = rec.status
+		}
+		userCtx := ctx
+		if rec.backend != nil {
+			userCtx = context.WithValue(ctx, backendKey{}, *rec.backend)
+		}
+		rec.mu.Unlock()
+		entry.User = s.actingUser(userCtx)
+
+		switch {
+		case err != nil:
+			entry.Status = audit.StatusError
+			entry.Error = err.Error()
+		case result != nil && result.IsError:
+			entry.Status = audit.StatusError
+			entry.Error = resultErrorText(result)
+		}
+
+		if werr := s.audit.Write(entry); werr != nil {
+			s.logger.Error("failed to write audit entry", "tool", entry.Tool, "error", werr)
+		}
+		return result, err
+	}
+}
+
+// resultErrorText extracts a bounded error message from a tool result.
+func resultErrorText(result *mcp.CallToolResult) string {
+	var sb strings.Builder
+	for _, c := range result.Content {
+		if text, ok := c.(mcp.TextContent); ok {
+			sb.WriteString(text.Text)
+		}
+	}
+	text := sb.String()
+	if len(text) > maxAuditError {
+		text = text[:maxAuditError] + "..."
+	}
+	return text
+}
+
+func (s *Server) handleAuditLog(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if s.audit == nil {
+		return mcp.NewToolResultError("audit log is disabled on this server"), nil
+	}
+
+	// The log records every user's calls, so only admins may read it
+	user, err := s.api(ctx).GetMe()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to check role: %v", err)), nil
+	}
+	if user == nil || !user.CanAdmin() {
+		return mcp.NewToolResultError("reading the audit log requires the Admin role"), nil
+	}
+
+	args := request.GetArguments()
+	filter := audit.Filter{Limit: 50}
+	filter.Tool, _ = args["tool"].(string)
+	filter.User, _ = args["user"].(string)
+	filter.Status, _ = args["status"].(string)
+	if l, ok := args["limit"].(float64); ok && l > 0 {
+		filter.Limit = int(l)
+	}
+	if since, _ := args["since"].(string); since != "" {
+		t, err := parseSince(since)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		filter.Since = t
+	}
+
+	entries, err := s.audit.Query(filter)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read audit log: %v", err)), nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Audit Log (%d entries)\n\n", len(entries)))
+	if len(entries) == 0 {
+		sb.WriteString("No matching entries.\n")
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+
+	sb.WriteString("| Time | User | Tool | Status | Details |\n")
+	sb.WriteString("|------|------|------|--------|---------|\n")
+	for _, e := range entries {
+		var details []string
+		for _, k := range slices.Sorted(maps.Keys(e.Args)) {
+			details = append(details, fmt.Sprintf("%s=%v", k, e.Args[k]))
+		}
+		for _, k := range slices.Sorted(maps.Keys(e.Refs)) {
+			details = append(details, fmt.Sprintf("→%s=%s", k, e.Refs[k]))
+		}
+		if e.Error != "" {
+			msg, _, _ := strings.Cut(e.Error, "\n")
+			details = append(details, "error: "+msg)
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s |\n",
+			e.Time.Format(time.RFC3339), e.User, e.Tool, e.Status, strings.Join(details, ", ")))
+	}
+
+	return mcp.NewToolResultText(sb.String()), nil
+}
+
+// parseSince parses a date, RFC 3339 timestamp, or relative duration such
+// as "24h" into an absolute time.
+func parseSince(since string) (time.Time, error) {
+	if d, err := time.ParseDuration(since); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, since); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", since); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid since value %q (use a duration like 24h, a date like 2025-12-01, or RFC 3339)", since)
+}
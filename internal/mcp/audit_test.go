@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/audit"
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+)
+
+func TestAuditMiddleware(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/me"):
+			json.NewEncoder(w).Encode(client.MeResponse{User: client.User{Name: "alice"}})
+		case strings.HasSuffix(r.URL.Path, "/rw/upload"):
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(client.UploadResponse{Path: "sensors/a/README.md"})
+		}
+	})
+	log, err := audit.Open(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatalf("audit.Open() error = %v", err)
+	}
+	defer log.Close()
+	WithAuditLog(log)(s)
+
+	handler := s.auditMiddleware(s.handleUploadFile)
+	handler(context.Background(), toolRequest("upload_file", map[string]any{
+		"dest_path": "sensors/a/README.md",
+		"content":   "# Secret draft",
+	}))
+	// Read-only tools are not audited
+	s.auditMiddleware(s.handleGetStatus)(context.Background(), toolRequest("get_status", nil))
+
+	entries, err := log.Query(audit.Filter{})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("audit entries = %d, want 1", len(entries))
+	}
+	e := entries[0]
+	if e.User != "alice" || e.Tool != "upload_file" || e.Status != audit.StatusSuccess {
+		t.Errorf("audit entry = %+v", e)
+	}
+	if e.Refs["path"] != "sensors/a/README.md" {
+		t.Errorf("audit refs = %v, want path", e.Refs)
+	}
+	if e.Args["content"] != "[redacted 14 bytes]" {
+		t.Errorf("audit content arg = %v, want redacted", e.Args["content"])
+	}
+}
+
+func TestAuditLog_RequiresAdmin(t *testing.T) {
+	caps := []string{"read:*", "write:*"}
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(client.MeResponse{User: client.User{Name: "alice", Capabilities: caps}})
+	})
+	log, err := audit.Open(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatalf("audit.Open() error = %v", err)
+	}
+	defer log.Close()
+	WithAuditLog(log)(s)
+
+	result, _ := s.handleAuditLog(context.Background(), toolRequest("audit_log", nil))
+	if !result.IsError || !strings.Contains(resultText(result), "Admin role") {
+		t.Errorf("audit_log for a writer = %s, want Admin role error", resultText(result))
+	}
+
+	caps = []string{"*"}
+	result, _ = s.handleAuditLog(context.Background(), toolRequest("audit_log", nil))
+	if result.IsError {
+		t.Errorf("audit_log for an admin failed: %s", resultText(result))
+	}
+}
+
+func TestActingUser_ForgetsRenamedUser(t *testing.T) {
+	name := "alice"
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			name = "alice2"
+			return
+		}
+		json.NewEncoder(w).Encode(client.MeResponse{User: client.User{ID: "usr_me", Name: name}})
+	})
+
+	if got := s.actingUser(context.Background()); got != "alice" {
+		t.Fatalf("actingUser() = %q, want alice", got)
+	}
+	result, _ := s.handleRenameUser(context.Background(), toolRequest("rename_user", map[string]any{"user_id": "usr_me", "name": "alice2"}))
+	if result.IsError {
+		t.Fatalf("rename_user failed: %s", resultText(result))
+	}
+	if got := s.actingUser(context.Background()); got != "alice2" {
+		t.Errorf("actingUser() after rename = %q, want alice2", got)
+	}
+}
@@ -0,0 +1,405 @@
+package mcp
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+)
+
+// Backend is a named Manuals API instance.
+type Backend struct {
+	Name   string
+	Client *client.Client
+}
+
+// serverTools act on the MCP server itself rather than on a backend, so
+// they take no backend argument.
+var serverTools = map[string]bool{
+	"switch_profile": true,
+}
+
+// idArgs are the tool arguments that hold backend-scoped identifiers.
+// With several backends they may be namespaced as "backend:id".
+var idArgs = []string{"device_id", "document_id", "guide_id"}
+
+// WithBackends serves several Manuals API instances. Read tools query all
+// of them and merge the results, namespacing IDs as "backend:id"; write
+// tools require a "backend" argument. The first backend is the primary and
+// answers tools that are not federated when no backend is given.
+func WithBackends(backends []Backend) Option {
+	return func(s *Server) {
+		if len(backends) == 0 {
+			return
+		}
+		s.backends = backends
+	}
+}
+
+type backendKey struct{}
+
+// backendList returns the configured backends, primary first.
+func (s *Server) backendList() []Backend {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.backends
+}
+
+// Backends returns the API backends in use, primary first.
+func (s *Server) Backends() []Backend {
+	return slices.Clone(s.backendList())
+}
+
+// SetBackends replaces the API backends while the server runs and
+// re-registers the tools so their backend arguments match. Tool calls
+// already in progress finish on the old clients, whose idle connections
+// are closed.
+func (s *Server) SetBackends(backends []Backend) {
+	if len(backends) == 0 {
+		return
+	}
+	s.mu.Lock()
+	old := s.backends
+	s.backends = backends
+	s.mu.Unlock()
+	s.registerTools()
+
+	for _, b := range old {
+		if slices.ContainsFunc(backends, func(n Backend) bool { return n.Client == b.Client }) {
+			continue
+		}
+		b.Client.CloseIdleConnections()
+		s.userMu.Lock()
+		delete(s.userNames, b.Client)
+		s.userMu.Unlock()
+	}
+}
+
+// api returns the client for the backend selected for the current tool
+// call, or the primary backend, making its requests within ctx.
+func (s *Server) api(ctx context.Context) *client.Client {
+	return s.selectedBackend(ctx).Client.WithContext(ctx)
+}
+
+// federated reports whether read tools fan out to several backends.
+func (s *Server) federated() bool {
+	return len(s.backendList()) > 1
+}
+
+// backend returns the backend with the given name.
+func (s *Server) backend(name string) (Backend, bool) {
+	for _, b := range s.backendList() {
+		if b.Name == name {
+			return b, true
+		}
+	}
+	return Backend{}, false
+}
+
+// backendNames returns the configured backend names in order.
+func (s *Server) backendNames() []string {
+	backends := s.backendList()
+	names := make([]string, len(backends))
+	for i, b := range backends {
+		names[i] = b.Name
+	}
+	return names
+}
+
+// addTool registers a tool, adding the "backend" argument when several
+// backends are configured.
+func (s *Server) addTool(tool mcp.Tool, handler server.ToolHandlerFunc) {
+	if s.federated() && !serverTools[tool.Name] {
+		desc := "Backend to use: " + strings.Join(s.backendNames(), ", ") + "."
+		if mutatingTools[tool.Name] {
+			desc += " Required."
+		} else {
+			desc += " Defaults to all backends for searches and listings, otherwise to the namespace of the ID or " + s.backendList()[0].Name + "."
+		}
+		mcp.WithString("backend", mcp.Description(desc), mcp.Enum(s.backendNames()...))(&tool)
+	}
+	s.mcp.AddTool(tool, handler)
+}
+
+// backendMiddleware selects the backend for a tool call from its "backend"
+// argument or a namespaced ID, stripping the namespace before the handler
+// sees it. Mutating tools must name their backend explicitly.
+func (s *Server) backendMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if !s.federated() || serverTools[request.Params.Name] {
+			return next(ctx, request)
+		}
+
+		// Strip namespaces from a copy so the caller's arguments, which the
+		// audit log records, keep them
+		args := maps.Clone(request.GetArguments())
+		name, _ := args["backend"].(string)
+		for _, key := range idArgs {
+			id, _ := args[key].(string)
+			prefix, rest, ok := strings.Cut(id, ":")
+			if !ok {
+				continue
+			}
+			if _, known := s.backend(prefix); !known {
+				continue
+			}
+			if name != "" && name != prefix {
+				return mcp.NewToolResultError(fmt.Sprintf("%s %q belongs to backend %q, not %q", key, id, prefix, name)), nil
+			}
+			name = prefix
+			args[key] = rest
+		}
+		request.Params.Arguments = args
+
+		if name == "" {
+			if mutatingTools[request.Params.Name] {
+				return mcp.NewToolResultError(fmt.Sprintf("%s changes data, so it needs an explicit backend: %s", request.Params.Name, strings.Join(s.backendNames(), ", "))), nil
+			}
+			return next(ctx, request)
+		}
+		b, ok := s.backend(name)
+		if !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("unknown backend %q (configured: %s)", name, strings.Join(s.backendNames(), ", "))), nil
+		}
+		auditBackend(ctx, b)
+		return next(context.WithValue(ctx, backendKey{}, b), request)
+	}
+}
+
+// resolveID returns the client for a possibly namespaced ID, and the ID
+// without its namespace.
+func (s *Server) resolveID(id string) (*client.Client, string) {
+	if prefix, rest, ok := strings.Cut(id, ":"); ok && s.federated() {
+		if b, known := s.backend(prefix); known {
+			return b.Client, rest
+		}
+	}
+	return s.backendList()[0].Client, id
+}
+
+// targets returns the backends a read tool should query: the selected
+// backend, or every backend when none was selected.
+func (s *Server) targets(ctx context.Context) []Backend {
+	if b, ok := ctx.Value(backendKey{}).(Backend); ok {
+		return []Backend{b}
+	}
+	return s.backendList()
+}
+
+// qualify namespaces id with the backend name when several backends are
+// configured, so it can be passed back to any tool.
+func (s *Server) qualify(b Backend, id string) string {
+	if !s.federated() || id == "" {
+		return id
+	}
+	return b.Name + ":" + id
+}
+
+// backendResult is the outcome of one backend's part of a fan-out.
+type backendResult[T any] struct {
+	backend Backend
+	value   T
+	err     error
+}
+
+// fanOut calls fn for each target backend concurrently. It returns the
+// successful results in backend order and a warning for each backend that
+// failed, or the first error if every backend failed.
+func fanOut[T any](ctx context.Context, s *Server, fn func(c *client.Client) (T, error)) ([]backendResult[T], []string, error) {
+	targets := s.targets(ctx)
+	results := make([]backendResult[T], len(targets))
+	var wg sync.WaitGroup
+	for i, b := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := fn(b.Client.WithContext(ctx))
+			results[i] = backendResult[T]{backend: b, value: v, err: err}
+		}()
+	}
+	wg.Wait()
+
+	var ok []backendResult[T]
+	var warnings []string
+	for _, r := range results {
+		if r.err != nil {
+			warnings = append(warnings, fmt.Sprintf("backend %s: %v", r.backend.Name, r.err))
+			continue
+		}
+		ok = append(ok, r)
+	}
+	if len(ok) == 0 {
+		return nil, nil, results[0].err
+	}
+	return ok, warnings, nil
+}
+
+// writeBackendWarnings notes backends that could not be queried.
+func writeBackendWarnings(sb *strings.Builder, warnings []string) {
+	if len(warnings) == 0 {
+		return
+	}
+	sb.WriteString("\n**Partial results** - some backends failed:\n")
+	for _, w := range warnings {
+		sb.WriteString(fmt.Sprintf("- %s\n", w))
+	}
+}
+
+// search runs a full-text search on every target backend and merges the
+// results by score.
+func (s *Server) search(ctx context.Context, query string, limit int, domain, deviceType string) (*client.SearchResponse, []string, error) {
+	results, warnings, err := fanOut(ctx, s, func(c *client.Client) (*client.SearchResponse, error) {
+		return c.Search(query, limit, domain, deviceType)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(results) == 1 && !s.federated() {
+		return results[0].value, warnings, nil
+	}
+	merged := &client.SearchResponse{Query: query}
+	for _, r := range results {
+		merged.Total += r.value.Total
+		for _, hit := range r.value.Results {
+			hit.DeviceID = s.qualify(r.backend, hit.DeviceID)
+			merged.Results = append(merged.Results, hit)
+		}
+	}
+	slices.SortStableFunc(merged.Results, func(a, b client.SearchResult) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	merged.Results = truncate(merged.Results, limit)
+	return merged, warnings, nil
+}
+
+// semanticSearch runs a semantic search on every target backend and merges
+// the results by score.
+func (s *Server) semanticSearch(ctx context.Context, query string, limit int, domain, deviceType string) (*client.SemanticSearchResponse, []string, error) {
+	results, warnings, err := fanOut(ctx, s, func(c *client.Client) (*client.SemanticSearchResponse, error) {
+		return c.SemanticSearch(query, limit, domain, deviceType)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(results) == 1 && !s.federated() {
+		return results[0].value, warnings, nil
+	}
+	merged := &client.SemanticSearchResponse{Query: query}
+	for _, r := range results {
+		for _, hit := range r.value.Results {
+			hit.DeviceID = s.qualify(r.backend, hit.DeviceID)
+			merged.Results = append(merged.Results, hit)
+		}
+	}
+	slices.SortStableFunc(merged.Results, func(a, b client.SemanticSearchResult) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	merged.Results = truncate(merged.Results, limit)
+	merged.Count = len(merged.Results)
+	return merged, warnings, nil
+}
+
+// listDevices lists devices on every target backend, merged by name.
+func (s *Server) listDevices(ctx context.Context, limit int, domain, deviceType string) (*client.DevicesResponse, []string, error) {
+	results, warnings, err := fanOut(ctx, s, func(c *client.Client) (*client.DevicesResponse, error) {
+		return c.ListDevices(limit, 0, domain, deviceType)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(results) == 1 && !s.federated() {
+		return results[0].value, warnings, nil
+	}
+	merged := &client.DevicesResponse{Limit: limit}
+	for _, r := range results {
+		merged.Total += r.value.Total
+		for _, d := range r.value.Data {
+			d.ID = s.qualify(r.backend, d.ID)
+			merged.Data = append(merged.Data, d)
+		}
+	}
+	slices.SortStableFunc(merged.Data, func(a, b client.Device) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	merged.Data = truncate(merged.Data, limit)
+	return merged, warnings, nil
+}
+
+// listDocuments lists documents on every target backend, merged by
+// filename.
+func (s *Server) listDocuments(ctx context.Context, limit int, deviceID string) (*client.DocumentsResponse, []string, error) {
+	results, warnings, err := fanOut(ctx, s, func(c *client.Client) (*client.DocumentsResponse, error) {
+		return c.ListDocuments(limit, 0, deviceID)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(results) == 1 && !s.federated() {
+		return results[0].value, warnings, nil
+	}
+	merged := &client.DocumentsResponse{Limit: limit}
+	for _, r := range results {
+		merged.Total += r.value.Total
+		for _, d := range r.value.Data {
+			d.ID = s.qualify(r.backend, d.ID)
+			d.DeviceID = s.qualify(r.backend, d.DeviceID)
+			merged.Data = append(merged.Data, d)
+		}
+	}
+	slices.SortStableFunc(merged.Data, func(a, b client.Document) int {
+		return cmp.Compare(a.Filename, b.Filename)
+	})
+	merged.Data = truncate(merged.Data, limit)
+	return merged, warnings, nil
+}
+
+// listGuides lists guides on every target backend, merged by title.
+func (s *Server) listGuides(ctx context.Context, limit int) (*client.GuidesResponse, []string, error) {
+	results, warnings, err := fanOut(ctx, s, func(c *client.Client) (*client.GuidesResponse, error) {
+		return c.ListGuides(limit, 0)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(results) == 1 && !s.federated() {
+		return results[0].value, warnings, nil
+	}
+	merged := &client.GuidesResponse{Limit: limit}
+	for _, r := range results {
+		merged.Total += r.value.Total
+		for _, g := range r.value.Data {
+			g.ID = s.qualify(r.backend, g.ID)
+			merged.Data = append(merged.Data, g)
+		}
+	}
+	slices.SortStableFunc(merged.Data, func(a, b client.Guide) int {
+		return cmp.Compare(a.Title, b.Title)
+	})
+	merged.Data = truncate(merged.Data, limit)
+	return merged, warnings, nil
+}
+
+// truncate returns at most limit items of s. A non-positive limit keeps
+// everything.
+func truncate[T any](s []T, limit int) []T {
+	if limit > 0 && len(s) > limit {
+		return s[:limit]
+	}
+	return s
+}
+
+// selectedBackend returns the backend chosen for the current tool call,
+// or the primary backend.
+func (s *Server) selectedBackend(ctx context.Context) Backend {
+	if b, ok := ctx.Value(backendKey{}).(Backend); ok {
+		return b
+	}
+	return s.backendList()[0]
+}
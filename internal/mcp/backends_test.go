@@ -0,0 +1,184 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/audit"
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+)
+
+// newFederatedServer creates a server with a "public" and a "private"
+// backend, each answering search and device requests for its own devices.
+func newFederatedServer(t *testing.T) (*Server, map[string][]string) {
+	t.Helper()
+	var mu sync.Mutex
+	requests := make(map[string][]string)
+	backend := func(name string, hits []client.SearchResult) Backend {
+		api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path := strings.TrimPrefix(r.URL.Path, "/api/"+client.APIVersion)
+			mu.Lock()
+			requests[name] = append(requests[name], r.Method+" "+path)
+			mu.Unlock()
+			switch {
+			case path == "/search":
+				json.NewEncoder(w).Encode(client.SearchResponse{Query: r.URL.Query().Get("q"), Total: len(hits), Results: hits})
+			case strings.HasPrefix(path, "/devices/"):
+				id := strings.TrimPrefix(path, "/devices/")
+				json.NewEncoder(w).Encode(client.Device{ID: id, Name: name + " " + id})
+			case path == "/devices":
+				var resp client.DevicesResponse
+				for _, h := range hits {
+					resp.Data = append(resp.Data, client.Device{ID: h.DeviceID, Name: h.Name})
+				}
+				resp.Total = len(resp.Data)
+				json.NewEncoder(w).Encode(resp)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(client.ErrorResponse{})
+			}
+		}))
+		t.Cleanup(api.Close)
+		return Backend{Name: name, Client: client.New(api.URL, "test-key")}
+	}
+
+	public := backend("public", []client.SearchResult{
+		{DeviceID: "bme280", Name: "BME280", Score: 0.5},
+		{DeviceID: "esp32", Name: "ESP32", Score: 2},
+	})
+	private := backend("private", []client.SearchResult{
+		{DeviceID: "widget", Name: "Widget Board", Score: 1},
+	})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := NewServer(nil, "test", "none", "now", logger, WithBackends([]Backend{public, private}))
+	WithConfirmations(false)(s)
+	return s, requests
+}
+
+func TestBackends_FederatedSearch(t *testing.T) {
+	s, _ := newFederatedServer(t)
+
+	result, err := s.backendMiddleware(s.handleSearch)(context.Background(), toolRequest("search_manuals", map[string]any{"query": "sensor"}))
+	if err != nil || result.IsError {
+		t.Fatalf("search failed: %v %s", err, resultText(result))
+	}
+	text := resultText(result)
+	if !strings.Contains(text, "Found 3 results") {
+		t.Errorf("merged total missing:\n%s", text)
+	}
+	esp, widget, bme := strings.Index(text, "public:esp32"), strings.Index(text, "private:widget"), strings.Index(text, "public:bme280")
+	if esp < 0 || widget < 0 || bme < 0 || !(esp < widget && widget < bme) {
+		t.Errorf("results should be namespaced and ordered by score:\n%s", text)
+	}
+
+	result, _ = s.backendMiddleware(s.handleSearch)(context.Background(), toolRequest("search_manuals", map[string]any{"query": "sensor", "backend": "private"}))
+	if text := resultText(result); strings.Contains(text, "public:") || !strings.Contains(text, "private:widget") {
+		t.Errorf("backend argument should limit the search:\n%s", text)
+	}
+}
+
+func TestBackends_FederatedSearchPartialFailure(t *testing.T) {
+	s, _ := newFederatedServer(t)
+	s.backends[1].Client = client.New("http://127.0.0.1:1", "")
+
+	result, _ := s.backendMiddleware(s.handleListDevices)(context.Background(), toolRequest("list_devices", nil))
+	text := resultText(result)
+	if result.IsError || !strings.Contains(text, "public:esp32") || !strings.Contains(text, "backend private:") {
+		t.Errorf("expected public results with a private warning:\n%s", text)
+	}
+}
+
+func TestBackends_NamespacedIDRouting(t *testing.T) {
+	s, requests := newFederatedServer(t)
+
+	result, _ := s.backendMiddleware(s.handleGetDevice)(context.Background(), toolRequest("get_device", map[string]any{"device_id": "private:widget"}))
+	text := resultText(result)
+	if !strings.Contains(text, "# private widget") || !strings.Contains(text, "**ID:** private:widget") {
+		t.Errorf("namespaced ID should route to private:\n%s", text)
+	}
+	if len(requests["public"]) != 0 {
+		t.Errorf("public backend should not be queried, got %v", requests["public"])
+	}
+
+	result, _ = s.backendMiddleware(s.handleGetDevice)(context.Background(), toolRequest("get_device", map[string]any{"device_id": "private:widget", "backend": "public"}))
+	if !result.IsError {
+		t.Errorf("conflicting backend and namespace should fail:\n%s", resultText(result))
+	}
+}
+
+func TestBackends_WriteRequiresBackend(t *testing.T) {
+	s, requests := newFederatedServer(t)
+
+	result, _ := s.backendMiddleware(s.handleTriggerReindex)(context.Background(), toolRequest("trigger_reindex", nil))
+	if !result.IsError || !strings.Contains(resultText(result), "public, private") {
+		t.Errorf("write without backend should fail listing backends:\n%s", resultText(result))
+	}
+
+	result, _ = s.backendMiddleware(s.handleTriggerReindex)(context.Background(), toolRequest("trigger_reindex", map[string]any{"backend": "staging"}))
+	if !result.IsError || !strings.Contains(resultText(result), "unknown backend") {
+		t.Errorf("unknown backend should fail:\n%s", resultText(result))
+	}
+
+	s.backendMiddleware(s.handleTriggerReindex)(context.Background(), toolRequest("trigger_reindex", map[string]any{"backend": "private"}))
+	if len(requests["private"]) != 1 || requests["private"][0] != "POST /rw/reindex" || len(requests["public"]) != 0 {
+		t.Errorf("reindex should go to private only, got %v", requests)
+	}
+}
+
+func TestSetBackends_ForgetsReplacedClients(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	old := s.Backends()[0].Client
+	s.userNames = map[*client.Client]string{old: "alice"}
+
+	replacement := client.New("http://127.0.0.1:1", "")
+	s.SetBackends([]Backend{{Name: "default", Client: replacement}})
+
+	if s.Backends()[0].Client != replacement {
+		t.Error("SetBackends() did not replace the backend")
+	}
+	if _, ok := s.userNames[old]; ok {
+		t.Error("SetBackends() kept the cached user of the replaced client")
+	}
+}
+
+func TestBackends_AuditsRejectedWrites(t *testing.T) {
+	s, _ := newFederatedServer(t)
+	log, err := audit.Open(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatalf("audit.Open() error = %v", err)
+	}
+	defer log.Close()
+	WithAuditLog(log)(s)
+	handler := s.auditMiddleware(s.backendMiddleware(s.handleDeleteFile))
+
+	handler(context.Background(), toolRequest("trigger_reindex", nil))
+
+	args := map[string]any{"path": "sensors/a/README.md", "device_id": "private:widget"}
+	handler(context.Background(), toolRequest("delete_file", args))
+	if args["device_id"] != "private:widget" {
+		t.Errorf("backendMiddleware changed the caller's arguments: %v", args)
+	}
+
+	rejected, err := log.Query(audit.Filter{Tool: "trigger_reindex"})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(rejected) != 1 || rejected[0].Status != audit.StatusError {
+		t.Errorf("rejected call entries = %+v, want one error entry", rejected)
+	}
+	routed, err := log.Query(audit.Filter{Tool: "delete_file"})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(routed) != 1 || routed[0].Args["device_id"] != "private:widget" {
+		t.Errorf("routed call entries = %+v, want the namespaced ID", routed)
+	}
+}
@@ -0,0 +1,253 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// preparedFile is a batch file whose content has been loaded and is ready
+// to upload.
+type preparedFile struct {
+	DestPath string
+	Filename string
+	Content  []byte
+}
+
+// priorFile is the file a batch upload replaces. content is nil if the file
+// is indexed only as a device and its raw content cannot be downloaded.
+type priorFile struct {
+	content []byte
+}
+
+// publishBatchAtomic uploads files as a unit. All sources are read, and
+// the files the batch would replace are saved, before the first upload; if
+// any upload fails, files created by this call are deleted, replaced files
+// are restored, and the reindex is skipped.
+func (s *Server) publishBatchAtomic(ctx context.Context, request mcp.CallToolRequest, files []BatchFile, waitForReindex bool, reindexTimeout time.Duration) *mcp.CallToolResult {
+	var sb strings.Builder
+	sb.WriteString("# Atomic Batch Publish Results\n\n")
+	sb.WriteString(fmt.Sprintf("**Files to upload:** %d\n\n", len(files)))
+
+	// Read every file up front so invalid input aborts before anything is uploaded
+	prepared := make([]preparedFile, 0, len(files))
+	var invalid []string
+	for i, f := range files {
+		switch {
+		case f.DestPath == "":
+			invalid = append(invalid, fmt.Sprintf("%d. **Error:** Missing dest_path", i+1))
+		case f.LocalPath != "":
+			data, err := s.readLocalFile(f.LocalPath)
+			if err != nil {
+				invalid = append(invalid, fmt.Sprintf("%d. **Error:** %v", i+1, err))
+				continue
+			}
+			prepared = append(prepared, preparedFile{DestPath: f.DestPath, Filename: filepath.Base(f.LocalPath), Content: data})
+		case f.Content != "":
+			prepared = append(prepared, preparedFile{DestPath: f.DestPath, Filename: filepath.Base(f.DestPath), Content: []byte(f.Content)})
+		default:
+			invalid = append(invalid, fmt.Sprintf("%d. **Error:** %s - no local_path or content", i+1, f.DestPath))
+		}
+	}
+
+	if len(invalid) > 0 {
+		sb.WriteString("## Validation\n\n")
+		sb.WriteString(strings.Join(invalid, "\n"))
+		sb.WriteString(fmt.Sprintf("\n\n**⚠️ Aborted:** %d file(s) invalid. Nothing was uploaded.\n", len(invalid)))
+		return mcp.NewToolResultError(sb.String())
+	}
+
+	// Save what the batch replaces so a rollback can restore it
+	dests := make([]string, len(prepared))
+	for i, f := range prepared {
+		dests[i] = f.DestPath
+	}
+	prior, err := s.priorFiles(ctx, dests)
+	if err != nil {
+		sb.WriteString(fmt.Sprintf("**⚠️ Aborted:** could not check which files already exist, so a rollback could not be guaranteed: %v\n\nNothing was uploaded.\n", err))
+		return mcp.NewToolResultError(sb.String())
+	}
+
+	// Upload in order, stopping at the first failure
+	sb.WriteString("## Uploads\n\n")
+	var uploaded []preparedFile
+	for i, f := range prepared {
+		resp, err := s.uploadFile(ctx, f.DestPath, f.Filename, f.Content)
+		if err != nil {
+			sb.WriteString(fmt.Sprintf("%d. **Error:** %s - %v\n", i+1, f.DestPath, err))
+			if i+1 < len(prepared) {
+				sb.WriteString(fmt.Sprintf("\n*%d remaining file(s) not attempted.*\n", len(prepared)-i-1))
+			}
+			s.writeRollback(ctx, &sb, uploaded, prior)
+			auditRef(ctx, "rolled_back", strings.Join(batchPaths(uploaded), ","))
+			return mcp.NewToolResultError(sb.String())
+		}
+		status := "created"
+		if prior[f.DestPath] != nil {
+			status = "replaced"
+		}
+		sb.WriteString(fmt.Sprintf("%d. **✓** %s (%d bytes, %s)\n", i+1, resp.Path, resp.Size, status))
+		uploaded = append(uploaded, f)
+	}
+	auditRef(ctx, "paths", strings.Join(batchPaths(uploaded), ","))
+
+	sb.WriteString(fmt.Sprintf("\n**Uploaded:** %d/%d files\n", len(uploaded), len(files)))
+	s.writeBatchReindex(ctx, request, &sb, waitForReindex, reindexTimeout)
+
+	return mcp.NewToolResultText(sb.String())
+}
+
+// batchPaths returns the destination paths of files.
+func batchPaths(files []preparedFile) []string {
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.DestPath
+	}
+	return paths
+}
+
+// priorFiles finds which of paths already hold a file and downloads their
+// content. Documents are downloaded; device READMEs that are not listed as
+// documents are recorded without content.
+func (s *Server) priorFiles(ctx context.Context, paths []string) (map[string]*priorFile, error) {
+	wanted := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		wanted[strings.TrimPrefix(p, "/")] = true
+	}
+	prior := make(map[string]*priorFile)
+
+	for offset := 0; ; offset += verifyPageSize {
+		resp, err := s.api(ctx).ListDocuments(verifyPageSize, offset, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list documents: %w", err)
+		}
+		for _, d := range resp.Data {
+			p := strings.TrimPrefix(d.Path, "/")
+			if !wanted[p] || prior[p] != nil {
+				continue
+			}
+			content, _, err := s.api(ctx).DownloadDocument(d.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to download %s: %w", p, err)
+			}
+			prior[p] = &priorFile{content: content}
+		}
+		if len(resp.Data) == 0 || offset+len(resp.Data) >= resp.Total {
+			break
+		}
+	}
+
+	for offset := 0; ; offset += verifyPageSize {
+		resp, err := s.api(ctx).ListDevices(verifyPageSize, offset, "", "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list devices: %w", err)
+		}
+		for _, d := range resp.Data {
+			readme := strings.TrimPrefix(strings.TrimSuffix(d.Path, "/"), "/")
+			if path.Base(readme) != "README.md" {
+				readme = path.Join(readme, "README.md")
+			}
+			if wanted[readme] && prior[readme] == nil {
+				prior[readme] = &priorFile{}
+			}
+		}
+		if len(resp.Data) == 0 || offset+len(resp.Data) >= resp.Total {
+			break
+		}
+	}
+
+	// Key by the paths as given
+	byPath := make(map[string]*priorFile, len(prior))
+	for _, p := range paths {
+		if f := prior[strings.TrimPrefix(p, "/")]; f != nil {
+			byPath[p] = f
+		}
+	}
+	return byPath, nil
+}
+
+// writeRollback undoes the given uploads in reverse order and renders a
+// rollback report: created files are deleted and replaced files are
+// re-uploaded with their previous content. Reindex is not triggered.
+func (s *Server) writeRollback(ctx context.Context, sb *strings.Builder, uploaded []preparedFile, prior map[string]*priorFile) {
+	sb.WriteString("\n## Rollback\n\n")
+	if len(uploaded) == 0 {
+		sb.WriteString("No files were uploaded, nothing to roll back.\n")
+		return
+	}
+
+	var failed, kept []string
+	for i := len(uploaded) - 1; i >= 0; i-- {
+		f := uploaded[i]
+		old := prior[f.DestPath]
+		switch {
+		case old == nil:
+			if _, err := s.api(ctx).DeleteFile(f.DestPath, false); err != nil {
+				s.logger.Warn("rollback delete failed", "path", f.DestPath, "error", err)
+				sb.WriteString(fmt.Sprintf("- **✗** %s - delete failed: %v\n", f.DestPath, err))
+				failed = append(failed, f.DestPath)
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("- **↩** %s deleted (created by this batch)\n", f.DestPath))
+		case old.content != nil:
+			if _, err := s.uploadFile(ctx, f.DestPath, f.Filename, old.content); err != nil {
+				s.logger.Warn("rollback restore failed", "path", f.DestPath, "error", err)
+				sb.WriteString(fmt.Sprintf("- **✗** %s - restore failed: %v\n", f.DestPath, err))
+				failed = append(failed, f.DestPath)
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("- **↩** %s restored to its previous version\n", f.DestPath))
+		default:
+			sb.WriteString(fmt.Sprintf("- **⚠️** %s kept the new version (the previous version could not be downloaded)\n", f.DestPath))
+			kept = append(kept, f.DestPath)
+		}
+	}
+
+	if len(failed) == 0 && len(kept) == 0 {
+		sb.WriteString(fmt.Sprintf("\n**Rolled back:** %d file(s). The library is back to its previous state and no reindex was triggered.\n", len(uploaded)))
+		return
+	}
+	if len(failed) > 0 {
+		sb.WriteString(fmt.Sprintf("\n**⚠️ Rollback incomplete:** %d of %d file(s) could not be rolled back. Delete created files with `delete_file()` and restore replaced ones from `doc_history()`:\n", len(failed), len(uploaded)))
+		for _, p := range failed {
+			sb.WriteString(fmt.Sprintf("- `%s`\n", p))
+		}
+	}
+	if len(kept) > 0 {
+		sb.WriteString(fmt.Sprintf("\n**⚠️ Not restored:** %d replaced file(s) still hold the new content. Find the previous version with `doc_history()` and `doc_diff()`:\n", len(kept)))
+		for _, p := range kept {
+			sb.WriteString(fmt.Sprintf("- `%s`\n", p))
+		}
+	}
+	sb.WriteString("\nNo reindex was triggered.\n")
+}
+
+// writeBatchReindex triggers a single reindex after a batch upload and
+// optionally waits for it.
+func (s *Server) writeBatchReindex(ctx context.Context, request mcp.CallToolRequest, sb *strings.Builder, waitForReindex bool, reindexTimeout time.Duration) {
+	sb.WriteString("\n## Reindex\n\n")
+	before := s.reindexBaseline(ctx)
+	reindexResp, err := s.api(ctx).TriggerReindex()
+	if err != nil {
+		sb.WriteString(fmt.Sprintf("**⚠️ Warning:** Reindex failed: %v\n", err))
+		return
+	}
+
+	sb.WriteString(fmt.Sprintf("- **Status:** %s\n", reindexResp.Status))
+	auditRef(ctx, "reindex", reindexResp.Status)
+
+	// Wait for reindex if requested
+	if waitForReindex {
+		wait, err := s.waitForReindex(ctx, request, before, reindexTimeout)
+		if err != nil {
+			sb.WriteString(fmt.Sprintf("- **Warning:** %v\n", err))
+		} else {
+			writeReindexWait(sb, wait)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+)
+
+func TestPublishBatchAtomic_Rollback(t *testing.T) {
+	var deleted []string
+	reindexed := false
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/rw/upload"):
+			path := r.FormValue("path")
+			if strings.Contains(path, "bad") {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(client.ErrorResponse{Error: "invalid path"})
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(client.UploadResponse{Path: path, Size: 4})
+		case strings.HasSuffix(r.URL.Path, "/rw/delete"):
+			deleted = append(deleted, r.URL.Query().Get("path"))
+			json.NewEncoder(w).Encode(client.DeleteResponse{Success: true})
+		case strings.HasSuffix(r.URL.Path, "/rw/reindex"):
+			reindexed = true
+			json.NewEncoder(w).Encode(client.ReindexResponse{Status: "started"})
+		case strings.HasSuffix(r.URL.Path, "/documents"):
+			json.NewEncoder(w).Encode(client.DocumentsResponse{})
+		case strings.HasSuffix(r.URL.Path, "/devices"):
+			json.NewEncoder(w).Encode(client.DevicesResponse{})
+		}
+	})
+
+	files := []BatchFile{
+		{DestPath: "sensors/a/README.md", Content: "good"},
+		{DestPath: "sensors/bad/README.md", Content: "fail"},
+		{DestPath: "sensors/c/README.md", Content: "skip"},
+	}
+	result := s.publishBatchAtomic(context.Background(), mcp.CallToolRequest{}, files, false, 0)
+
+	if !result.IsError {
+		t.Error("publishBatchAtomic() IsError = false, want true")
+	}
+	if len(deleted) != 1 || deleted[0] != "sensors/a/README.md" {
+		t.Errorf("publishBatchAtomic() deleted = %v, want [sensors/a/README.md]", deleted)
+	}
+	if reindexed {
+		t.Error("publishBatchAtomic() triggered reindex after rollback")
+	}
+}
+
+func TestPublishBatchAtomic_InvalidAbortsBeforeUpload(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected API request: %s", r.URL.Path)
+	})
+
+	files := []BatchFile{
+		{DestPath: "sensors/a/README.md", Content: "good"},
+		{DestPath: "sensors/b/README.md", LocalPath: "/nonexistent/README.md"},
+	}
+	result := s.publishBatchAtomic(context.Background(), mcp.CallToolRequest{}, files, false, 0)
+
+	if !result.IsError {
+		t.Error("publishBatchAtomic() IsError = false, want true")
+	}
+}
+
+func TestPublishBatchAtomic_RollbackRestoresReplacedFiles(t *testing.T) {
+	storage := map[string]string{
+		"sensors/a/datasheet.md": "old datasheet",
+		"sensors/b/README.md":    "old readme",
+	}
+	var deleted []string
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/rw/upload"):
+			path := r.FormValue("path")
+			if strings.Contains(path, "bad") {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(client.ErrorResponse{Error: "invalid path"})
+				return
+			}
+			file, _, err := r.FormFile("file")
+			if err != nil {
+				t.Fatalf("upload without file: %v", err)
+			}
+			content, _ := io.ReadAll(file)
+			storage[path] = string(content)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(client.UploadResponse{Path: path, Size: int64(len(content))})
+		case strings.HasSuffix(r.URL.Path, "/rw/delete"):
+			path := r.URL.Query().Get("path")
+			deleted = append(deleted, path)
+			delete(storage, path)
+			json.NewEncoder(w).Encode(client.DeleteResponse{Success: true})
+		case strings.HasSuffix(r.URL.Path, "/documents/d1/download"):
+			w.Write([]byte(storage["sensors/a/datasheet.md"]))
+		case strings.HasSuffix(r.URL.Path, "/documents"):
+			json.NewEncoder(w).Encode(client.DocumentsResponse{
+				Data:  []client.Document{{ID: "d1", Path: "sensors/a/datasheet.md"}},
+				Total: 1,
+			})
+		case strings.HasSuffix(r.URL.Path, "/devices"):
+			json.NewEncoder(w).Encode(client.DevicesResponse{
+				Data:  []client.Device{{ID: "b", Path: "sensors/b"}},
+				Total: 1,
+			})
+		default:
+			t.Errorf("unexpected API request: %s", r.URL.Path)
+		}
+	})
+
+	files := []BatchFile{
+		{DestPath: "sensors/a/datasheet.md", Content: "new datasheet"},
+		{DestPath: "sensors/b/README.md", Content: "new readme"},
+		{DestPath: "sensors/new/README.md", Content: "new device"},
+		{DestPath: "sensors/bad/README.md", Content: "fail"},
+	}
+	result := s.publishBatchAtomic(context.Background(), mcp.CallToolRequest{}, files, false, 0)
+	text := resultText(result)
+
+	if !result.IsError {
+		t.Error("publishBatchAtomic() IsError = false, want true")
+	}
+	if len(deleted) != 1 || deleted[0] != "sensors/new/README.md" {
+		t.Errorf("deleted = %v, want only the created sensors/new/README.md", deleted)
+	}
+	if got := storage["sensors/a/datasheet.md"]; got != "old datasheet" {
+		t.Errorf("replaced document = %q, want the previous content restored", got)
+	}
+	if _, ok := storage["sensors/b/README.md"]; !ok {
+		t.Error("replaced device README was deleted")
+	}
+	if !strings.Contains(text, "restored to its previous version") || !strings.Contains(text, "Not restored") {
+		t.Errorf("rollback report should list restored and unrestored files:\n%s", text)
+	}
+	if strings.Contains(text, "back to its previous state") {
+		t.Errorf("rollback report claims a full restore:\n%s", text)
+	}
+}
@@ -0,0 +1,184 @@
+package mcp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rmrfslashbin/manuals-mcp/internal/audit"
+)
+
+// confirmTTL is how long a confirmation token remains valid.
+const confirmTTL = 5 * time.Minute
+
+// pendingConfirmation is an issued confirmation token awaiting use.
+type pendingConfirmation struct {
+	fingerprint string
+	expires     time.Time
+}
+
+// confirmations tracks outstanding two-step confirmation tokens.
+type confirmations struct {
+	mu      sync.Mutex
+	pending map[string]pendingConfirmation
+}
+
+// issue creates a single-use token bound to the given call fingerprint.
+func (c *confirmations) issue(fingerprint string) (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate confirmation token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pending == nil {
+		c.pending = make(map[string]pendingConfirmation)
+	}
+	now := time.Now()
+	for t, p := range c.pending {
+		if now.After(p.expires) {
+			delete(c.pending, t)
+		}
+	}
+	c.pending[token] = pendingConfirmation{fingerprint: fingerprint, expires: now.Add(confirmTTL)}
+	return token, nil
+}
+
+// consume removes the token and reports whether it was valid for fingerprint.
+func (c *confirmations) consume(token, fingerprint string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.pending[token]
+	if !ok {
+		return false
+	}
+	delete(c.pending, token)
+	return p.fingerprint == fingerprint && time.Now().Before(p.expires)
+}
+
+// callFingerprint identifies a tool call by name and arguments, ignoring
+// the confirm_token argument itself.
+func callFingerprint(request mcp.CallToolRequest) string {
+	args := make(map[string]any)
+	for k, v := range request.GetArguments() {
+		if k != "confirm_token" {
+			args[k] = v
+		}
+	}
+	data, _ := json.Marshal(args)
+	return request.Params.Name + ":" + string(data)
+}
+
+// supportsElicitation reports whether the calling client declared
+// elicitation support during initialization.
+func supportsElicitation(ctx context.Context) bool {
+	session, ok := server.ClientSessionFromContext(ctx).(server.SessionWithClientInfo)
+	if !ok {
+		return false
+	}
+	return session.GetClientCapabilities().Elicitation != nil
+}
+
+// requireConfirmation gates an irreversible operation behind explicit human
+// approval. It returns nil when the call may proceed; otherwise it returns
+// the result to send back instead of performing the operation.
+//
+// Clients that support elicitation are asked directly. Other clients receive
+// a single-use confirm_token that must be passed back with identical
+// arguments within confirmTTL.
+func (s *Server) requireConfirmation(ctx context.Context, request mcp.CallToolRequest, summary string) *mcp.CallToolResult {
+	return s.confirmFingerprint(ctx, request, summary, callFingerprint(request))
+}
+
+// requireTargetConfirmation is requireConfirmation for operations whose
+// targets the server computes from the arguments. The confirm_token is also
+// bound to the sorted targets, so it cannot approve a set that changed
+// after the user reviewed it.
+func (s *Server) requireTargetConfirmation(ctx context.Context, request mcp.CallToolRequest, summary string, targets []string) *mcp.CallToolResult {
+	sorted := slices.Sorted(slices.Values(targets))
+	return s.confirmFingerprint(ctx, request, summary, callFingerprint(request)+":"+strings.Join(sorted, ","))
+}
+
+// confirmFingerprint implements requireConfirmation for a given fingerprint.
+func (s *Server) confirmFingerprint(ctx context.Context, request mcp.CallToolRequest, summary, fingerprint string) *mcp.CallToolResult {
+	if !s.confirmEnabled {
+		return nil
+	}
+
+	if token, _ := request.GetArguments()["confirm_token"].(string); token != "" {
+		if s.confirms.consume(token, fingerprint) {
+			return nil
+		}
+		return mcp.NewToolResultError("confirm_token is invalid, expired, or was issued for different arguments or targets. Call again without confirm_token to get a new one.")
+	}
+
+	if supportsElicitation(ctx) {
+		result, err := s.mcp.RequestElicitation(ctx, mcp.ElicitationRequest{
+			Params: mcp.ElicitationParams{
+				Message: summary + "\n\nThis action cannot be undone. Proceed?",
+				RequestedSchema: map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"confirm": map[string]any{
+							"type":        "boolean",
+							"title":       "Confirm",
+							"description": "Perform this irreversible operation",
+						},
+					},
+					"required": []string{"confirm"},
+				},
+			},
+		})
+		if err == nil {
+			content, _ := result.Content.(map[string]any)
+			if confirmed, _ := content["confirm"].(bool); result.Action == mcp.ElicitationResponseActionAccept && confirmed {
+				return nil
+			}
+			auditStatus(ctx, audit.StatusCancelled)
+			return mcp.NewToolResultText(fmt.Sprintf("# Cancelled\n\nThe user did not confirm `%s`. Nothing was changed.", request.Params.Name))
+		}
+		s.logger.Warn("elicitation failed, falling back to confirmation token", "tool", request.Params.Name, "error", err)
+	}
+
+	token, err := s.confirms.issue(fingerprint)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error())
+	}
+	auditStatus(ctx, audit.StatusPending)
+
+	var sb strings.Builder
+	sb.WriteString("# Confirmation Required\n\n")
+	sb.WriteString(summary)
+	sb.WriteString("\n\n**This action cannot be undone.** Nothing has been changed yet.\n\n")
+	sb.WriteString("Show the details above to the user. Only after they explicitly approve, ")
+	sb.WriteString(fmt.Sprintf("call `%s` again with the same arguments plus `confirm_token: \"%s\"`. ", request.Params.Name, token))
+	sb.WriteString(fmt.Sprintf("The token expires in %s.\n", confirmTTL))
+
+	return mcp.NewToolResultText(sb.String())
+}
+
+// describeUser looks up a user for confirmation summaries. Lookup failures
+// are reported inline rather than blocking the confirmation.
+func (s *Server) describeUser(ctx context.Context, userID string) string {
+	resp, err := s.api(ctx).ListUsers()
+	if err != nil {
+		return fmt.Sprintf("- **User ID:** %s (details unavailable: %v)\n", userID, err)
+	}
+	for _, u := range resp.Users {
+		if u.ID == userID {
+			return fmt.Sprintf("- **User:** %s (ID: %s)\n- **Role:** %s\n- **Capabilities:** %s\n- **Active:** %t\n- **Last Seen:** %s\n- **Key Expires:** %s\n",
+				u.Name, u.ID, u.Role(), u.CapabilitiesString(), u.IsActive, u.LastSeenAt, formatKeyExpiry(u.KeyExpiresAt))
+		}
+	}
+	return fmt.Sprintf("- **User ID:** %s (not found in user list)\n", userID)
+}
@@ -0,0 +1,80 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+)
+
+// toolRequest builds a CallToolRequest for a handler under test.
+func toolRequest(name string, args map[string]any) mcp.CallToolRequest {
+	var request mcp.CallToolRequest
+	request.Params.Name = name
+	request.Params.Arguments = args
+	return request
+}
+
+// resultText returns the text content of a tool result.
+func resultText(result *mcp.CallToolResult) string {
+	var sb strings.Builder
+	for _, c := range result.Content {
+		if text, ok := c.(mcp.TextContent); ok {
+			sb.WriteString(text.Text)
+		}
+	}
+	return sb.String()
+}
+
+var confirmTokenPattern = regexp.MustCompile(`confirm_token: "([0-9a-f]+)"`)
+
+func TestDeleteFile_RequiresConfirmation(t *testing.T) {
+	deletes := 0
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		deletes++
+		json.NewEncoder(w).Encode(client.DeleteResponse{Success: true, Path: "a/README.md"})
+	})
+	ctx := context.Background()
+
+	result, _ := s.handleDeleteFile(ctx, toolRequest("delete_file", map[string]any{"path": "a/README.md"}))
+	if deletes != 0 {
+		t.Fatal("delete_file executed without confirmation")
+	}
+	match := confirmTokenPattern.FindStringSubmatch(resultText(result))
+	if match == nil {
+		t.Fatalf("delete_file did not return a confirm_token:\n%s", resultText(result))
+	}
+
+	// A token is bound to the original arguments
+	result, _ = s.handleDeleteFile(ctx, toolRequest("delete_file", map[string]any{"path": "b/README.md", "confirm_token": match[1]}))
+	if !result.IsError || deletes != 0 {
+		t.Error("delete_file accepted a token issued for different arguments")
+	}
+
+	// Tokens are single use, so request a fresh one
+	result, _ = s.handleDeleteFile(ctx, toolRequest("delete_file", map[string]any{"path": "a/README.md"}))
+	match = confirmTokenPattern.FindStringSubmatch(resultText(result))
+	result, _ = s.handleDeleteFile(ctx, toolRequest("delete_file", map[string]any{"path": "a/README.md", "confirm_token": match[1]}))
+	if result.IsError || deletes != 1 {
+		t.Errorf("delete_file with valid token: IsError = %t, deletes = %d", result.IsError, deletes)
+	}
+}
+
+func TestDeleteFile_ConfirmationsDisabled(t *testing.T) {
+	deletes := 0
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		deletes++
+		json.NewEncoder(w).Encode(client.DeleteResponse{Success: true})
+	})
+	WithConfirmations(false)(s)
+
+	s.handleDeleteFile(context.Background(), toolRequest("delete_file", map[string]any{"path": "a/README.md"}))
+	if deletes != 1 {
+		t.Errorf("delete_file deletes = %d, want 1", deletes)
+	}
+}
@@ -0,0 +1,53 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+)
+
+func TestDocFilePath(t *testing.T) {
+	tests := map[string]string{
+		"/boards/esp32/":                  "boards/esp32/README.md",
+		"boards/esp32-s3.v2":              "boards/esp32-s3.v2/README.md",
+		"boards/esp32/README.md":          "boards/esp32/README.md",
+		"boards/esp32/ESP32_Reference.md": "boards/esp32/ESP32_Reference.md",
+	}
+	for in, want := range tests {
+		if got := docFilePath(in); got != want {
+			t.Errorf("docFilePath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDocDiff_TruncatesAtLine(t *testing.T) {
+	line := "+" + strings.Repeat("x", 97) + "\n"
+	full := strings.Repeat(line, maxDiffLength/len(line)+10)
+	var requested string
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Query().Get("path")
+		json.NewEncoder(w).Encode(client.DiffResponse{Path: requested, Diff: full})
+	})
+
+	result, _ := s.handleDocDiff(context.Background(), toolRequest("doc_diff", map[string]any{"path": "boards/esp32-s3.v2"}))
+	if result.IsError {
+		t.Fatalf("doc_diff failed: %s", resultText(result))
+	}
+	if requested != "boards/esp32-s3.v2/README.md" {
+		t.Errorf("doc_diff requested %q, want the folder README", requested)
+	}
+	text := resultText(result)
+	body := text[strings.Index(text, "```diff\n")+len("```diff\n") : strings.LastIndex(text, "\n```")]
+	for _, l := range strings.Split(body, "\n") {
+		if l+"\n" != line {
+			t.Fatalf("doc_diff output has a partial line %q", l)
+		}
+	}
+	if !strings.Contains(text, "truncated") {
+		t.Error("doc_diff output does not mention truncation")
+	}
+}
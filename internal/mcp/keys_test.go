@@ -0,0 +1,35 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+	"github.com/rmrfslashbin/manuals-mcp/internal/secrets"
+)
+
+func TestRotateAPIKey_SecretSink(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(client.RotateKeyResponse{APIKey: "mk_plaintext_secret"})
+	})
+	dir := filepath.Join(t.TempDir(), "keys")
+	WithConfirmations(false)(s)
+	WithSecretSink(&secrets.FileSink{Dir: dir})(s)
+
+	result, _ := s.handleRotateAPIKey(context.Background(), toolRequest("rotate_api_key", map[string]any{"user_id": "usr_1"}))
+	text := resultText(result)
+
+	if result.IsError {
+		t.Fatalf("rotate_api_key failed: %s", text)
+	}
+	if strings.Contains(text, "mk_plaintext_secret") {
+		t.Error("rotate_api_key leaked the plaintext key into the result")
+	}
+	if !strings.Contains(text, dir) || !strings.Contains(text, secrets.Fingerprint("mk_plaintext_secret")) {
+		t.Errorf("rotate_api_key result missing reference or fingerprint:\n%s", text)
+	}
+}
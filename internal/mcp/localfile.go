@@ -0,0 +1,161 @@
+package mcp
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DefaultMaxFileSize is the default limit on files read from local_path.
+const DefaultMaxFileSize = 25 << 20
+
+// DefaultDenyPatterns are path component patterns that are never readable
+// through local_path, regardless of the allowed roots. They cover common
+// credential and key material locations.
+var DefaultDenyPatterns = []string{
+	".ssh", ".gnupg", ".aws", ".azure", ".kube", ".docker", ".config/gcloud",
+	".netrc", ".git-credentials", ".npmrc", ".pypirc",
+	".env", ".env.*", "*.pem", "*.key", "*.p12", "*.pfx", "*.keystore",
+	"id_rsa*", "id_dsa*", "id_ecdsa*", "id_ed25519*",
+	".manuals-mcp",
+}
+
+// LocalFilePolicy restricts which local files upload tools may read.
+type LocalFilePolicy struct {
+	// Roots are the directories local_path must resolve inside. With no
+	// roots, local_path is disabled and only inline content is accepted.
+	Roots []string
+
+	// DenyPatterns are path.Match patterns checked against every
+	// component of the requested and resolved paths. Patterns containing
+	// a slash match consecutive components.
+	DenyPatterns []string
+
+	// MaxSize is the largest file, in bytes, that may be read.
+	MaxSize int64
+}
+
+// withDefaults adds DefaultDenyPatterns to p and fills in a zero MaxSize.
+func (p LocalFilePolicy) withDefaults() LocalFilePolicy {
+	p.DenyPatterns = append(slices.Clone(DefaultDenyPatterns), p.DenyPatterns...)
+	if p.MaxSize <= 0 {
+		p.MaxSize = DefaultMaxFileSize
+	}
+	return p
+}
+
+// SetLocalFilePolicy replaces the file policy while the server runs, with
+// the same defaults as WithLocalFilePolicy.
+func (s *Server) SetLocalFilePolicy(policy LocalFilePolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files = policy.withDefaults()
+}
+
+// localFilePolicy returns the current file policy.
+func (s *Server) localFilePolicy() LocalFilePolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.files
+}
+
+// readLocalFile reads a local_path argument after checking it against the
+// server's file policy. The path is checked against the roots before it is
+// touched, so probing outside them reveals nothing about what exists, and
+// again after resolving symlinks so a link inside an allowed root cannot
+// point outside it.
+func (s *Server) readLocalFile(localPath string) ([]byte, error) {
+	p := s.localFilePolicy()
+	if len(p.Roots) == 0 {
+		return nil, fmt.Errorf("local_path is disabled on this server (no allowed directories configured); pass the file as content instead")
+	}
+	notAllowed := fmt.Errorf("local_path '%s' is not allowed: it is outside the allowed directories (%s)", localPath, strings.Join(p.Roots, ", "))
+
+	abs, err := filepath.Abs(localPath)
+	if err != nil || !p.allowed(abs) {
+		return nil, notAllowed
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local file '%s': %w", localPath, err)
+	}
+	if !p.allowed(resolved) {
+		return nil, notAllowed
+	}
+	if pattern := p.deniedBy(abs, resolved); pattern != "" {
+		return nil, fmt.Errorf("local_path '%s' is not allowed: matches protected pattern %q", localPath, pattern)
+	}
+
+	file, err := os.Open(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local file '%s': %w", localPath, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local file '%s': %w", localPath, err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("local_path '%s' is not a regular file", localPath)
+	}
+	if info.Size() > p.MaxSize {
+		return nil, fmt.Errorf("local file '%s' is %d bytes, larger than the %d byte limit", localPath, info.Size(), p.MaxSize)
+	}
+
+	// Bound the read as well in case the file grows after Stat
+	data, err := io.ReadAll(io.LimitReader(file, p.MaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local file '%s': %w", localPath, err)
+	}
+	if int64(len(data)) > p.MaxSize {
+		return nil, fmt.Errorf("local file '%s' is larger than the %d byte limit", localPath, p.MaxSize)
+	}
+	return data, nil
+}
+
+// allowed reports whether path lies inside one of the policy roots. Roots
+// match both as given and resolved, so e.g. /tmp matches on systems where
+// it is a symlink to /private/tmp.
+func (p LocalFilePolicy) allowed(path string) bool {
+	for _, root := range p.Roots {
+		r, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+		candidates := []string{r}
+		if real, err := filepath.EvalSymlinks(r); err == nil && real != r {
+			candidates = append(candidates, real)
+		}
+		for _, c := range candidates {
+			rel, err := filepath.Rel(c, path)
+			if err != nil {
+				continue
+			}
+			if rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// deniedBy returns the first deny pattern matching any of paths, or "".
+func (p LocalFilePolicy) deniedBy(paths ...string) string {
+	for _, name := range paths {
+		parts := strings.Split(filepath.ToSlash(name), "/")
+		for _, pattern := range p.DenyPatterns {
+			n := strings.Count(pattern, "/") + 1
+			for i := 0; i+n <= len(parts); i++ {
+				if ok, _ := path.Match(pattern, strings.Join(parts[i:i+n], "/")); ok {
+					return pattern
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,44 @@
+package mcp
+
+import (
+	"context"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rmrfslashbin/manuals-mcp/internal/audit"
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// loggingMiddleware gives each tool call a correlation ID, which its API
+// requests send as X-Request-ID, and logs the call at debug level with
+// secrets and file content redacted from the arguments.
+func (s *Server) loggingMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id := client.NewRequestID()
+		ctx = client.WithRequestID(ctx, id)
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("manuals.request_id", id))
+
+		start := time.Now()
+		result, err := next(ctx, request)
+
+		attrs := []any{
+			"request_id", id,
+			"tool", request.Params.Name,
+			"args", audit.Redact(request.GetArguments()),
+			"duration_ms", time.Since(start).Milliseconds(),
+		}
+		switch {
+		case err != nil:
+			attrs = append(attrs, "status", audit.StatusError, "error", err)
+		case result != nil && result.IsError:
+			attrs = append(attrs, "status", audit.StatusError, "error", resultErrorText(result))
+		default:
+			attrs = append(attrs, "status", audit.StatusSuccess)
+		}
+		s.logger.DebugContext(ctx, "tool call", attrs...)
+		return result, err
+	}
+}
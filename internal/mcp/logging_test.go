@@ -0,0 +1,46 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+)
+
+func TestLoggingMiddleware(t *testing.T) {
+	var requestID string
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requestID = r.Header.Get(client.RequestIDHeader)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(client.UploadResponse{Path: "devices/esp32/guide.md"})
+	})
+	var logs strings.Builder
+	s.logger = slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	req := toolRequest("upload_file", map[string]any{
+		"dest_path": "devices/esp32",
+		"filename":  "guide.md",
+		"content":   "# top secret content",
+	})
+	if _, err := s.loggingMiddleware(s.handleUploadFile)(context.Background(), req); err != nil {
+		t.Fatalf("upload_file error = %v", err)
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(logs.String()), &entry); err != nil {
+		t.Fatalf("log is not one JSON entry: %v\n%s", err, logs.String())
+	}
+	if requestID == "" || entry["request_id"] != requestID {
+		t.Errorf("log request_id = %v, X-Request-ID = %q, want the same ID", entry["request_id"], requestID)
+	}
+	if entry["tool"] != "upload_file" || entry["status"] != "success" {
+		t.Errorf("log entry = %v", entry)
+	}
+	if strings.Contains(logs.String(), "top secret") {
+		t.Errorf("log contains upload content:\n%s", logs.String())
+	}
+}
@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"context"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+	"github.com/rmrfslashbin/manuals-mcp/internal/metrics"
+)
+
+// WithMetrics records tool calls, uploads and reindex runs in m. Upstream
+// API latency is recorded by the clients themselves (client.Options.Observer).
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(s *Server) {
+		s.metrics = m
+	}
+}
+
+// metricsMiddleware counts tool calls and measures their latency. A call
+// fails if it returns an error or an error result.
+func (s *Server) metricsMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if s.metrics == nil {
+			return next(ctx, request)
+		}
+		start := time.Now()
+		result, err := next(ctx, request)
+		s.metrics.ObserveTool(request.Params.Name, err != nil || (result != nil && result.IsError), time.Since(start))
+		return result, err
+	}
+}
+
+// uploadFile uploads content through the selected backend and counts the
+// bytes sent.
+func (s *Server) uploadFile(ctx context.Context, destPath, filename string, content []byte) (*client.UploadResponse, error) {
+	resp, err := s.api(ctx).UploadFile(destPath, filename, content)
+	if err == nil {
+		s.metrics.AddUploadBytes(len(content))
+	}
+	return resp, err
+}
+
+// reindexStatus fetches the reindex status of the selected backend and
+// records the duration of its last completed run.
+func (s *Server) reindexStatus(ctx context.Context) (*client.ReindexStatusResponse, error) {
+	status, err := s.api(ctx).GetReindexStatus()
+	if err != nil {
+		return nil, err
+	}
+	if status.LastRun != nil {
+		if d, err := time.ParseDuration(status.LastRun.Duration); err == nil {
+			s.metrics.ObserveReindex(s.selectedBackend(ctx).Name, status.LastCompleted, d)
+		}
+	}
+	return status, nil
+}
@@ -0,0 +1,54 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+	"github.com/rmrfslashbin/manuals-mcp/internal/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/reindex/status"):
+			w.Write([]byte(`{"status":"idle","last_completed":"2025-12-01T00:00:00Z","last_run":{"duration":"1m30s"}}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(client.ErrorResponse{Error: "boom"})
+		}
+	})
+	m := metrics.New()
+	WithMetrics(m)(s)
+
+	ctx := context.Background()
+	for _, name := range []string{"get_reindex_status", "get_reindex_status", "search"} {
+		req := toolRequest(name, map[string]any{"query": "esp32"})
+		handler := s.handleGetReindexStatus
+		if name == "search" {
+			handler = s.handleSearch
+		}
+		if _, err := s.metricsMiddleware(handler)(ctx, req); err != nil {
+			t.Fatalf("%s error = %v", name, err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	for _, want := range []string{
+		`manuals_mcp_tool_calls_total{status="success",tool="get_reindex_status"} 2`,
+		`manuals_mcp_tool_calls_total{status="error",tool="search"} 1`,
+		`manuals_mcp_reindex_duration_seconds_sum{backend="default"} 90`,
+		`manuals_mcp_reindex_duration_seconds_count{backend="default"} 1`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Profile is what switch_profile applies from a config profile.
+type Profile struct {
+	Backends   []Backend
+	FilePolicy LocalFilePolicy
+}
+
+// ProfileLoader connects to the backends of a named config profile and
+// reads the rest of the settings switch_profile applies.
+type ProfileLoader func(name string) (*Profile, error)
+
+// profileSet holds the config profiles switch_profile may select.
+type profileSet struct {
+	names   []string
+	current string
+	load    ProfileLoader
+
+	// switching serializes profile switches
+	switching sync.Mutex
+}
+
+// WithProfiles enables the switch_profile tool, which replaces the
+// backends with those of another config profile. current is the profile
+// in use at startup ("" for none).
+func WithProfiles(names []string, current string, load ProfileLoader) Option {
+	return func(s *Server) {
+		s.profiles.names = names
+		s.profiles.current = current
+		s.profiles.load = load
+	}
+}
+
+// currentProfile returns the active profile name, or "".
+func (s *Server) currentProfile() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.profiles.current
+}
+
+func (s *Server) handleSwitchProfile(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	name, _ := args["profile"].(string)
+
+	s.profiles.switching.Lock()
+	defer s.profiles.switching.Unlock()
+	current := s.currentProfile()
+
+	if name == "" {
+		var sb strings.Builder
+		sb.WriteString("# Profiles\n\n")
+		for _, p := range s.profiles.names {
+			if p == current {
+				sb.WriteString(fmt.Sprintf("- **%s** (active)\n", p))
+			} else {
+				sb.WriteString(fmt.Sprintf("- %s\n", p))
+			}
+		}
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+	if !slices.Contains(s.profiles.names, name) {
+		return mcp.NewToolResultError(fmt.Sprintf("unknown profile %q (configured: %s)", name, strings.Join(s.profiles.names, ", "))), nil
+	}
+	if name == current {
+		return mcp.NewToolResultText(fmt.Sprintf("Profile %s is already active.", name)), nil
+	}
+
+	// Only an admin of the active profile may switch, so a session running
+	// under a restricted profile cannot escalate itself.
+	c := s.api(ctx)
+	if !c.HasAPIKey() {
+		return mcp.NewToolResultError("switching profiles requires an admin API key; the active profile is anonymous"), nil
+	}
+	user, err := c.GetMe()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to check role: %v", err)), nil
+	}
+	if user == nil || !user.CanAdmin() {
+		return mcp.NewToolResultError("switching profiles requires the Admin role in the active profile"), nil
+	}
+
+	profile, err := s.profiles.load(name)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to switch profile: %v", err)), nil
+	}
+	s.mu.Lock()
+	s.profiles.current = name
+	s.mu.Unlock()
+	s.SetBackends(profile.Backends)
+	s.SetLocalFilePolicy(profile.FilePolicy)
+	auditRef(ctx, "profile", name)
+	s.logger.Info("switched profile", "from", current, "to", name, "backends", len(profile.Backends), "allowed_roots", profile.FilePolicy.Roots)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("## Switched to profile %s\n\n", name))
+	for _, b := range profile.Backends {
+		sb.WriteString(fmt.Sprintf("- **%s:** %s (API %s)\n", b.Name, b.Client.GetAPIURL(), b.Client.Version()))
+	}
+	if roots := profile.FilePolicy.Roots; len(roots) > 0 {
+		sb.WriteString(fmt.Sprintf("- **Local Files:** %s\n", strings.Join(roots, ", ")))
+	} else {
+		sb.WriteString("- **Local Files:** disabled\n")
+	}
+	sb.WriteString("\nAll tools now use this profile. Run `info` or `my_capabilities` to check the new role.\n")
+	return mcp.NewToolResultText(sb.String()), nil
+}
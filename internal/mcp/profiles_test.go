@@ -0,0 +1,73 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+)
+
+// profileBackend creates a backend whose key belongs to a user with the
+// given capabilities.
+func profileBackend(t *testing.T, name string, caps ...string) Backend {
+	t.Helper()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(client.MeResponse{User: client.User{Name: name, Capabilities: caps}})
+	}))
+	t.Cleanup(api.Close)
+	return Backend{Name: "default", Client: client.New(api.URL, name+"-key")}
+}
+
+func TestSwitchProfile(t *testing.T) {
+	profiles := map[string]Backend{
+		"lab":      profileBackend(t, "lab", "*"),
+		"readonly": profileBackend(t, "readonly", "read:*"),
+	}
+	var loaded []string
+	load := func(name string) (*Profile, error) {
+		loaded = append(loaded, name)
+		return &Profile{Backends: []Backend{profiles[name]}, FilePolicy: LocalFilePolicy{Roots: []string{"/srv/" + name}}}, nil
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := NewServer(profiles["lab"].Client, "test", "none", "now", logger, WithProfiles([]string{"lab", "readonly"}, "lab", load))
+
+	switchTo := func(name string) (bool, string) {
+		result, err := s.handleSwitchProfile(context.Background(), toolRequest("switch_profile", map[string]any{"profile": name}))
+		if err != nil {
+			t.Fatalf("handleSwitchProfile() error = %v", err)
+		}
+		return result.IsError, resultText(result)
+	}
+
+	result, _ := s.handleSwitchProfile(context.Background(), toolRequest("switch_profile", nil))
+	if text := resultText(result); !strings.Contains(text, "**lab** (active)") || !strings.Contains(text, "- readonly") {
+		t.Errorf("listing should mark the active profile:\n%s", text)
+	}
+
+	if isErr, text := switchTo("prod"); !isErr || !strings.Contains(text, "unknown profile") {
+		t.Errorf("unknown profile should fail: %s", text)
+	}
+
+	if isErr, text := switchTo("readonly"); isErr {
+		t.Fatalf("admin switch failed: %s", text)
+	}
+	if s.currentProfile() != "readonly" || s.selectedBackend(context.Background()).Client != profiles["readonly"].Client {
+		t.Errorf("switch should replace the backend, profile = %q", s.currentProfile())
+	}
+	if roots := s.localFilePolicy().Roots; len(roots) != 1 || roots[0] != "/srv/readonly" {
+		t.Errorf("switch should apply the file policy, roots = %v", roots)
+	}
+
+	if isErr, text := switchTo("lab"); !isErr || !strings.Contains(text, "Admin role") {
+		t.Errorf("readonly profile should not be able to switch back: %s", text)
+	}
+	if len(loaded) != 1 {
+		t.Errorf("loader calls = %v, want only readonly", loaded)
+	}
+}
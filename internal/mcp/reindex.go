@@ -0,0 +1,146 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+)
+
+const (
+	// defaultReindexTimeout is how long publish tools wait for a reindex when
+	// the caller does not supply a timeout.
+	defaultReindexTimeout = 60 * time.Second
+
+	// maxReindexTimeout caps caller-supplied timeouts so a single tool call
+	// cannot block the session indefinitely.
+	maxReindexTimeout = 10 * time.Minute
+
+	// reindexPollInterval is the delay between reindex status checks.
+	reindexPollInterval = 2 * time.Second
+)
+
+// reindexTimeoutArg reads a timeout in seconds from the named tool argument,
+// falling back to defaultReindexTimeout and clamping to maxReindexTimeout.
+func reindexTimeoutArg(args map[string]interface{}, name string) time.Duration {
+	timeout := defaultReindexTimeout
+	if t, ok := args[name].(float64); ok && t > 0 {
+		timeout = time.Duration(t * float64(time.Second))
+	}
+	if timeout > maxReindexTimeout {
+		timeout = maxReindexTimeout
+	}
+	return timeout
+}
+
+// reindexBaseline reads the reindex status before a trigger so the wait can
+// tell the triggered run from the previous one. If the status cannot be
+// read, an empty baseline is returned and any reported completion counts.
+func (s *Server) reindexBaseline(ctx context.Context) *client.ReindexStatusResponse {
+	status, err := s.api(ctx).GetReindexStatus()
+	if err != nil || status == nil {
+		return &client.ReindexStatusResponse{}
+	}
+	return status
+}
+
+// waitForReindex waits for the reindex triggered after before was read to
+// finish, the timeout to elapse or ctx to be cancelled. A nil before waits
+// for whatever job is current. If the request carries a progress token, a
+// progress notification is sent after every poll.
+func (s *Server) waitForReindex(ctx context.Context, request mcp.CallToolRequest, before *client.ReindexStatusResponse, timeout time.Duration) (*client.ReindexWait, error) {
+	var token mcp.ProgressToken
+	if request.Params.Meta != nil {
+		token = request.Params.Meta.ProgressToken
+	}
+
+	watch := client.ReindexWatch{
+		Timeout:  timeout,
+		Interval: reindexPollInterval,
+		Status:   func() (*client.ReindexStatusResponse, error) { return s.reindexStatus(ctx) },
+		OnPoll: func(poll int, status *client.ReindexStatusResponse) {
+			s.notifyReindexProgress(ctx, token, poll, status)
+		},
+	}
+	if before != nil {
+		watch.Triggered = true
+		watch.Previous = before.LastCompleted
+	}
+
+	wait, err := client.WaitForReindex(ctx, watch)
+	if err != nil && ctx.Err() == nil {
+		return nil, fmt.Errorf("failed to check reindex status: %w", err)
+	}
+	return wait, err
+}
+
+// notifyReindexProgress sends an MCP progress notification for a running
+// reindex. It is a no-op when the client did not request progress.
+func (s *Server) notifyReindexProgress(ctx context.Context, token mcp.ProgressToken, poll int, status *client.ReindexStatusResponse) {
+	if token == nil {
+		return
+	}
+
+	message := "Reindex " + status.Status
+	if status.Elapsed != "" {
+		message += " (elapsed " + status.Elapsed + ")"
+	}
+
+	err := s.mcp.SendNotificationToClient(ctx, "notifications/progress", map[string]any{
+		"progressToken": token,
+		"progress":      poll,
+		"message":       message,
+	})
+	if err != nil {
+		s.logger.Debug("failed to send reindex progress notification", "error", err)
+	}
+}
+
+// writeReindexWait renders the outcome of waitForReindex as markdown list items.
+func writeReindexWait(sb *strings.Builder, wait *client.ReindexWait) {
+	if !wait.Completed {
+		sb.WriteString(fmt.Sprintf("- **Still Running:** Reindex did not finish within %s\n", wait.Timeout))
+		if wait.Status.StartedAt != "" {
+			sb.WriteString(fmt.Sprintf("- **Started At:** %s\n", wait.Status.StartedAt))
+		}
+		if wait.Status.Elapsed != "" {
+			sb.WriteString(fmt.Sprintf("- **Elapsed:** %s\n", wait.Status.Elapsed))
+		}
+		sb.WriteString("- **Note:** Use `wait_for_reindex()` to keep waiting or `get_reindex_status()` to check progress.\n")
+		return
+	}
+
+	lastRun := wait.Status.LastRun
+	if lastRun == nil {
+		sb.WriteString("- **Completed:** Reindex finished (no run statistics reported)\n")
+		return
+	}
+	sb.WriteString(fmt.Sprintf("- **Completed:** Reindex finished in %s\n", lastRun.Duration))
+	sb.WriteString(fmt.Sprintf("- **Devices:** %d indexed\n", lastRun.DevicesIndexed))
+	sb.WriteString(fmt.Sprintf("- **Documents:** %d indexed\n", lastRun.DocumentsIndexed))
+	if lastRun.GuidesIndexed > 0 {
+		sb.WriteString(fmt.Sprintf("- **Guides:** %d indexed\n", lastRun.GuidesIndexed))
+	}
+	if lastRun.Errors > 0 {
+		sb.WriteString(fmt.Sprintf("- **Errors:** %d\n", lastRun.Errors))
+	}
+}
+
+func (s *Server) handleWaitForReindex(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	timeout := reindexTimeoutArg(request.GetArguments(), "timeout_seconds")
+
+	wait, err := s.waitForReindex(ctx, request, nil, timeout)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to wait for reindex: %v", err)), nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Reindex Wait\n\n")
+	sb.WriteString(fmt.Sprintf("- **Waited:** %s\n", wait.Waited.Round(time.Second)))
+	writeReindexWait(&sb, wait)
+
+	return mcp.NewToolResultText(sb.String()), nil
+}
@@ -0,0 +1,101 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+)
+
+// newTestServer creates a Server backed by an httptest API server.
+func newTestServer(t *testing.T, handler http.HandlerFunc) *Server {
+	t.Helper()
+	api := httptest.NewServer(handler)
+	t.Cleanup(api.Close)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewServer(client.New(api.URL, "test-key"), "test", "none", "now", logger)
+}
+
+func TestWaitForReindex_IdleWithoutLastRun(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(client.ReindexStatusResponse{Status: "idle"})
+	})
+
+	wait, err := s.waitForReindex(context.Background(), mcp.CallToolRequest{}, nil, time.Second)
+	if err != nil {
+		t.Fatalf("waitForReindex() error = %v", err)
+	}
+	if !wait.Completed {
+		t.Error("waitForReindex() Completed = false, want true")
+	}
+
+	var sb strings.Builder
+	writeReindexWait(&sb, wait)
+	if !strings.Contains(sb.String(), "no run statistics") {
+		t.Errorf("writeReindexWait() = %q, want no run statistics note", sb.String())
+	}
+}
+
+func TestWaitForReindex_Timeout(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(client.ReindexStatusResponse{Status: "running", Elapsed: "5s"})
+	})
+
+	wait, err := s.waitForReindex(context.Background(), mcp.CallToolRequest{}, nil, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("waitForReindex() error = %v", err)
+	}
+	if wait.Completed {
+		t.Error("waitForReindex() Completed = true, want false")
+	}
+
+	var sb strings.Builder
+	writeReindexWait(&sb, wait)
+	if !strings.Contains(sb.String(), "Still Running") {
+		t.Errorf("writeReindexWait() = %q, want still running result", sb.String())
+	}
+}
+
+func TestWaitForReindex_StaleIdle(t *testing.T) {
+	previous := client.ReindexStatusResponse{Status: "idle", LastCompleted: "2026-10-18T10:00:00Z"}
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(previous)
+	})
+
+	wait, err := s.waitForReindex(context.Background(), mcp.CallToolRequest{}, &previous, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("waitForReindex() error = %v", err)
+	}
+	if wait.Completed {
+		t.Error("waitForReindex() treated the previous run as the triggered one")
+	}
+}
+
+func TestReindexTimeoutArg(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want time.Duration
+	}{
+		{"default", map[string]interface{}{}, defaultReindexTimeout},
+		{"custom", map[string]interface{}{"timeout_seconds": float64(120)}, 120 * time.Second},
+		{"clamped", map[string]interface{}{"timeout_seconds": float64(3600)}, maxReindexTimeout},
+		{"negative", map[string]interface{}{"timeout_seconds": float64(-1)}, defaultReindexTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reindexTimeoutArg(tt.args, "timeout_seconds"); got != tt.want {
+				t.Errorf("reindexTimeoutArg() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,34 +6,133 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
-	"os"
+	"maps"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/rmrfslashbin/manuals-mcp/internal/audit"
 	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+	"github.com/rmrfslashbin/manuals-mcp/internal/metrics"
+	"github.com/rmrfslashbin/manuals-mcp/internal/secrets"
+	"github.com/rmrfslashbin/manuals-mcp/internal/settings"
 )
 
 // Server wraps the MCP server with our API client.
 type Server struct {
 	mcp       *server.MCPServer
-	client    *client.Client
 	logger    *slog.Logger
 	version   string
 	gitCommit string
 	buildTime string
+
+	// API backends, primary first. mu guards the settings that can change
+	// while the server runs.
+	mu       sync.RWMutex
+	backends []Backend
+
+	// Git sync settings
+	commitURLTemplate string
+	gitEmailDomain    string
+
+	// Confirmation of destructive operations
+	confirmEnabled bool
+	confirms       confirmations
+
+	// Audit log of mutating tool calls (nil when disabled)
+	audit     *audit.Log
+	userMu    sync.Mutex
+	userNames map[*client.Client]string
+
+	// Destination for newly generated API keys (nil shows keys inline)
+	secrets secrets.Sink
+
+	// Restrictions on files read from local_path (guarded by mu)
+	files LocalFilePolicy
+
+	// Config profiles for switch_profile (guarded by mu)
+	profiles profileSet
+
+	// Prometheus metrics (nil when disabled)
+	metrics *metrics.Metrics
+}
+
+// Option configures optional Server behavior.
+type Option func(*Server)
+
+// WithCommitURLTemplate sets a URL template used to link sync commits.
+// The placeholders {commit} and {branch} are replaced with values from the
+// sync response, e.g. "https://github.com/org/docs/commit/{commit}".
+func WithCommitURLTemplate(tmpl string) Option {
+	return func(s *Server) {
+		s.commitURLTemplate = tmpl
+	}
+}
+
+// WithGitEmailDomain sets the domain used to build commit author emails
+// from the authenticated user name.
+func WithGitEmailDomain(domain string) Option {
+	return func(s *Server) {
+		if domain != "" {
+			s.gitEmailDomain = domain
+		}
+	}
+}
+
+// WithConfirmations enables or disables human confirmation of destructive
+// operations. Confirmations are enabled by default; disable them for
+// unattended automation.
+func WithConfirmations(enabled bool) Option {
+	return func(s *Server) {
+		s.confirmEnabled = enabled
+	}
+}
+
+// WithAuditLog records every mutating tool call in the given audit log.
+func WithAuditLog(log *audit.Log) Option {
+	return func(s *Server) {
+		s.audit = log
+	}
+}
+
+// WithSecretSink stores newly generated API keys in sink and returns only a
+// reference and fingerprint to the model instead of the plaintext key.
+func WithSecretSink(sink secrets.Sink) Option {
+	return func(s *Server) {
+		s.secrets = sink
+	}
+}
+
+// WithLocalFilePolicy sets the directories, deny patterns and size limit
+// for files read from local_path. Deny patterns are added to
+// DefaultDenyPatterns, and a zero MaxSize keeps DefaultMaxFileSize.
+func WithLocalFilePolicy(policy LocalFilePolicy) Option {
+	return func(s *Server) {
+		s.files = policy.withDefaults()
+	}
 }
 
 // NewServer creates a new MCP server instance.
-func NewServer(apiClient *client.Client, version, gitCommit, buildTime string, logger *slog.Logger) *Server {
+func NewServer(apiClient *client.Client, version, gitCommit, buildTime string, logger *slog.Logger, opts ...Option) *Server {
 	s := &Server{
-		client:    apiClient,
-		logger:    logger,
-		version:   version,
-		gitCommit: gitCommit,
-		buildTime: buildTime,
+		backends:       []Backend{{Name: "default", Client: apiClient}},
+		logger:         logger,
+		version:        version,
+		gitCommit:      gitCommit,
+		buildTime:      buildTime,
+		gitEmailDomain: "manuals-mcp.local",
+		confirmEnabled: true,
+		files: LocalFilePolicy{
+			DenyPatterns: DefaultDenyPatterns,
+			MaxSize:      DefaultMaxFileSize,
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
 
 	// Create MCP server
@@ -43,6 +142,12 @@ func NewServer(apiClient *client.Client, version, gitCommit, buildTime string, l
 		server.WithToolCapabilities(true),
 		server.WithResourceCapabilities(true, true),
 		server.WithLogging(),
+		server.WithElicitation(),
+		server.WithToolHandlerMiddleware(s.metricsMiddleware),
+		server.WithToolHandlerMiddleware(s.tracingMiddleware),
+		server.WithToolHandlerMiddleware(s.loggingMiddleware),
+		server.WithToolHandlerMiddleware(s.auditMiddleware),
+		server.WithToolHandlerMiddleware(s.backendMiddleware),
 	)
 
 	// Register tools
@@ -62,12 +167,12 @@ func (s *Server) registerTools() {
 	// ===========================================
 
 	// Tool: my_capabilities - Show available actions based on role
-	s.mcp.AddTool(mcp.NewTool("my_capabilities",
+	s.addTool(mcp.NewTool("my_capabilities",
 		mcp.WithDescription("Show available tools and capabilities based on your authentication role. Use this first to understand what actions you can perform. Returns a categorized list of available tools with usage examples."),
 	), s.handleMyCapabilities)
 
 	// Tool: ingest_workflow - Get document ingestion workflow guidance
-	s.mcp.AddTool(mcp.NewTool("ingest_workflow",
+	s.addTool(mcp.NewTool("ingest_workflow",
 		mcp.WithDescription("Get step-by-step guidance for ingesting new documentation into the platform. Explains the complete workflow from processing a source document (PDF/datasheet) to publishing it. Use this when you need to add new hardware or software documentation."),
 		mcp.WithString("doc_type",
 			mcp.Description("Type of documentation to ingest: 'hardware' (MCU, sensor, SBC), 'software' (applications, tools), or 'protocol' (I2C, SPI, UART). Defaults to 'hardware'."),
@@ -79,7 +184,7 @@ func (s *Server) registerTools() {
 	// ===========================================
 
 	// Tool: search - Full-text search
-	s.mcp.AddTool(mcp.NewTool("search_manuals",
+	s.addTool(mcp.NewTool("search_manuals",
 		mcp.WithDescription("Search across all hardware and software documentation using full-text search. Returns matching devices with relevance scores and text snippets. Use this to find devices by name, feature, interface (I2C, SPI, UART), or any keyword in the documentation."),
 		mcp.WithString("query",
 			mcp.Description("Search query - can be device name, feature, interface type, or any keyword"),
@@ -97,7 +202,7 @@ func (s *Server) registerTools() {
 	), s.handleSearch)
 
 	// Tool: search_semantic - Semantic/vector search using embeddings
-	s.mcp.AddTool(mcp.NewTool("search_semantic",
+	s.addTool(mcp.NewTool("search_semantic",
 		mcp.WithDescription("Search documentation using semantic similarity (AI embeddings). Unlike keyword search, this understands meaning and context. Use for natural language queries like 'sensor for outdoor weather monitoring' or 'microcontroller with WiFi for IoT'. Returns results ranked by semantic similarity. Note: Requires vector search to be enabled on the API server."),
 		mcp.WithString("query",
 			mcp.Description("Natural language query describing what you're looking for"),
@@ -115,7 +220,7 @@ func (s *Server) registerTools() {
 	), s.handleSemanticSearch)
 
 	// Tool: get_device - Get device details
-	s.mcp.AddTool(mcp.NewTool("get_device",
+	s.addTool(mcp.NewTool("get_device",
 		mcp.WithDescription("Get complete documentation for a specific device including full markdown content, metadata, and specifications. Use the device_id from search_manuals or list_devices results."),
 		mcp.WithString("device_id",
 			mcp.Description("Device ID (e.g., 'sbc-raspberry-pi-raspberry-pi-5'). Use search_manuals to find device IDs."),
@@ -124,7 +229,7 @@ func (s *Server) registerTools() {
 	), s.handleGetDevice)
 
 	// Tool: list_devices - List all devices
-	s.mcp.AddTool(mcp.NewTool("list_devices",
+	s.addTool(mcp.NewTool("list_devices",
 		mcp.WithDescription("Browse all devices in the documentation library with optional filtering. Returns device names, IDs, domains, and types. Use this to explore available documentation or find devices by category."),
 		mcp.WithString("domain",
 			mcp.Description("Filter by domain: 'hardware', 'software', or 'protocol'"),
@@ -138,7 +243,7 @@ func (s *Server) registerTools() {
 	), s.handleListDevices)
 
 	// Tool: get_pinout - Get GPIO pinout
-	s.mcp.AddTool(mcp.NewTool("get_pinout",
+	s.addTool(mcp.NewTool("get_pinout",
 		mcp.WithDescription("Get GPIO pinout table for a hardware device. Returns physical pin numbers, GPIO numbers, pin names, and descriptions. Essential for wiring diagrams and hardware connections."),
 		mcp.WithString("device_id",
 			mcp.Description("Device ID (e.g., 'sbc-raspberry-pi-raspberry-pi-5')"),
@@ -147,7 +252,7 @@ func (s *Server) registerTools() {
 	), s.handleGetPinout)
 
 	// Tool: get_specs - Get device specifications
-	s.mcp.AddTool(mcp.NewTool("get_specs",
+	s.addTool(mcp.NewTool("get_specs",
 		mcp.WithDescription("Get technical specifications for a device as key-value pairs. Useful for comparing devices or quick specification lookups without retrieving full documentation."),
 		mcp.WithString("device_id",
 			mcp.Description("Device ID (e.g., 'sensors-temperature-ds18b20')"),
@@ -156,7 +261,7 @@ func (s *Server) registerTools() {
 	), s.handleGetSpecs)
 
 	// Tool: get_device_refs - Get device references
-	s.mcp.AddTool(mcp.NewTool("get_device_refs",
+	s.addTool(mcp.NewTool("get_device_refs",
 		mcp.WithDescription("Get references for a device including related devices, external links, and documentation references. Useful for finding related hardware or additional resources."),
 		mcp.WithString("device_id",
 			mcp.Description("Device ID (e.g., 'sbc-raspberry-pi-raspberry-pi-5')"),
@@ -165,7 +270,7 @@ func (s *Server) registerTools() {
 	), s.handleGetDeviceRefs)
 
 	// Tool: list_documents - List documents
-	s.mcp.AddTool(mcp.NewTool("list_documents",
+	s.addTool(mcp.NewTool("list_documents",
 		mcp.WithDescription("List available PDF documents and datasheets. Returns document IDs, filenames, and sizes. Documents can be associated with specific devices or be standalone."),
 		mcp.WithString("device_id",
 			mcp.Description("Filter to show only documents for a specific device"),
@@ -176,7 +281,7 @@ func (s *Server) registerTools() {
 	), s.handleListDocuments)
 
 	// Tool: get_document - Get document details
-	s.mcp.AddTool(mcp.NewTool("get_document",
+	s.addTool(mcp.NewTool("get_document",
 		mcp.WithDescription("Get details for a specific document including filename, size, mime type, and checksum. Use the document_id from list_documents results."),
 		mcp.WithString("document_id",
 			mcp.Description("Document ID to retrieve"),
@@ -185,7 +290,7 @@ func (s *Server) registerTools() {
 	), s.handleGetDocument)
 
 	// Tool: list_guides - List documentation guides
-	s.mcp.AddTool(mcp.NewTool("list_guides",
+	s.addTool(mcp.NewTool("list_guides",
 		mcp.WithDescription("List available documentation guides. Guides provide tutorials, how-tos, and reference documentation that isn't device-specific."),
 		mcp.WithNumber("limit",
 			mcp.Description("Maximum results (default: 50)"),
@@ -193,7 +298,7 @@ func (s *Server) registerTools() {
 	), s.handleListGuides)
 
 	// Tool: get_guide - Get guide content
-	s.mcp.AddTool(mcp.NewTool("get_guide",
+	s.addTool(mcp.NewTool("get_guide",
 		mcp.WithDescription("Get full content of a documentation guide. Use the guide_id from list_guides results."),
 		mcp.WithString("guide_id",
 			mcp.Description("Guide ID to retrieve"),
@@ -201,13 +306,43 @@ func (s *Server) registerTools() {
 		),
 	), s.handleGetGuide)
 
+	// Tool: doc_history - Git history for a documentation file
+	s.addTool(mcp.NewTool("doc_history",
+		mcp.WithDescription("Show the git commit history for a documentation file, newest first. Use to answer questions like 'what changed in the BME280 page last week'. A device folder path resolves to its README.md."),
+		mcp.WithString("path",
+			mcp.Description("Path in docs storage (e.g., 'sensors/environmental/bme280' or 'sensors/environmental/bme280/README.md')"),
+			mcp.Required(),
+		),
+		mcp.WithString("since",
+			mcp.Description("Only show commits after this date (e.g., '2025-12-01')"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum commits to return (default: 20)"),
+		),
+	), s.handleDocHistory)
+
+	// Tool: doc_diff - Unified diff for a documentation file
+	s.addTool(mcp.NewTool("doc_diff",
+		mcp.WithDescription("Show a unified diff of a documentation file between two git revisions. Use commit hashes from doc_history. A device folder path resolves to its README.md."),
+		mcp.WithString("path",
+			mcp.Description("Path in docs storage (e.g., 'sensors/environmental/bme280')"),
+			mcp.Required(),
+		),
+		mcp.WithString("from",
+			mcp.Description("Starting revision (commit hash or ref). Defaults to the commit before 'to'."),
+		),
+		mcp.WithString("to",
+			mcp.Description("Ending revision (commit hash or ref). Defaults to HEAD."),
+		),
+	), s.handleDocDiff)
+
 	// Tool: get_status - Get API status
-	s.mcp.AddTool(mcp.NewTool("get_status",
+	s.addTool(mcp.NewTool("get_status",
 		mcp.WithDescription("Get Manuals API health status and database statistics. Shows total device count, document count, and last reindex time. Use to verify the API is operational."),
 	), s.handleGetStatus)
 
 	// Tool: info - Get MCP server information
-	s.mcp.AddTool(mcp.NewTool("info",
+	s.addTool(mcp.NewTool("info",
 		mcp.WithDescription("Get MCP server version, build info, API connection status, and current authentication details. Shows your user name, role, and what capabilities are available to you."),
 	), s.handleInfo)
 
@@ -216,24 +351,32 @@ func (s *Server) registerTools() {
 	// ===========================================
 
 	// Tool: trigger_reindex - Trigger documentation reindex
-	s.mcp.AddTool(mcp.NewTool("trigger_reindex",
+	s.addTool(mcp.NewTool("trigger_reindex",
 		mcp.WithDescription("Trigger a background reindex of all documentation. The index is updated from files in the docs storage. Use after uploading new files. Requires RW or Admin role."),
 	), s.handleTriggerReindex)
 
 	// Tool: get_reindex_status - Get reindex status
-	s.mcp.AddTool(mcp.NewTool("get_reindex_status",
+	s.addTool(mcp.NewTool("get_reindex_status",
 		mcp.WithDescription("Check the status of the documentation reindex operation. Shows if reindex is running, last completion time, and statistics from the last run. Requires RW or Admin role."),
 	), s.handleGetReindexStatus)
 
+	// Tool: wait_for_reindex - Wait for a running reindex to finish
+	s.addTool(mcp.NewTool("wait_for_reindex",
+		mcp.WithDescription("Wait for a running documentation reindex to finish. Use after publish or trigger_reindex reports the reindex is still running. Sends progress notifications while waiting. Requires RW or Admin role."),
+		mcp.WithNumber("timeout_seconds",
+			mcp.Description("Maximum seconds to wait (default: 60, max: 600)"),
+		),
+	), s.handleWaitForReindex)
+
 	// Tool: upload_file - Upload a file from local filesystem
-	s.mcp.AddTool(mcp.NewTool("upload_file",
+	s.addTool(mcp.NewTool("upload_file",
 		mcp.WithDescription("Upload a file to the documentation storage. Can read directly from a local file path (preferred) or accept content as a string. Requires RW or Admin role."),
 		mcp.WithString("dest_path",
 			mcp.Description("Destination path in docs storage (e.g., 'sensors/environmental/bme680/BME680_Reference.md' or 'guides/QUICKSTART.md')"),
 			mcp.Required(),
 		),
 		mcp.WithString("local_path",
-			mcp.Description("Local filesystem path to read the file from (e.g., '/home/user/docs/README.md'). Preferred over content parameter."),
+			mcp.Description("Local filesystem path to read the file from (e.g., '/tmp/device_docs/README.md'). Must be inside a directory the server allows; key and credential files are always refused. Preferred over content parameter."),
 		),
 		mcp.WithString("content",
 			mcp.Description("File content as text. Only use if local_path is not available. For binary files, use local_path instead."),
@@ -241,14 +384,14 @@ func (s *Server) registerTools() {
 	), s.handleUploadFile)
 
 	// Tool: publish - Upload file and trigger reindex in one operation
-	s.mcp.AddTool(mcp.NewTool("publish",
+	s.addTool(mcp.NewTool("publish",
 		mcp.WithDescription("Upload a file and automatically trigger reindex. Combines upload_file + trigger_reindex in one operation. This is the preferred method for publishing new documentation. Requires RW or Admin role."),
 		mcp.WithString("dest_path",
 			mcp.Description("Destination path in docs storage (e.g., 'sensors/temperature/ds18b20/DS18B20_Reference.md')"),
 			mcp.Required(),
 		),
 		mcp.WithString("local_path",
-			mcp.Description("Local filesystem path to read the file from. Preferred method."),
+			mcp.Description("Local filesystem path to read the file from. Must be inside a directory the server allows. Preferred method."),
 		),
 		mcp.WithString("content",
 			mcp.Description("File content as text. Only use if local_path is not available."),
@@ -256,10 +399,16 @@ func (s *Server) registerTools() {
 		mcp.WithBoolean("wait_for_reindex",
 			mcp.Description("If true, wait for reindex to complete before returning (default: false)"),
 		),
+		mcp.WithNumber("reindex_timeout",
+			mcp.Description("Seconds to wait for reindex when wait_for_reindex is true (default: 60, max: 600)"),
+		),
+		mcp.WithBoolean("verify",
+			mcp.Description("If true, wait for reindex and then verify the device is indexed at the expected path with a parsed pinout and specs, reporting any indexer errors (default: false)"),
+		),
 	), s.handlePublish)
 
 	// Tool: publish_batch - Upload multiple files and trigger single reindex
-	s.mcp.AddTool(mcp.NewTool("publish_batch",
+	s.addTool(mcp.NewTool("publish_batch",
 		mcp.WithDescription("Upload multiple files and trigger a single reindex after all uploads complete. More efficient than multiple publish calls. Requires RW or Admin role."),
 		mcp.WithString("files",
 			mcp.Description("JSON array of file objects: [{\"local_path\": \"/path/to/file\", \"dest_path\": \"sensors/temp/file.md\"}, ...]. Each object must have dest_path and either local_path or content."),
@@ -268,11 +417,17 @@ func (s *Server) registerTools() {
 		mcp.WithBoolean("wait_for_reindex",
 			mcp.Description("If true, wait for reindex to complete before returning (default: false)"),
 		),
+		mcp.WithNumber("reindex_timeout",
+			mcp.Description("Seconds to wait for reindex when wait_for_reindex is true (default: 60, max: 600)"),
+		),
+		mcp.WithBoolean("atomic",
+			mcp.Description("If true, publish all files or none: every file is read and the files it would replace are saved before uploading. If any upload fails, files created by this call are deleted, replaced files are restored to their previous content, and no reindex runs (default: false)"),
+		),
 	), s.handlePublishBatch)
 
 	// Tool: delete_file - Delete a file from documentation storage
-	s.mcp.AddTool(mcp.NewTool("delete_file",
-		mcp.WithDescription("Delete a file from the documentation storage. Use to remove incorrect files, duplicates, or outdated documentation. Optionally trigger reindex to update search results immediately. Requires RW or Admin role."),
+	s.addTool(mcp.NewTool("delete_file",
+		mcp.WithDescription("Delete a file from the documentation storage. Use to remove incorrect files, duplicates, or outdated documentation. Optionally trigger reindex to update search results immediately. Requires human confirmation. Requires RW or Admin role."),
 		mcp.WithString("path",
 			mcp.Description("Path to file in docs storage (e.g., 'power-supplies/fnirsi-dps150/FNIRSI_DPS150.md'). This is the same path format used in upload_file's dest_path."),
 			mcp.Required(),
@@ -280,11 +435,23 @@ func (s *Server) registerTools() {
 		mcp.WithBoolean("reindex",
 			mcp.Description("Trigger reindex after deletion to update search results immediately (default: false). Set to true if you want the file removed from search results right away."),
 		),
+		mcp.WithString("confirm_token",
+			mcp.Description("Confirmation token from a previous call. Only pass this after the user has explicitly approved the operation."),
+		),
 	), s.handleDeleteFile)
 
 	// Tool: sync_to_git - Sync documentation to git repository
-	s.mcp.AddTool(mcp.NewTool("sync_to_git",
-		mcp.WithDescription("Sync all documentation changes to the git repository. Commits and pushes any new or modified files to the remote repository. Use this after publishing new documentation to persist changes. Requires RW or Admin role."),
+	s.addTool(mcp.NewTool("sync_to_git",
+		mcp.WithDescription("Sync documentation changes to the git repository. Commits new or modified files as the authenticated user and pushes them to the remote repository. Use this after publishing new documentation to persist changes. Requires RW or Admin role."),
+		mcp.WithString("message",
+			mcp.Description("Commit message describing the change (e.g., 'Add BME280 sensor documentation'). Defaults to the server's generic sync message."),
+		),
+		mcp.WithString("paths",
+			mcp.Description("Comma-separated paths to include in the commit (e.g., 'sensors/environmental/bme280,guides/QUICKSTART.md'). Defaults to all changes."),
+		),
+		mcp.WithBoolean("push",
+			mcp.Description("Push the commit to the remote repository (default: true). Set to false to commit locally only."),
+		),
 	), s.handleSyncToGit)
 
 	// ===========================================
@@ -292,13 +459,13 @@ func (s *Server) registerTools() {
 	// ===========================================
 
 	// Tool: list_users - List all users
-	s.mcp.AddTool(mcp.NewTool("list_users",
-		mcp.WithDescription("List all users with their roles, status, and creation dates. Use to audit user access. Requires Admin role."),
+	s.addTool(mcp.NewTool("list_users",
+		mcp.WithDescription("List all users with their roles, capabilities, active status, creation dates, and when they were last seen. Use to audit user access. Requires Admin role."),
 	), s.handleListUsers)
 
 	// Tool: create_user - Create a new user
-	s.mcp.AddTool(mcp.NewTool("create_user",
-		mcp.WithDescription("Create a new user account and generate an API key. IMPORTANT: The API key is only shown once - save it immediately. Requires Admin role."),
+	s.addTool(mcp.NewTool("create_user",
+		mcp.WithDescription("Create a new user account and generate an API key. IMPORTANT: The API key is only shown once - save it immediately. If the server stores keys outside the conversation, only a reference and fingerprint are returned. Requires Admin role."),
 		mcp.WithString("name",
 			mcp.Description("User name (e.g., 'alice', 'ci-bot', 'readonly-viewer')"),
 			mcp.Required(),
@@ -307,19 +474,25 @@ func (s *Server) registerTools() {
 			mcp.Description("User role: 'admin' (full access), 'rw' (read + publish docs), or 'ro' (read-only)"),
 			mcp.Required(),
 		),
+		mcp.WithNumber("expires_in_days",
+			mcp.Description("Days until the API key expires (default: never, max: 3650)"),
+		),
 	), s.handleCreateUser)
 
 	// Tool: delete_user - Delete a user
-	s.mcp.AddTool(mcp.NewTool("delete_user",
-		mcp.WithDescription("Delete a user account and invalidate their API key. This action cannot be undone. Requires Admin role."),
+	s.addTool(mcp.NewTool("delete_user",
+		mcp.WithDescription("Delete a user account and invalidate their API key. This action cannot be undone and requires human confirmation. Requires Admin role."),
 		mcp.WithString("user_id",
 			mcp.Description("User ID to delete (get from list_users)"),
 			mcp.Required(),
 		),
+		mcp.WithString("confirm_token",
+			mcp.Description("Confirmation token from a previous call. Only pass this after the user has explicitly approved the operation."),
+		),
 	), s.handleDeleteUser)
 
 	// Tool: update_user_role - Update a user's role
-	s.mcp.AddTool(mcp.NewTool("update_user_role",
+	s.addTool(mcp.NewTool("update_user_role",
 		mcp.WithDescription("Update a user's role. Valid roles are 'admin', 'rw', or 'ro'. Requires Admin role."),
 		mcp.WithString("user_id",
 			mcp.Description("User ID to update (get from list_users)"),
@@ -331,32 +504,166 @@ func (s *Server) registerTools() {
 		),
 	), s.handleUpdateUserRole)
 
+	// Tool: set_user_capabilities - Set fine-grained capabilities
+	s.addTool(mcp.NewTool("set_user_capabilities",
+		mcp.WithDescription("Replace a user's capability list with fine-grained capabilities instead of a fixed role. Example: 'read:*,write:publish' gives a CI bot read access plus publishing only. Shows the capabilities before and after. Requires Admin role."),
+		mcp.WithString("user_id",
+			mcp.Description("User ID to update (get from list_users)"),
+			mcp.Required(),
+		),
+		mcp.WithString("capabilities",
+			mcp.Description("Comma-separated capabilities: '*' or scope:name where scope is read, write or admin and name may be '*' (e.g., 'read:*,write:publish')"),
+			mcp.Required(),
+		),
+	), s.handleSetUserCapabilities)
+
+	// Tool: set_user_active - Deactivate or reactivate a user
+	s.addTool(mcp.NewTool("set_user_active",
+		mcp.WithDescription("Deactivate or reactivate a user account. A deactivated user keeps their capabilities but their API key is rejected. Use instead of delete_user to suspend access reversibly. Requires Admin role."),
+		mcp.WithString("user_id",
+			mcp.Description("User ID to update (get from list_users)"),
+			mcp.Required(),
+		),
+		mcp.WithBoolean("active",
+			mcp.Description("false to deactivate, true to reactivate"),
+			mcp.Required(),
+		),
+	), s.handleSetUserActive)
+
+	// Tool: rename_user - Rename a user
+	s.addTool(mcp.NewTool("rename_user",
+		mcp.WithDescription("Change a user's name. The user ID and API key are unchanged. Requires Admin role."),
+		mcp.WithString("user_id",
+			mcp.Description("User ID to rename (get from list_users)"),
+			mcp.Required(),
+		),
+		mcp.WithString("name",
+			mcp.Description("New user name"),
+			mcp.Required(),
+		),
+	), s.handleRenameUser)
+
 	// Tool: rotate_api_key - Rotate a user's API key
-	s.mcp.AddTool(mcp.NewTool("rotate_api_key",
-		mcp.WithDescription("Generate a new API key for a user, invalidating the old one. IMPORTANT: The new API key is only shown once - save it immediately. Requires Admin role."),
+	s.addTool(mcp.NewTool("rotate_api_key",
+		mcp.WithDescription("Generate a new API key for a user, invalidating the old one. IMPORTANT: The new API key is only shown once - save it immediately. If the server stores keys outside the conversation, only a reference and fingerprint are returned. Requires human confirmation. Requires Admin role."),
 		mcp.WithString("user_id",
 			mcp.Description("User ID whose key to rotate (get from list_users)"),
 			mcp.Required(),
 		),
+		mcp.WithNumber("expires_in_days",
+			mcp.Description("Days until the new API key expires (default: never, max: 3650)"),
+		),
+		mcp.WithString("confirm_token",
+			mcp.Description("Confirmation token from a previous call. Only pass this after the user has explicitly approved the operation."),
+		),
 	), s.handleRotateAPIKey)
 
+	// Tool: list_stale_users - Find inactive users and old keys
+	s.addTool(mcp.NewTool("list_stale_users",
+		mcp.WithDescription("Flag users who have not been seen recently and API keys that are expired, expire within 7 days, or are older than the rotation window. Use before rotate_stale_keys. Requires Admin role."),
+		mcp.WithNumber("inactive_days",
+			mcp.Description("Flag users not seen for this many days (default: 90)"),
+		),
+		mcp.WithNumber("rotation_days",
+			mcp.Description("Flag keys older than this many days (default: 90)"),
+		),
+	), s.handleListStaleUsers)
+
+	// Tool: rotate_stale_keys - Bulk rotate old keys
+	s.addTool(mcp.NewTool("rotate_stale_keys",
+		mcp.WithDescription("Rotate every API key flagged by list_stale_users as expired, expiring, or past the rotation window. The key this server uses and deactivated users are skipped. New keys are shown once or stored in the server's key sink. Requires human confirmation. Requires Admin role."),
+		mcp.WithNumber("rotation_days",
+			mcp.Description("Rotate keys older than this many days (default: 90)"),
+		),
+		mcp.WithNumber("expires_in_days",
+			mcp.Description("Days until the new keys expire (default: never, max: 3650)"),
+		),
+		mcp.WithBoolean("include_inactive",
+			mcp.Description("Also rotate keys of deactivated users (default: false)"),
+		),
+		mcp.WithString("confirm_token",
+			mcp.Description("Confirmation token from a previous call. Only pass this after the user has explicitly approved the operation."),
+		),
+	), s.handleRotateStaleKeys)
+
+	// Tool: audit_log - Query the local audit log
+	s.addTool(mcp.NewTool("audit_log",
+		mcp.WithDescription("Query the local audit log of content and admin tool calls made through this MCP server (uploads, deletes, syncs, user and setting changes). Shows who acted, the redacted arguments, result status, and API response IDs. Newest entries first. Requires Admin role."),
+		mcp.WithString("tool",
+			mcp.Description("Filter by tool name (e.g., 'delete_file', 'publish')"),
+		),
+		mcp.WithString("user",
+			mcp.Description("Filter by acting user name"),
+		),
+		mcp.WithString("status",
+			mcp.Description("Filter by status: 'success', 'error', 'pending_confirmation', or 'cancelled'"),
+		),
+		mcp.WithString("since",
+			mcp.Description("Only entries after this time: a duration like '24h', a date like '2025-12-01', or an RFC 3339 timestamp"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum entries to return (default: 50)"),
+		),
+	), s.handleAuditLog)
+
 	// Tool: list_settings - List all settings
-	s.mcp.AddTool(mcp.NewTool("list_settings",
-		mcp.WithDescription("List all configuration settings and their current values. Requires Admin role."),
+	s.addTool(mcp.NewTool("list_settings",
+		mcp.WithDescription("List all configuration settings with their current values, types, and descriptions, plus available settings still using defaults. Requires Admin role."),
 	), s.handleListSettings)
 
 	// Tool: update_setting - Update a setting
-	s.mcp.AddTool(mcp.NewTool("update_setting",
-		mcp.WithDescription("Update a configuration setting value. Use list_settings to see available settings. Requires Admin role."),
+	s.addTool(mcp.NewTool("update_setting",
+		mcp.WithDescription("Update a configuration setting value. The value is validated against the setting's type and allowed values, and the change is shown as a diff. Use list_settings to see available settings. Requires human confirmation. Requires Admin role."),
 		mcp.WithString("key",
 			mcp.Description("Setting key to update"),
 			mcp.Required(),
 		),
 		mcp.WithString("value",
-			mcp.Description("New value for the setting"),
+			mcp.Description("New value for the setting (e.g., '4' for int, 'true' for bool, '30s' for duration)"),
 			mcp.Required(),
 		),
+		mcp.WithBoolean("force",
+			mcp.Description("Write the value without validation when setting types are unavailable (default: false)"),
+		),
+		mcp.WithString("confirm_token",
+			mcp.Description("Confirmation token from a previous call. Only pass this after the user has explicitly approved the operation."),
+		),
 	), s.handleUpdateSetting)
+
+	// Tool: export_settings - Back up settings as YAML
+	s.addTool(mcp.NewTool("export_settings",
+		mcp.WithDescription("Export all configuration settings as YAML for backup or to copy to another server. Restore with import_settings. Requires Admin role."),
+	), s.handleExportSettings)
+
+	// Tool: import_settings - Restore settings from YAML
+	s.addTool(mcp.NewTool("import_settings",
+		mcp.WithDescription("Restore configuration settings from YAML produced by export_settings. Every value is validated first and only settings that differ are changed; the diff is shown before anything is applied. Settings missing from the YAML are left unchanged. Requires human confirmation. Requires Admin role."),
+		mcp.WithString("yaml",
+			mcp.Description("Settings YAML as produced by export_settings"),
+		),
+		mcp.WithString("local_path",
+			mcp.Description("Local path of a settings YAML file. Used instead of yaml."),
+		),
+		mcp.WithBoolean("dry_run",
+			mcp.Description("If true, only validate and show the diff (default: false)"),
+		),
+		mcp.WithBoolean("force",
+			mcp.Description("Apply the values without validation when setting types are unavailable (default: false)"),
+		),
+		mcp.WithString("confirm_token",
+			mcp.Description("Confirmation token from a previous call. Only pass this after the user has explicitly approved the operation."),
+		),
+	), s.handleImportSettings)
+
+	// Tool: switch_profile - Switch to another config profile
+	if len(s.profiles.names) > 0 {
+		s.addTool(mcp.NewTool("switch_profile",
+			mcp.WithDescription("Switch the server to another profile from the config file (e.g. lab, prod, readonly), replacing the API backends and key for all later tool calls. Without a profile, lists the available profiles. Requires Admin role in the active profile, so a restricted profile cannot switch back."),
+			mcp.WithString("profile",
+				mcp.Description("Profile to switch to: "+strings.Join(s.profiles.names, ", ")),
+			),
+		), s.handleSwitchProfile)
+	}
 }
 
 // registerResources registers MCP resources.
@@ -398,8 +705,8 @@ func (s *Server) handleMyCapabilities(ctx context.Context, request mcp.CallToolR
 	// Check authentication status
 	var role string
 	var userName string
-	if s.client.HasAPIKey() {
-		user, err := s.client.GetMe()
+	if s.api(ctx).HasAPIKey() {
+		user, err := s.api(ctx).GetMe()
 		if err != nil {
 			sb.WriteString("**Status:** Error checking authentication\n\n")
 			role = "unknown"
@@ -427,6 +734,8 @@ func (s *Server) handleMyCapabilities(ctx context.Context, request mcp.CallToolR
 	sb.WriteString("| `get_pinout` | Get GPIO pinout table |\n")
 	sb.WriteString("| `get_specs` | Get device specifications |\n")
 	sb.WriteString("| `list_documents` | List PDFs and datasheets |\n")
+	sb.WriteString("| `doc_history` | Git history for a device page |\n")
+	sb.WriteString("| `doc_diff` | Diff a device page between commits |\n")
 	sb.WriteString("| `get_status` | Check API health |\n")
 	sb.WriteString("| `info` | Get server and auth info |\n")
 	sb.WriteString("| `ingest_workflow` | Get document ingestion guidance |\n\n")
@@ -441,6 +750,7 @@ func (s *Server) handleMyCapabilities(ctx context.Context, request mcp.CallToolR
 		sb.WriteString("| `publish_batch` | Upload multiple files + reindex |\n")
 		sb.WriteString("| `trigger_reindex` | Manually trigger reindex |\n")
 		sb.WriteString("| `get_reindex_status` | Check reindex progress |\n")
+		sb.WriteString("| `wait_for_reindex` | Wait for a running reindex to finish |\n")
 		sb.WriteString("| `sync_to_git` | Commit and push docs to git repo |\n\n")
 	} else {
 		sb.WriteString("## Content Management Tools (Requires RW Role)\n\n")
@@ -454,7 +764,19 @@ func (s *Server) handleMyCapabilities(ctx context.Context, request mcp.CallToolR
 		sb.WriteString("|------|-------------|\n")
 		sb.WriteString("| `list_users` | List all user accounts |\n")
 		sb.WriteString("| `create_user` | Create new user + API key |\n")
-		sb.WriteString("| `delete_user` | Delete user account |\n\n")
+		sb.WriteString("| `delete_user` | Delete user account |\n")
+		sb.WriteString("| `set_user_capabilities` | Grant fine-grained capabilities |\n")
+		sb.WriteString("| `set_user_active` | Deactivate or reactivate a user |\n")
+		sb.WriteString("| `rename_user` | Rename a user |\n")
+		sb.WriteString("| `list_stale_users` | Find inactive users and old keys |\n")
+		sb.WriteString("| `rotate_stale_keys` | Rotate all stale keys |\n")
+		sb.WriteString("| `export_settings` | Back up settings as YAML |\n")
+		sb.WriteString("| `import_settings` | Restore settings from YAML |\n")
+		sb.WriteString("| `audit_log` | Review mutating tool calls |\n")
+		if len(s.profiles.names) > 0 {
+			sb.WriteString("| `switch_profile` | Switch to another config profile |\n")
+		}
+		sb.WriteString("\n")
 	} else if role == "rw" {
 		sb.WriteString("## Admin Tools (Requires Admin Role)\n\n")
 		sb.WriteString("*Not available with your current role.*\n\n")
@@ -484,8 +806,8 @@ func (s *Server) handleIngestWorkflow(ctx context.Context, request mcp.CallToolR
 	sb.WriteString("# Document Ingestion Workflow\n\n")
 
 	// Check if user has RW permissions
-	if s.client.HasAPIKey() {
-		user, err := s.client.GetMe()
+	if s.api(ctx).HasAPIKey() {
+		user, err := s.api(ctx).GetMe()
 		if err == nil && user != nil && (user.CanWrite() || user.CanAdmin()) {
 			sb.WriteString("**Your Role:** " + user.Role() + " ✓ (can publish)\n\n")
 		} else {
@@ -601,7 +923,7 @@ func (s *Server) handleIngestWorkflow(ctx context.Context, request mcp.CallToolR
 	sb.WriteString("   ```\n\n")
 
 	sb.WriteString("## Step 5: Verify\n\n")
-	sb.WriteString("After publishing:\n")
+	sb.WriteString("Publish with `verify: true` to run these checks automatically, or after publishing:\n")
 	sb.WriteString("1. `get_reindex_status()` - Confirm reindex completed\n")
 	sb.WriteString("2. `search_manuals(query: \"device name\")` - Verify document is searchable\n")
 	sb.WriteString("3. `get_device(device_id: \"...\")` - Check content renders correctly\n")
@@ -639,7 +961,7 @@ func (s *Server) handleSearch(ctx context.Context, request mcp.CallToolRequest)
 		limit = int(l)
 	}
 
-	results, err := s.client.Search(query, limit, domain, deviceType)
+	results, warnings, err := s.search(ctx, query, limit, domain, deviceType)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("search failed: %v", err)), nil
 	}
@@ -655,6 +977,7 @@ func (s *Server) handleSearch(ctx context.Context, request mcp.CallToolRequest)
 		}
 		sb.WriteString("\n")
 	}
+	writeBackendWarnings(&sb, warnings)
 
 	return mcp.NewToolResultText(sb.String()), nil
 }
@@ -669,7 +992,7 @@ func (s *Server) handleSemanticSearch(ctx context.Context, request mcp.CallToolR
 		limit = int(l)
 	}
 
-	results, err := s.client.SemanticSearch(query, limit, domain, deviceType)
+	results, warnings, err := s.semanticSearch(ctx, query, limit, domain, deviceType)
 	if err != nil {
 		// Check if semantic search is not enabled
 		if strings.Contains(err.Error(), "not enabled") || strings.Contains(err.Error(), "503") {
@@ -704,6 +1027,7 @@ func (s *Server) handleSemanticSearch(ctx context.Context, request mcp.CallToolR
 		sb.WriteString("- Removing filters to broaden the search\n")
 		sb.WriteString("- Using `search_manuals` for keyword-based search\n")
 	}
+	writeBackendWarnings(&sb, warnings)
 
 	return mcp.NewToolResultText(sb.String()), nil
 }
@@ -712,14 +1036,14 @@ func (s *Server) handleGetDevice(ctx context.Context, request mcp.CallToolReques
 	args := request.GetArguments()
 	deviceID, _ := args["device_id"].(string)
 
-	device, err := s.client.GetDevice(deviceID, true)
+	device, err := s.api(ctx).GetDevice(deviceID, true)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to get device: %v", err)), nil
 	}
 
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("# %s\n\n", device.Name))
-	sb.WriteString(fmt.Sprintf("**ID:** %s\n", device.ID))
+	sb.WriteString(fmt.Sprintf("**ID:** %s\n", s.qualify(s.selectedBackend(ctx), device.ID)))
 	sb.WriteString(fmt.Sprintf("**Domain:** %s\n", device.Domain))
 	sb.WriteString(fmt.Sprintf("**Type:** %s\n", device.Type))
 	sb.WriteString(fmt.Sprintf("**Path:** %s\n", device.Path))
@@ -742,7 +1066,7 @@ func (s *Server) handleListDevices(ctx context.Context, request mcp.CallToolRequ
 		limit = int(l)
 	}
 
-	result, err := s.client.ListDevices(limit, 0, domain, deviceType)
+	result, warnings, err := s.listDevices(ctx, limit, domain, deviceType)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to list devices: %v", err)), nil
 	}
@@ -753,6 +1077,7 @@ func (s *Server) handleListDevices(ctx context.Context, request mcp.CallToolRequ
 	for _, d := range result.Data {
 		sb.WriteString(fmt.Sprintf("- **%s** (ID: %s) - %s/%s\n", d.Name, d.ID, d.Domain, d.Type))
 	}
+	writeBackendWarnings(&sb, warnings)
 
 	return mcp.NewToolResultText(sb.String()), nil
 }
@@ -761,7 +1086,7 @@ func (s *Server) handleGetPinout(ctx context.Context, request mcp.CallToolReques
 	args := request.GetArguments()
 	deviceID, _ := args["device_id"].(string)
 
-	pinout, err := s.client.GetDevicePinout(deviceID)
+	pinout, err := s.api(ctx).GetDevicePinout(deviceID)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to get pinout: %v", err)), nil
 	}
@@ -786,7 +1111,7 @@ func (s *Server) handleGetSpecs(ctx context.Context, request mcp.CallToolRequest
 	args := request.GetArguments()
 	deviceID, _ := args["device_id"].(string)
 
-	specs, err := s.client.GetDeviceSpecs(deviceID)
+	specs, err := s.api(ctx).GetDeviceSpecs(deviceID)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to get specs: %v", err)), nil
 	}
@@ -805,7 +1130,7 @@ func (s *Server) handleGetDeviceRefs(ctx context.Context, request mcp.CallToolRe
 	args := request.GetArguments()
 	deviceID, _ := args["device_id"].(string)
 
-	refs, err := s.client.GetDeviceRefs(deviceID)
+	refs, err := s.api(ctx).GetDeviceRefs(deviceID)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to get device refs: %v", err)), nil
 	}
@@ -820,7 +1145,7 @@ func (s *Server) handleGetDeviceRefs(ctx context.Context, request mcp.CallToolRe
 			if ref.URL != "" {
 				sb.WriteString(fmt.Sprintf("- **%s** (%s): [%s](%s)\n", ref.Title, ref.Type, ref.URL, ref.URL))
 			} else if ref.ID != "" {
-				sb.WriteString(fmt.Sprintf("- **%s** (%s): Device ID: %s\n", ref.Title, ref.Type, ref.ID))
+				sb.WriteString(fmt.Sprintf("- **%s** (%s): Device ID: %s\n", ref.Title, ref.Type, s.qualify(s.selectedBackend(ctx), ref.ID)))
 			} else {
 				sb.WriteString(fmt.Sprintf("- **%s** (%s)\n", ref.Title, ref.Type))
 			}
@@ -838,7 +1163,7 @@ func (s *Server) handleListDocuments(ctx context.Context, request mcp.CallToolRe
 		limit = int(l)
 	}
 
-	result, err := s.client.ListDocuments(limit, 0, deviceID)
+	result, warnings, err := s.listDocuments(ctx, limit, deviceID)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to list documents: %v", err)), nil
 	}
@@ -850,6 +1175,7 @@ func (s *Server) handleListDocuments(ctx context.Context, request mcp.CallToolRe
 		size := float64(d.SizeBytes) / 1024
 		sb.WriteString(fmt.Sprintf("- **%s** (ID: %s) - %.1f KB\n", d.Filename, d.ID, size))
 	}
+	writeBackendWarnings(&sb, warnings)
 
 	return mcp.NewToolResultText(sb.String()), nil
 }
@@ -858,15 +1184,15 @@ func (s *Server) handleGetDocument(ctx context.Context, request mcp.CallToolRequ
 	args := request.GetArguments()
 	documentID, _ := args["document_id"].(string)
 
-	doc, err := s.client.GetDocument(documentID)
+	doc, err := s.api(ctx).GetDocument(documentID)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to get document: %v", err)), nil
 	}
 
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("# Document: %s\n\n", doc.Filename))
-	sb.WriteString(fmt.Sprintf("- **ID:** %s\n", doc.ID))
-	sb.WriteString(fmt.Sprintf("- **Device ID:** %s\n", doc.DeviceID))
+	sb.WriteString(fmt.Sprintf("- **ID:** %s\n", s.qualify(s.selectedBackend(ctx), doc.ID)))
+	sb.WriteString(fmt.Sprintf("- **Device ID:** %s\n", s.qualify(s.selectedBackend(ctx), doc.DeviceID)))
 	sb.WriteString(fmt.Sprintf("- **Path:** %s\n", doc.Path))
 	sb.WriteString(fmt.Sprintf("- **MIME Type:** %s\n", doc.MimeType))
 	sb.WriteString(fmt.Sprintf("- **Size:** %.1f KB\n", float64(doc.SizeBytes)/1024))
@@ -883,7 +1209,7 @@ func (s *Server) handleListGuides(ctx context.Context, request mcp.CallToolReque
 		limit = int(l)
 	}
 
-	result, err := s.client.ListGuides(limit, 0)
+	result, warnings, err := s.listGuides(ctx, limit)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to list guides: %v", err)), nil
 	}
@@ -894,6 +1220,7 @@ func (s *Server) handleListGuides(ctx context.Context, request mcp.CallToolReque
 	for _, g := range result.Data {
 		sb.WriteString(fmt.Sprintf("- **%s** (ID: %s)\n", g.Title, g.ID))
 	}
+	writeBackendWarnings(&sb, warnings)
 
 	return mcp.NewToolResultText(sb.String()), nil
 }
@@ -902,14 +1229,14 @@ func (s *Server) handleGetGuide(ctx context.Context, request mcp.CallToolRequest
 	args := request.GetArguments()
 	guideID, _ := args["guide_id"].(string)
 
-	guide, err := s.client.GetGuide(guideID)
+	guide, err := s.api(ctx).GetGuide(guideID)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to get guide: %v", err)), nil
 	}
 
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("# %s\n\n", guide.Title))
-	sb.WriteString(fmt.Sprintf("**ID:** %s\n", guide.ID))
+	sb.WriteString(fmt.Sprintf("**ID:** %s\n", s.qualify(s.selectedBackend(ctx), guide.ID)))
 	sb.WriteString(fmt.Sprintf("**Path:** %s\n\n", guide.Path))
 	if guide.Content != "" {
 		sb.WriteString("---\n\n")
@@ -919,8 +1246,108 @@ func (s *Server) handleGetGuide(ctx context.Context, request mcp.CallToolRequest
 	return mcp.NewToolResultText(sb.String()), nil
 }
 
+// maxDiffLength limits how much of a diff is returned to the model.
+const maxDiffLength = 50000
+
+// docFilePath resolves a device folder path to its README.md. Paths that
+// already name a markdown file are returned unchanged; folder names may
+// contain dots, e.g. boards/esp32-s3.v2.
+func docFilePath(p string) string {
+	p = strings.Trim(p, "/")
+	if strings.HasSuffix(p, ".md") {
+		return p
+	}
+	return path.Join(p, "README.md")
+}
+
+func (s *Server) handleDocHistory(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	path, _ := args["path"].(string)
+	since, _ := args["since"].(string)
+	limit := 20
+	if l, ok := args["limit"].(float64); ok {
+		limit = int(l)
+	}
+
+	if path == "" {
+		return mcp.NewToolResultError("path parameter is required"), nil
+	}
+
+	history, err := s.api(ctx).DocHistory(docFilePath(path), limit, since)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get history: %v", err)), nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# History for %s\n\n", history.Path))
+
+	if len(history.Commits) == 0 {
+		sb.WriteString("No commits found.\n")
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+
+	sb.WriteString("| Commit | Date | Author | Message |\n")
+	sb.WriteString("|--------|------|--------|---------|\n")
+	for _, c := range history.Commits {
+		hash := c.ShortHash
+		if hash == "" {
+			hash = c.Hash
+		}
+		message, _, _ := strings.Cut(c.Message, "\n")
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n", hash, c.Date, c.Author, message))
+	}
+	sb.WriteString("\nUse `doc_diff(path, from, to)` with these commit hashes to see what changed.\n")
+
+	return mcp.NewToolResultText(sb.String()), nil
+}
+
+func (s *Server) handleDocDiff(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	path, _ := args["path"].(string)
+	from, _ := args["from"].(string)
+	to, _ := args["to"].(string)
+
+	if path == "" {
+		return mcp.NewToolResultError("path parameter is required"), nil
+	}
+
+	diff, err := s.api(ctx).DocDiff(docFilePath(path), from, to)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get diff: %v", err)), nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Diff for %s\n\n", diff.Path))
+	sb.WriteString(fmt.Sprintf("- **From:** %s\n", diff.From))
+	sb.WriteString(fmt.Sprintf("- **To:** %s\n", diff.To))
+	sb.WriteString(fmt.Sprintf("- **Changes:** +%d / -%d lines\n\n", diff.Additions, diff.Deletions))
+
+	if diff.Diff == "" {
+		sb.WriteString("No differences.\n")
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+
+	text := diff.Diff
+	truncated := len(text) > maxDiffLength
+	if truncated {
+		// Cut at a line boundary so the last diff line stays intact
+		text = text[:maxDiffLength]
+		if cut := strings.LastIndex(text, "\n"); cut > 0 {
+			text = text[:cut+1]
+		}
+	}
+	sb.WriteString("```diff\n")
+	sb.WriteString(strings.TrimRight(text, "\n"))
+	sb.WriteString("\n```\n")
+	if truncated {
+		sb.WriteString(fmt.Sprintf("\n*Diff truncated to %d bytes at a line boundary.*\n", len(text)))
+	}
+
+	return mcp.NewToolResultText(sb.String()), nil
+}
+
 func (s *Server) handleGetStatus(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	status, err := s.client.GetStatus()
+	status, err := s.api(ctx).GetStatus()
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to get status: %v", err)), nil
 	}
@@ -949,19 +1376,36 @@ func (s *Server) handleInfo(ctx context.Context, request mcp.CallToolRequest) (*
 	sb.WriteString(fmt.Sprintf("- **Git Commit:** %s\n", s.gitCommit))
 	sb.WriteString(fmt.Sprintf("- **Build Time:** %s\n", s.buildTime))
 	sb.WriteString("- **Project:** github.com/rmrfslashbin/manuals-mcp\n")
-	sb.WriteString("- **License:** MIT\n\n")
+	sb.WriteString("- **License:** MIT\n")
+	if profile := s.currentProfile(); profile != "" {
+		sb.WriteString(fmt.Sprintf("- **Profile:** %s\n", profile))
+	}
+	sb.WriteString("\n")
+
+	// Configured backends
+	if s.federated() {
+		sb.WriteString("## Backends\n\n")
+		sb.WriteString("Searches and listings query every backend; IDs are namespaced as `backend:id`.\n\n")
+		for _, b := range s.backendList() {
+			sb.WriteString(fmt.Sprintf("- **%s:** %s (API %s)\n", b.Name, b.Client.GetAPIURL(), b.Client.Version()))
+		}
+		sb.WriteString("\n")
+	}
 
 	// API Connection info
 	sb.WriteString("## API Connection\n\n")
-	sb.WriteString(fmt.Sprintf("- **API URL:** %s\n", s.client.GetAPIURL()))
+	if s.federated() {
+		sb.WriteString(fmt.Sprintf("- **Backend:** %s\n", s.selectedBackend(ctx).Name))
+	}
+	sb.WriteString(fmt.Sprintf("- **API URL:** %s\n", s.api(ctx).GetAPIURL()))
 
 	// Get API status
-	status, err := s.client.GetStatus()
+	status, err := s.api(ctx).GetStatus()
 	if err != nil {
 		sb.WriteString(fmt.Sprintf("- **Status:** Error (%v)\n", err))
 	} else {
 		sb.WriteString(fmt.Sprintf("- **Status:** %s\n", status.Status))
-		sb.WriteString(fmt.Sprintf("- **API Version:** %s\n", status.APIVersion))
+		sb.WriteString(fmt.Sprintf("- **API Version:** %s (client supports %s)\n", s.api(ctx).Version(), strings.Join(client.SupportedVersions, ", ")))
 		sb.WriteString(fmt.Sprintf("- **Devices:** %d\n", status.Counts.Devices))
 		sb.WriteString(fmt.Sprintf("- **Documents:** %d\n", status.Counts.Documents))
 	}
@@ -969,9 +1413,9 @@ func (s *Server) handleInfo(ctx context.Context, request mcp.CallToolRequest) (*
 
 	// Authentication info
 	sb.WriteString("## Authentication\n\n")
-	if s.client.HasAPIKey() {
+	if s.api(ctx).HasAPIKey() {
 		sb.WriteString("- **Mode:** Authenticated\n")
-		user, err := s.client.GetMe()
+		user, err := s.api(ctx).GetMe()
 		if err != nil {
 			sb.WriteString(fmt.Sprintf("- **User:** Error fetching user info (%v)\n", err))
 		} else if user != nil {
@@ -992,16 +1436,17 @@ func (s *Server) handleInfo(ctx context.Context, request mcp.CallToolRequest) (*
 // RW tool handlers
 
 func (s *Server) handleTriggerReindex(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	resp, err := s.client.TriggerReindex()
+	resp, err := s.api(ctx).TriggerReindex()
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to trigger reindex: %v", err)), nil
 	}
+	auditRef(ctx, "reindex", resp.Status)
 
 	return mcp.NewToolResultText(fmt.Sprintf("# Reindex Triggered\n\n- **Status:** %s\n- **Message:** %s\n", resp.Status, resp.Message)), nil
 }
 
 func (s *Server) handleGetReindexStatus(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	resp, err := s.client.GetReindexStatus()
+	resp, err := s.reindexStatus(ctx)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to get reindex status: %v", err)), nil
 	}
@@ -1050,9 +1495,9 @@ func (s *Server) handleUploadFile(ctx context.Context, request mcp.CallToolReque
 	// Prefer local_path over content
 	if localPath != "" {
 		// Read file from local filesystem
-		data, err := os.ReadFile(localPath)
+		data, err := s.readLocalFile(localPath)
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to read local file '%s': %v", localPath, err)), nil
+			return mcp.NewToolResultError(err.Error()), nil
 		}
 		fileContent = data
 		filename = filepath.Base(localPath)
@@ -1064,10 +1509,11 @@ func (s *Server) handleUploadFile(ctx context.Context, request mcp.CallToolReque
 		return mcp.NewToolResultError("either local_path or content must be provided"), nil
 	}
 
-	resp, err := s.client.UploadFile(destPath, filename, fileContent)
+	resp, err := s.uploadFile(ctx, destPath, filename, fileContent)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to upload file: %v", err)), nil
 	}
+	auditRef(ctx, "path", resp.Path)
 
 	var sb strings.Builder
 	sb.WriteString("# File Uploaded\n\n")
@@ -1088,6 +1534,8 @@ func (s *Server) handlePublish(ctx context.Context, request mcp.CallToolRequest)
 	localPath, _ := args["local_path"].(string)
 	content, _ := args["content"].(string)
 	waitForReindex, _ := args["wait_for_reindex"].(bool)
+	verify, _ := args["verify"].(bool)
+	reindexTimeout := reindexTimeoutArg(args, "reindex_timeout")
 
 	if destPath == "" {
 		return mcp.NewToolResultError("dest_path is required"), nil
@@ -1098,9 +1546,9 @@ func (s *Server) handlePublish(ctx context.Context, request mcp.CallToolRequest)
 
 	// Prefer local_path over content
 	if localPath != "" {
-		data, err := os.ReadFile(localPath)
+		data, err := s.readLocalFile(localPath)
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to read local file '%s': %v", localPath, err)), nil
+			return mcp.NewToolResultError(err.Error()), nil
 		}
 		fileContent = data
 		filename = filepath.Base(localPath)
@@ -1115,10 +1563,15 @@ func (s *Server) handlePublish(ctx context.Context, request mcp.CallToolRequest)
 	sb.WriteString("# Publish Results\n\n")
 
 	// Upload file
-	uploadResp, err := s.client.UploadFile(destPath, filename, fileContent)
+	var previousIndexedAt string
+	if verify {
+		previousIndexedAt = s.deviceIndexedAt(ctx, destPath)
+	}
+	uploadResp, err := s.uploadFile(ctx, destPath, filename, fileContent)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to upload file: %v", err)), nil
 	}
+	auditRef(ctx, "path", uploadResp.Path)
 
 	sb.WriteString("## Upload\n\n")
 	sb.WriteString(fmt.Sprintf("- **Destination:** %s\n", uploadResp.Path))
@@ -1129,7 +1582,8 @@ func (s *Server) handlePublish(ctx context.Context, request mcp.CallToolRequest)
 	}
 
 	// Trigger reindex
-	reindexResp, err := s.client.TriggerReindex()
+	before := s.reindexBaseline(ctx)
+	reindexResp, err := s.api(ctx).TriggerReindex()
 	if err != nil {
 		sb.WriteString("\n## Reindex\n\n")
 		sb.WriteString(fmt.Sprintf("**⚠️ Warning:** Reindex failed: %v\n", err))
@@ -1140,34 +1594,30 @@ func (s *Server) handlePublish(ctx context.Context, request mcp.CallToolRequest)
 	sb.WriteString("\n## Reindex\n\n")
 	sb.WriteString(fmt.Sprintf("- **Status:** %s\n", reindexResp.Status))
 
-	// Wait for reindex if requested
-	if waitForReindex {
-		sb.WriteString("- **Waiting:** Polling for completion...\n")
-
-		// Poll for up to 60 seconds
-		for i := 0; i < 30; i++ {
-			time.Sleep(2 * time.Second)
-			status, err := s.client.GetReindexStatus()
-			if err != nil {
-				sb.WriteString(fmt.Sprintf("- **Warning:** Error checking status: %v\n", err))
-				break
-			}
-			if status.Status == "idle" {
-				sb.WriteString(fmt.Sprintf("- **Completed:** Reindex finished in %s\n", status.LastRun.Duration))
-				sb.WriteString(fmt.Sprintf("- **Devices:** %d indexed\n", status.LastRun.DevicesIndexed))
-				sb.WriteString(fmt.Sprintf("- **Documents:** %d indexed\n", status.LastRun.DocumentsIndexed))
-				if status.LastRun.GuidesIndexed > 0 {
-					sb.WriteString(fmt.Sprintf("- **Guides:** %d indexed\n", status.LastRun.GuidesIndexed))
-				}
-				break
-			}
+	// Wait for reindex if requested (verification always waits)
+	var wait *client.ReindexWait
+	if waitForReindex || verify {
+		wait, err = s.waitForReindex(ctx, request, before, reindexTimeout)
+		if err != nil {
+			sb.WriteString(fmt.Sprintf("- **Warning:** %v\n", err))
+		} else {
+			writeReindexWait(&sb, wait)
 		}
 	} else {
 		sb.WriteString("- **Note:** Reindex running in background. Use `get_reindex_status()` to check progress.\n")
 	}
 
+	if verify {
+		if problems := s.writePublishVerification(ctx, &sb, destPath, previousIndexedAt, wait); problems == 0 {
+			sb.WriteString("\n**✓ Verified:** The published document is indexed and parsed.\n")
+		} else {
+			sb.WriteString(fmt.Sprintf("\n**⚠️ Verification found %d problem(s).** Review the items above.\n", problems))
+		}
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+
 	sb.WriteString("\n## Next Steps\n\n")
-	sb.WriteString(fmt.Sprintf("1. Verify: `search_manuals(query: \"%s\")`\n", filename))
+	sb.WriteString(fmt.Sprintf("1. Verify: `search_manuals(query: \"%s\")` or publish with `verify: true`\n", filename))
 	sb.WriteString("2. Check content: `get_device(device_id: \"...\")` using ID from search\n")
 
 	return mcp.NewToolResultText(sb.String()), nil
@@ -1184,6 +1634,8 @@ func (s *Server) handlePublishBatch(ctx context.Context, request mcp.CallToolReq
 	args := request.GetArguments()
 	filesJSON, _ := args["files"].(string)
 	waitForReindex, _ := args["wait_for_reindex"].(bool)
+	atomic, _ := args["atomic"].(bool)
+	reindexTimeout := reindexTimeoutArg(args, "reindex_timeout")
 
 	if filesJSON == "" {
 		return mcp.NewToolResultError("files parameter is required (JSON array)"), nil
@@ -1199,6 +1651,10 @@ func (s *Server) handlePublishBatch(ctx context.Context, request mcp.CallToolReq
 		return mcp.NewToolResultError("files array is empty"), nil
 	}
 
+	if atomic {
+		return s.publishBatchAtomic(ctx, request, files, waitForReindex, reindexTimeout), nil
+	}
+
 	var sb strings.Builder
 	sb.WriteString("# Batch Publish Results\n\n")
 	sb.WriteString(fmt.Sprintf("**Files to upload:** %d\n\n", len(files)))
@@ -1206,6 +1662,7 @@ func (s *Server) handlePublishBatch(ctx context.Context, request mcp.CallToolReq
 	// Upload each file
 	sb.WriteString("## Uploads\n\n")
 	successCount := 0
+	var uploaded []string
 	for i, f := range files {
 		if f.DestPath == "" {
 			sb.WriteString(fmt.Sprintf("%d. **Error:** Missing dest_path\n", i+1))
@@ -1216,9 +1673,9 @@ func (s *Server) handlePublishBatch(ctx context.Context, request mcp.CallToolReq
 		var filename string
 
 		if f.LocalPath != "" {
-			data, err := os.ReadFile(f.LocalPath)
+			data, err := s.readLocalFile(f.LocalPath)
 			if err != nil {
-				sb.WriteString(fmt.Sprintf("%d. **Error:** %s - failed to read: %v\n", i+1, f.LocalPath, err))
+				sb.WriteString(fmt.Sprintf("%d. **Error:** %v\n", i+1, err))
 				continue
 			}
 			fileContent = data
@@ -1231,15 +1688,17 @@ func (s *Server) handlePublishBatch(ctx context.Context, request mcp.CallToolReq
 			continue
 		}
 
-		resp, err := s.client.UploadFile(f.DestPath, filename, fileContent)
+		resp, err := s.uploadFile(ctx, f.DestPath, filename, fileContent)
 		if err != nil {
 			sb.WriteString(fmt.Sprintf("%d. **Error:** %s - %v\n", i+1, f.DestPath, err))
 			continue
 		}
 
 		sb.WriteString(fmt.Sprintf("%d. **✓** %s (%d bytes)\n", i+1, resp.Path, resp.Size))
+		uploaded = append(uploaded, resp.Path)
 		successCount++
 	}
+	auditRef(ctx, "paths", strings.Join(uploaded, ","))
 
 	sb.WriteString(fmt.Sprintf("\n**Uploaded:** %d/%d files\n", successCount, len(files)))
 
@@ -1249,37 +1708,7 @@ func (s *Server) handlePublishBatch(ctx context.Context, request mcp.CallToolReq
 	}
 
 	// Trigger single reindex for all uploads
-	sb.WriteString("\n## Reindex\n\n")
-	reindexResp, err := s.client.TriggerReindex()
-	if err != nil {
-		sb.WriteString(fmt.Sprintf("**⚠️ Warning:** Reindex failed: %v\n", err))
-		return mcp.NewToolResultText(sb.String()), nil
-	}
-
-	sb.WriteString(fmt.Sprintf("- **Status:** %s\n", reindexResp.Status))
-
-	// Wait for reindex if requested
-	if waitForReindex {
-		sb.WriteString("- **Waiting:** Polling for completion...\n")
-
-		for i := 0; i < 30; i++ {
-			time.Sleep(2 * time.Second)
-			status, err := s.client.GetReindexStatus()
-			if err != nil {
-				sb.WriteString(fmt.Sprintf("- **Warning:** Error checking status: %v\n", err))
-				break
-			}
-			if status.Status == "idle" {
-				sb.WriteString(fmt.Sprintf("- **Completed:** Reindex finished in %s\n", status.LastRun.Duration))
-				sb.WriteString(fmt.Sprintf("- **Devices:** %d indexed\n", status.LastRun.DevicesIndexed))
-				sb.WriteString(fmt.Sprintf("- **Documents:** %d indexed\n", status.LastRun.DocumentsIndexed))
-				if status.LastRun.GuidesIndexed > 0 {
-					sb.WriteString(fmt.Sprintf("- **Guides:** %d indexed\n", status.LastRun.GuidesIndexed))
-				}
-				break
-			}
-		}
-	}
+	s.writeBatchReindex(ctx, request, &sb, waitForReindex, reindexTimeout)
 
 	return mcp.NewToolResultText(sb.String()), nil
 }
@@ -1293,11 +1722,17 @@ func (s *Server) handleDeleteFile(ctx context.Context, request mcp.CallToolReque
 		return mcp.NewToolResultError("path parameter is required"), nil
 	}
 
+	summary := fmt.Sprintf("## Delete File\n\n- **Path:** %s\n- **Reindex:** %t\n", path, reindex)
+	if result := s.requireConfirmation(ctx, request, summary); result != nil {
+		return result, nil
+	}
+
 	// Call API to delete file
-	resp, err := s.client.DeleteFile(path, reindex)
+	resp, err := s.api(ctx).DeleteFile(path, reindex)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to delete file: %v", err)), nil
 	}
+	auditRef(ctx, "path", resp.Path)
 
 	// Build formatted response
 	var sb strings.Builder
@@ -1329,10 +1764,34 @@ func (s *Server) handleDeleteFile(ctx context.Context, request mcp.CallToolReque
 }
 
 func (s *Server) handleSyncToGit(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	resp, err := s.client.TriggerSync()
+	args := request.GetArguments()
+	message, _ := args["message"].(string)
+	paths, _ := args["paths"].(string)
+
+	req := client.SyncRequest{Message: message}
+	for _, p := range strings.Split(paths, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			req.Paths = append(req.Paths, p)
+		}
+	}
+	if push, ok := args["push"].(bool); ok {
+		req.Push = &push
+	}
+
+	// Attribute the commit to the authenticated user
+	if user, err := s.api(ctx).GetMe(); err != nil {
+		s.logger.Warn("failed to get user for commit author", "error", err)
+	} else if user != nil {
+		req.AuthorName = user.Name
+		req.AuthorEmail = authorEmail(user, s.gitEmailDomain)
+	}
+
+	resp, err := s.api(ctx).TriggerSync(req)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to trigger sync: %v", err)), nil
 	}
+	auditRef(ctx, "commit", resp.Commit)
+	auditRef(ctx, "branch", resp.Branch)
 
 	var sb strings.Builder
 	sb.WriteString("# Git Sync Results\n\n")
@@ -1340,10 +1799,21 @@ func (s *Server) handleSyncToGit(ctx context.Context, request mcp.CallToolReques
 	switch resp.Status {
 	case "success":
 		sb.WriteString("**Status:** ✓ Success\n\n")
-		sb.WriteString(fmt.Sprintf("- **Commit:** %s\n", resp.Commit))
+		if link := s.commitURL(resp); link != "" {
+			sb.WriteString(fmt.Sprintf("- **Commit:** [%s](%s)\n", resp.Commit, link))
+		} else {
+			sb.WriteString(fmt.Sprintf("- **Commit:** %s\n", resp.Commit))
+		}
 		sb.WriteString(fmt.Sprintf("- **Files Changed:** %d\n", resp.FilesChanged))
 		sb.WriteString(fmt.Sprintf("- **Branch:** %s\n", resp.Branch))
-		sb.WriteString("\nDocumentation changes have been committed and pushed to the remote repository.\n")
+		if req.AuthorName != "" {
+			sb.WriteString(fmt.Sprintf("- **Author:** %s <%s>\n", req.AuthorName, req.AuthorEmail))
+		}
+		if req.Push != nil && !*req.Push {
+			sb.WriteString("\nDocumentation changes have been committed locally. Run `sync_to_git()` again with `push: true` to publish them.\n")
+		} else {
+			sb.WriteString("\nDocumentation changes have been committed and pushed to the remote repository.\n")
+		}
 
 	case "no_changes":
 		sb.WriteString("**Status:** No Changes\n\n")
@@ -1368,22 +1838,67 @@ func (s *Server) handleSyncToGit(ctx context.Context, request mcp.CallToolReques
 	return mcp.NewToolResultText(sb.String()), nil
 }
 
+// commitURL renders the configured commit URL template for a sync response.
+// Returns an empty string if no template is configured or no commit was made.
+func (s *Server) commitURL(resp *client.SyncResponse) string {
+	if s.commitURLTemplate == "" || resp.Commit == "" {
+		return ""
+	}
+	return strings.NewReplacer("{commit}", resp.Commit, "{branch}", resp.Branch).Replace(s.commitURLTemplate)
+}
+
+// authorEmail builds a commit author email for user under domain. The
+// local part is the user name reduced to [a-z0-9._-], or the user ID if
+// nothing of the name is left.
+func authorEmail(user *client.User, domain string) string {
+	local := emailLocalPart(user.Name)
+	if local == "" {
+		local = emailLocalPart(user.ID)
+	}
+	if local == "" {
+		local = "user"
+	}
+	return local + "@" + domain
+}
+
+// emailLocalPart lowercases name and replaces each run of characters
+// outside [a-z0-9._-] with a hyphen.
+func emailLocalPart(name string) string {
+	var sb strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			sb.WriteRune(r)
+			hyphen = false
+		case !hyphen:
+			sb.WriteByte('-')
+			hyphen = true
+		}
+	}
+	return strings.Trim(sb.String(), ".-")
+}
+
 // Admin tool handlers
 
 func (s *Server) handleListUsers(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	resp, err := s.client.ListUsers()
+	resp, err := s.api(ctx).ListUsers()
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to list users: %v", err)), nil
 	}
 
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("# Users (%d)\n\n", resp.Count))
-	sb.WriteString("| ID | Name | Role | Capabilities | Active | Created |\n")
-	sb.WriteString("|-----|------|------|--------------|--------|--------|\n")
+	sb.WriteString("| ID | Name | Role | Capabilities | Active | Created | Last Seen |\n")
+	sb.WriteString("|-----|------|------|--------------|--------|--------|-----------|\n")
 
 	for _, u := range resp.Users {
-		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %t | %s |\n",
-			u.ID, u.Name, u.Role(), u.CapabilitiesString(), u.IsActive, u.CreatedAt))
+		lastSeen := u.LastSeenAt
+		if lastSeen == "" {
+			lastSeen = "never"
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %t | %s | %s |\n",
+			u.ID, u.Name, u.Role(), u.CapabilitiesString(), u.IsActive, u.CreatedAt, lastSeen))
 	}
 
 	return mcp.NewToolResultText(sb.String()), nil
@@ -1393,11 +1908,16 @@ func (s *Server) handleCreateUser(ctx context.Context, request mcp.CallToolReque
 	args := request.GetArguments()
 	name, _ := args["name"].(string)
 	role, _ := args["role"].(string)
+	expiresAt, err := expiryArg(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
-	resp, err := s.client.CreateUser(name, role)
+	resp, err := s.api(ctx).CreateUser(name, role, expiresAt)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to create user: %v", err)), nil
 	}
+	auditRef(ctx, "user_id", resp.User.ID)
 
 	var sb strings.Builder
 	sb.WriteString("# User Created\n\n")
@@ -1405,9 +1925,11 @@ func (s *Server) handleCreateUser(ctx context.Context, request mcp.CallToolReque
 	sb.WriteString(fmt.Sprintf("- **Name:** %s\n", resp.User.Name))
 	sb.WriteString(fmt.Sprintf("- **Role:** %s\n", resp.User.Role()))
 	sb.WriteString(fmt.Sprintf("- **Capabilities:** %s\n", resp.User.CapabilitiesString()))
+	sb.WriteString(fmt.Sprintf("- **Key Expires:** %s\n", formatKeyExpiry(resp.User.KeyExpiresAt)))
 	sb.WriteString("\n## API Key\n\n")
-	sb.WriteString(fmt.Sprintf("```\n%s\n```\n\n", resp.APIKey))
-	sb.WriteString("**⚠️ Save this API key now - it will not be shown again!**\n")
+	if err := s.writeNewAPIKey(&sb, resp.User.Name+"-"+resp.User.ID, resp.APIKey); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("user %s was created but its API key could not be stored: %v. The key was not displayed; fix the key sink and use rotate_api_key to issue a new one.", resp.User.ID, err)), nil
+	}
 
 	return mcp.NewToolResultText(sb.String()), nil
 }
@@ -1416,7 +1938,12 @@ func (s *Server) handleDeleteUser(ctx context.Context, request mcp.CallToolReque
 	args := request.GetArguments()
 	userID, _ := args["user_id"].(string)
 
-	err := s.client.DeleteUser(userID)
+	summary := "## Delete User\n\n" + s.describeUser(ctx, userID) + "\nThe account and its API key will be removed permanently."
+	if result := s.requireConfirmation(ctx, request, summary); result != nil {
+		return result, nil
+	}
+
+	err := s.api(ctx).DeleteUser(userID)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to delete user: %v", err)), nil
 	}
@@ -1429,7 +1956,16 @@ func (s *Server) handleUpdateUserRole(ctx context.Context, request mcp.CallToolR
 	userID, _ := args["user_id"].(string)
 	role, _ := args["role"].(string)
 
-	err := s.client.UpdateUserRole(userID, role)
+	// Demoting the calling account would lock this server out
+	meID, result := s.actingUserID(ctx, "its own admin access cannot be protected")
+	if result != nil {
+		return result, nil
+	}
+	if meID == userID && role != "admin" {
+		return mcp.NewToolResultError("refusing to remove the admin role from the account this server is authenticated as"), nil
+	}
+
+	err := s.api(ctx).UpdateUserRole(userID, role)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to update user role: %v", err)), nil
 	}
@@ -1440,26 +1976,37 @@ func (s *Server) handleUpdateUserRole(ctx context.Context, request mcp.CallToolR
 func (s *Server) handleRotateAPIKey(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	args := request.GetArguments()
 	userID, _ := args["user_id"].(string)
+	expiresAt, err := expiryArg(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
-	resp, err := s.client.RotateAPIKey(userID)
+	summary := "## Rotate API Key\n\n" + s.describeUser(ctx, userID) + "\nThe current API key will stop working immediately."
+	if result := s.requireConfirmation(ctx, request, summary); result != nil {
+		return result, nil
+	}
+
+	resp, err := s.api(ctx).RotateAPIKey(userID, expiresAt)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to rotate API key: %v", err)), nil
 	}
 
 	var sb strings.Builder
 	sb.WriteString("# API Key Rotated\n\n")
-	sb.WriteString(fmt.Sprintf("User `%s` has a new API key.\n\n", userID))
-	sb.WriteString("**⚠️ IMPORTANT:** Save this key now - it will not be shown again!\n\n")
-	sb.WriteString(fmt.Sprintf("```\n%s\n```\n", resp.APIKey))
+	sb.WriteString(fmt.Sprintf("User `%s` has a new API key (expires: %s).\n\n", userID, formatKeyExpiry(resp.ExpiresAt)))
+	if err := s.writeNewAPIKey(&sb, userID, resp.APIKey); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("API key for user %s was rotated but the new key could not be stored: %v. The key was not displayed; fix the key sink and rotate again.", userID, err)), nil
+	}
 
 	return mcp.NewToolResultText(sb.String()), nil
 }
 
 func (s *Server) handleListSettings(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	resp, err := s.client.ListSettings()
+	resp, err := s.api(ctx).ListSettings()
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to list settings: %v", err)), nil
 	}
+	schema, schemaErr := s.settingsSchema(ctx)
 
 	var sb strings.Builder
 	sb.WriteString("# Configuration Settings\n\n")
@@ -1467,12 +2014,37 @@ func (s *Server) handleListSettings(ctx context.Context, request mcp.CallToolReq
 	if len(resp.Settings) == 0 {
 		sb.WriteString("No settings configured.\n")
 	} else {
-		sb.WriteString("| Key | Value | Updated At |\n")
-		sb.WriteString("|-----|-------|------------|\n")
-		for _, s := range resp.Settings {
-			sb.WriteString(fmt.Sprintf("| %s | %s | %s |\n", s.Key, s.Value, s.UpdatedAt))
+		sb.WriteString("| Key | Value | Type | Description | Updated At |\n")
+		sb.WriteString("|-----|-------|------|-------------|------------|\n")
+		for _, setting := range resp.Settings {
+			def := schema[setting.Key]
+			sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s |\n", setting.Key, setting.Value, describeType(def), def.Description, setting.UpdatedAt))
+		}
+	}
+
+	// Settings the schema knows about that have never been set
+	set := make(map[string]bool, len(resp.Settings))
+	for _, setting := range resp.Settings {
+		set[setting.Key] = true
+	}
+	var unset []string
+	for _, key := range slices.Sorted(maps.Keys(schema)) {
+		if !set[key] {
+			def := schema[key]
+			unset = append(unset, fmt.Sprintf("| %s | %s | %s | %s |", key, def.Default, describeType(def), def.Description))
 		}
 	}
+	if len(unset) > 0 {
+		sb.WriteString("\n## Available Settings (using defaults)\n\n")
+		sb.WriteString("| Key | Default | Type | Description |\n")
+		sb.WriteString("|-----|---------|------|-------------|\n")
+		sb.WriteString(strings.Join(unset, "\n"))
+		sb.WriteString("\n")
+	}
+
+	if schemaErr != nil {
+		sb.WriteString(fmt.Sprintf("\n*Setting types unavailable: %v*\n", schemaErr))
+	}
 
 	return mcp.NewToolResultText(sb.String()), nil
 }
@@ -1481,13 +2053,70 @@ func (s *Server) handleUpdateSetting(ctx context.Context, request mcp.CallToolRe
 	args := request.GetArguments()
 	key, _ := args["key"].(string)
 	value, _ := args["value"].(string)
+	force, _ := args["force"].(bool)
+
+	if key == "" {
+		return mcp.NewToolResultError("key is required"), nil
+	}
+
+	resp, err := s.api(ctx).ListSettings()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to list settings: %v", err)), nil
+	}
+
+	// Validate against the schema so a typo is rejected rather than stored
+	var warning string
+	schema, err := s.settingsSchema(ctx)
+	switch {
+	case err == nil:
+		if value, err = schema.Validate(key, value); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid setting: %v", err)), nil
+		}
+	case !force:
+		return mcp.NewToolResultError(fmt.Sprintf("setting types are unavailable (%v), so the value cannot be validated; retry later or pass force: true to write it unchecked", err)), nil
+	default:
+		warning = fmt.Sprintf("**⚠️ Not validated:** setting types are unavailable (%v).\n", err)
+	}
+
+	changes := settings.Diff(resp.Settings, map[string]string{key: value}, schema)
+	if len(changes) == 0 {
+		return mcp.NewToolResultText(fmt.Sprintf("# Setting Unchanged\n\n`%s` is already `%s`.", key, value)), nil
+	}
+	if schema == nil && changes[0].IsNew {
+		warning += fmt.Sprintf("**⚠️ New key:** `%s` is not an existing setting. Check the spelling.\n", key)
+	}
 
-	err := s.client.UpdateSetting(key, value)
+	summary := "## Update Setting\n\n" + formatSettingChanges(changes) + warning
+	if result := s.requireConfirmation(ctx, request, summary); result != nil {
+		return result, nil
+	}
+
+	err = s.api(ctx).UpdateSetting(key, value)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to update setting: %v", err)), nil
 	}
 
-	return mcp.NewToolResultText(fmt.Sprintf("# Setting Updated\n\n`%s` = `%s`", key, value)), nil
+	return mcp.NewToolResultText("# Setting Updated\n\n" + formatSettingChanges(changes)), nil
+}
+
+// writeNewAPIKey hands a newly generated API key to the user. With a secret
+// sink configured, only a reference and fingerprint enter the transcript;
+// otherwise the key is shown inline once.
+func (s *Server) writeNewAPIKey(sb *strings.Builder, account, apiKey string) error {
+	if s.secrets == nil {
+		sb.WriteString("**⚠️ IMPORTANT:** Save this key now - it will not be shown again!\n\n")
+		sb.WriteString(fmt.Sprintf("```\n%s\n```\n", apiKey))
+		return nil
+	}
+
+	ref, err := s.secrets.Store(account, apiKey)
+	if err != nil {
+		return err
+	}
+	sb.WriteString("The key was stored outside this conversation and is not shown here.\n\n")
+	sb.WriteString(fmt.Sprintf("- **Stored At:** %s\n", ref))
+	sb.WriteString(fmt.Sprintf("- **Fingerprint:** %s\n", secrets.Fingerprint(apiKey)))
+	return nil
 }
 
 // Resource handlers
@@ -1499,9 +2128,9 @@ func (s *Server) handleDeviceResource(ctx context.Context, request mcp.ReadResou
 	if len(parts) < 4 {
 		return nil, fmt.Errorf("invalid resource URI: %s", uri)
 	}
-	deviceID := parts[3]
+	api, deviceID := s.resolveID(parts[3])
 
-	device, err := s.client.GetDevice(deviceID, true)
+	device, err := api.GetDevice(deviceID, true)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get device: %w", err)
 	}
@@ -1524,9 +2153,9 @@ func (s *Server) handlePinoutResource(ctx context.Context, request mcp.ReadResou
 	if len(parts) < 4 {
 		return nil, fmt.Errorf("invalid resource URI: %s", uri)
 	}
-	deviceID := parts[3]
+	api, deviceID := s.resolveID(parts[3])
 
-	pinout, err := s.client.GetDevicePinout(deviceID)
+	pinout, err := api.GetDevicePinout(deviceID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pinout: %w", err)
 	}
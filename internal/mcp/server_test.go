@@ -0,0 +1,76 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+)
+
+func TestAuthorEmail(t *testing.T) {
+	tests := []struct {
+		user client.User
+		want string
+	}{
+		{client.User{ID: "usr_1", Name: "ci-bot"}, "ci-bot@example.com"},
+		{client.User{ID: "usr_1", Name: "Jane Doe"}, "jane-doe@example.com"},
+		{client.User{ID: "usr_1", Name: "ops <root>@evil"}, "ops-root-evil@example.com"},
+		{client.User{ID: "usr_1", Name: "人"}, "usr_1@example.com"},
+	}
+	for _, tt := range tests {
+		if got := authorEmail(&tt.user, "example.com"); got != tt.want {
+			t.Errorf("authorEmail(%q) = %q, want %q", tt.user.Name, got, tt.want)
+		}
+	}
+}
+
+func TestSyncToGit_Request(t *testing.T) {
+	var got client.SyncRequest
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/me"):
+			json.NewEncoder(w).Encode(client.MeResponse{User: client.User{ID: "usr_1", Name: "Jane Doe"}})
+		case strings.HasSuffix(r.URL.Path, "/rw/sync"):
+			if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+				t.Errorf("failed to decode sync request: %v", err)
+			}
+			json.NewEncoder(w).Encode(client.SyncResponse{Status: "success", Commit: "abc123", Branch: "main", FilesChanged: 2})
+		default:
+			t.Errorf("unexpected API request: %s", r.URL.Path)
+		}
+	})
+	WithGitEmailDomain("docs.example.com")(s)
+	WithCommitURLTemplate("https://git.example.com/docs/commit/{commit}")(s)
+
+	result, _ := s.handleSyncToGit(context.Background(), toolRequest("sync_to_git", map[string]any{
+		"message": "Add BME280 datasheet",
+		"paths":   "sensors/bme280, sensors/bme680/README.md",
+		"push":    false,
+	}))
+	text := resultText(result)
+	if result.IsError {
+		t.Fatalf("sync_to_git failed: %s", text)
+	}
+
+	if got.Message != "Add BME280 datasheet" {
+		t.Errorf("Message = %q", got.Message)
+	}
+	if got.AuthorName != "Jane Doe" || got.AuthorEmail != "jane-doe@docs.example.com" {
+		t.Errorf("author = %q <%s>, want Jane Doe <jane-doe@docs.example.com>", got.AuthorName, got.AuthorEmail)
+	}
+	if strings.Join(got.Paths, ",") != "sensors/bme280,sensors/bme680/README.md" {
+		t.Errorf("Paths = %v", got.Paths)
+	}
+	if got.Push == nil || *got.Push {
+		t.Errorf("Push = %v, want false", got.Push)
+	}
+	if !strings.Contains(text, "[abc123](https://git.example.com/docs/commit/abc123)") {
+		t.Errorf("result missing commit link:\n%s", text)
+	}
+	if !strings.Contains(text, "committed locally") {
+		t.Errorf("result should say the commit was not pushed:\n%s", text)
+	}
+}
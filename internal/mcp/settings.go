@@ -0,0 +1,150 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+	"github.com/rmrfslashbin/manuals-mcp/internal/settings"
+)
+
+// settingsSchema fetches the setting definitions from the API.
+func (s *Server) settingsSchema(ctx context.Context) (settings.Schema, error) {
+	resp, err := s.api(ctx).SettingsSchema()
+	if err != nil {
+		return nil, err
+	}
+	return settings.NewSchema(resp.Settings), nil
+}
+
+// describeType formats a setting's type and constraints for display.
+func describeType(def client.SettingSchema) string {
+	t := def.Type
+	switch {
+	case len(def.Allowed) > 0:
+		t = strings.Join(def.Allowed, " \\| ")
+	case def.Min != nil && def.Max != nil:
+		t = fmt.Sprintf("%s (%g-%g)", t, *def.Min, *def.Max)
+	case def.Min != nil:
+		t = fmt.Sprintf("%s (≥%g)", t, *def.Min)
+	case def.Max != nil:
+		t = fmt.Sprintf("%s (≤%g)", t, *def.Max)
+	}
+	return t
+}
+
+// formatSettingChanges renders setting changes as a diff block.
+func formatSettingChanges(changes []settings.Change) string {
+	return "```diff\n" + settings.FormatChanges(changes) + "```\n"
+}
+
+func (s *Server) handleExportSettings(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	resp, err := s.api(ctx).ListSettings()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to list settings: %v", err)), nil
+	}
+
+	data, err := settings.Export(resp.Settings, s.api(ctx).GetAPIURL(), time.Now())
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Settings Export (%d settings)\n\n", len(resp.Settings)))
+	sb.WriteString("```yaml\n")
+	sb.Write(data)
+	sb.WriteString("```\n\nRestore with `import_settings(yaml: ...)`.\n")
+
+	return mcp.NewToolResultText(sb.String()), nil
+}
+
+func (s *Server) handleImportSettings(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	content, _ := args["yaml"].(string)
+	localPath, _ := args["local_path"].(string)
+	dryRun, _ := args["dry_run"].(bool)
+	force, _ := args["force"].(bool)
+
+	data := []byte(content)
+	if localPath != "" {
+		var err error
+		if data, err = s.readLocalFile(localPath); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	} else if content == "" {
+		return mcp.NewToolResultError("either yaml or local_path must be provided"), nil
+	}
+
+	desired, err := settings.Import(data)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Validate everything before changing anything
+	schema, schemaErr := s.settingsSchema(ctx)
+	if schemaErr != nil && !force && !dryRun {
+		return mcp.NewToolResultError(fmt.Sprintf("setting types are unavailable (%v), so the values cannot be validated; retry later, preview with dry_run: true, or pass force: true to apply them unchecked", schemaErr)), nil
+	}
+	if schemaErr == nil {
+		var invalid []string
+		for key, value := range desired {
+			normalized, err := schema.Validate(key, value)
+			if err != nil {
+				invalid = append(invalid, "- "+err.Error())
+				continue
+			}
+			desired[key] = normalized
+		}
+		if len(invalid) > 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("# Import Aborted\n\n%d invalid setting(s); nothing was changed:\n\n%s\n", len(invalid), strings.Join(invalid, "\n"))), nil
+		}
+	}
+
+	resp, err := s.api(ctx).ListSettings()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to list settings: %v", err)), nil
+	}
+	changes := settings.Diff(resp.Settings, desired, schema)
+
+	var summary strings.Builder
+	summary.WriteString(fmt.Sprintf("## Import Settings\n\n%d of %d setting(s) differ from the server.\n\n", len(changes), len(desired)))
+	if len(changes) > 0 {
+		summary.WriteString(formatSettingChanges(changes))
+	}
+	if schemaErr != nil {
+		summary.WriteString(fmt.Sprintf("\n**⚠️ Not validated:** setting types are unavailable (%v).\n", schemaErr))
+	}
+
+	if dryRun || len(changes) == 0 {
+		header := "# Import Preview (dry run)\n\n"
+		if len(changes) == 0 {
+			header = "# Settings Already Match\n\n"
+		}
+		return mcp.NewToolResultText(header + summary.String()), nil
+	}
+
+	if result := s.requireConfirmation(ctx, request, summary.String()); result != nil {
+		return result, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Settings Imported\n\n")
+	failed := 0
+	for _, c := range changes {
+		if err := s.api(ctx).UpdateSetting(c.Key, c.New); err != nil {
+			sb.WriteString(fmt.Sprintf("- **Error:** %s - %v\n", c.Key, err))
+			failed++
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("- **✓** %s = %s\n", c.Key, c.New))
+	}
+	sb.WriteString(fmt.Sprintf("\n**Summary:** %d updated, %d failed\n", len(changes)-failed, failed))
+
+	if failed > 0 {
+		return mcp.NewToolResultError(sb.String()), nil
+	}
+	return mcp.NewToolResultText(sb.String()), nil
+}
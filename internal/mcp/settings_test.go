@@ -0,0 +1,106 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// settingsAPI serves a settings list and schema and records updates.
+func settingsAPI(t *testing.T, updates map[string]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/admin/settings/schema"):
+			w.Write([]byte(`{"settings":[{"key":"reindex.workers","type":"int","min":1,"max":16},{"key":"reindex.interval","type":"duration"},{"key":"search.fuzzy","type":"bool"}]}`))
+		case r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/admin/settings"):
+			w.Write([]byte(`{"settings":[{"key":"reindex.workers","value":"4"},{"key":"reindex.interval","value":"5m0s"},{"key":"search.fuzzy","value":"true"}]}`))
+		case r.Method == "PUT" && strings.Contains(r.URL.Path, "/admin/settings/"):
+			key := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+			updates[key] = "updated"
+		default:
+			t.Errorf("unexpected API request: %s %s", r.Method, r.URL.Path)
+		}
+	}
+}
+
+func TestUpdateSetting_RejectsInvalidValue(t *testing.T) {
+	updates := make(map[string]string)
+	s := newTestServer(t, settingsAPI(t, updates))
+	WithConfirmations(false)(s)
+
+	result, _ := s.handleUpdateSetting(context.Background(), toolRequest("update_setting", map[string]any{
+		"key":   "reindex.workers",
+		"value": "64",
+	}))
+	if !result.IsError || !strings.Contains(resultText(result), "at most 16") {
+		t.Errorf("update_setting = %s, want range error", resultText(result))
+	}
+	if len(updates) > 0 {
+		t.Errorf("invalid value was sent to the API: %v", updates)
+	}
+}
+
+func TestUpdateSetting_ShowsDiff(t *testing.T) {
+	updates := make(map[string]string)
+	s := newTestServer(t, settingsAPI(t, updates))
+	WithConfirmations(false)(s)
+
+	result, _ := s.handleUpdateSetting(context.Background(), toolRequest("update_setting", map[string]any{
+		"key":   "reindex.workers",
+		"value": "8",
+	}))
+	text := resultText(result)
+	if result.IsError {
+		t.Fatalf("update_setting failed: %s", text)
+	}
+	if !strings.Contains(text, "- reindex.workers: 4") || !strings.Contains(text, "+ reindex.workers: 8") {
+		t.Errorf("update_setting result missing diff:\n%s", text)
+	}
+}
+
+func TestImportSettings_OnlyAppliesChanges(t *testing.T) {
+	updates := make(map[string]string)
+	s := newTestServer(t, settingsAPI(t, updates))
+	WithConfirmations(false)(s)
+
+	yaml := "settings:\n  reindex.workers: 4\n  reindex.interval: 5m\n  search.fuzzy: off\n"
+
+	result, _ := s.handleImportSettings(context.Background(), toolRequest("import_settings", map[string]any{"yaml": yaml, "dry_run": true}))
+	if result.IsError || len(updates) > 0 {
+		t.Fatalf("dry run applied changes or failed: %v\n%s", updates, resultText(result))
+	}
+
+	result, _ = s.handleImportSettings(context.Background(), toolRequest("import_settings", map[string]any{"yaml": yaml}))
+	if result.IsError {
+		t.Fatalf("import_settings failed: %s", resultText(result))
+	}
+	if len(updates) != 1 || updates["search.fuzzy"] == "" {
+		t.Errorf("import_settings updated %v, want only search.fuzzy", updates)
+	}
+}
+
+func TestUpdateSetting_RequiresSchemaUnlessForced(t *testing.T) {
+	updates := make(map[string]string)
+	api := settingsAPI(t, updates)
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/admin/settings/schema") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		api(w, r)
+	})
+	WithConfirmations(false)(s)
+
+	args := map[string]any{"key": "reindex.workers", "value": "8"}
+	result, _ := s.handleUpdateSetting(context.Background(), toolRequest("update_setting", args))
+	if !result.IsError || len(updates) > 0 {
+		t.Fatalf("update_setting without a schema wrote %v: %s", updates, resultText(result))
+	}
+
+	args["force"] = true
+	result, _ = s.handleUpdateSetting(context.Background(), toolRequest("update_setting", args))
+	if result.IsError || updates["reindex.workers"] == "" {
+		t.Errorf("forced update_setting = %v: %s", updates, resultText(result))
+	}
+}
@@ -0,0 +1,295 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+)
+
+const (
+	// defaultInactiveDays flags users not seen for this many days.
+	defaultInactiveDays = 90
+
+	// defaultRotationDays flags keys older than this many days.
+	defaultRotationDays = 90
+
+	// maxExpiryDays bounds expires_in_days for new keys.
+	maxExpiryDays = 3650
+
+	// expiryWarning flags keys that expire within this window.
+	expiryWarning = 7 * 24 * time.Hour
+)
+
+// staleUser is a user flagged by findStaleUsers.
+type staleUser struct {
+	User client.User
+
+	// KeyStale is set when the key is expired, about to expire, or past
+	// the rotation window.
+	KeyStale bool
+
+	// Inactive is set when the user has not been seen recently.
+	Inactive bool
+
+	Reasons []string
+}
+
+// parseAPITime parses a timestamp returned by the API.
+func parseAPITime(v string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// days formats a duration as whole days.
+func days(d time.Duration) int {
+	return int(d.Hours() / 24)
+}
+
+// findStaleUsers returns users whose keys are expired, expiring soon, or
+// older than rotateAfter, and users not seen within inactiveAfter. Key age
+// falls back to the account creation time when the API does not report
+// when the key was issued.
+func findStaleUsers(users []client.User, now time.Time, inactiveAfter, rotateAfter time.Duration) []staleUser {
+	var stale []staleUser
+	for _, u := range users {
+		su := staleUser{User: u}
+
+		if exp, ok := parseAPITime(u.KeyExpiresAt); ok {
+			switch {
+			case !exp.After(now):
+				su.KeyStale = true
+				su.Reasons = append(su.Reasons, fmt.Sprintf("key expired %s", exp.Format("2006-01-02")))
+			case exp.Sub(now) <= expiryWarning:
+				su.KeyStale = true
+				su.Reasons = append(su.Reasons, fmt.Sprintf("key expires %s", exp.Format("2006-01-02")))
+			}
+		}
+
+		if issued, ok := parseAPITime(u.KeyCreatedAt); ok {
+			if age := now.Sub(issued); age > rotateAfter {
+				su.KeyStale = true
+				su.Reasons = append(su.Reasons, fmt.Sprintf("key is %d days old", days(age)))
+			}
+		} else if created, ok := parseAPITime(u.CreatedAt); ok {
+			if age := now.Sub(created); age > rotateAfter {
+				su.KeyStale = true
+				su.Reasons = append(su.Reasons, fmt.Sprintf("account is %d days old and key issue date is unknown", days(age)))
+			}
+		}
+
+		if seen, ok := parseAPITime(u.LastSeenAt); ok {
+			if idle := now.Sub(seen); idle > inactiveAfter {
+				su.Inactive = true
+				su.Reasons = append(su.Reasons, fmt.Sprintf("not seen for %d days", days(idle)))
+			}
+		} else if created, ok := parseAPITime(u.CreatedAt); ok && now.Sub(created) > inactiveAfter {
+			su.Inactive = true
+			su.Reasons = append(su.Reasons, "never used")
+		}
+
+		if len(su.Reasons) > 0 {
+			stale = append(stale, su)
+		}
+	}
+	return stale
+}
+
+// daysArg reads a positive whole-day argument, returning def when unset.
+func daysArg(args map[string]any, name string, def int) time.Duration {
+	if v, ok := args[name].(float64); ok && v >= 1 {
+		def = int(v)
+	}
+	return time.Duration(def) * 24 * time.Hour
+}
+
+// expiryArg converts an expires_in_days argument into an absolute expiry.
+// A missing or zero value means the key does not expire.
+func expiryArg(args map[string]any) (time.Time, error) {
+	v, ok := args["expires_in_days"].(float64)
+	if !ok || v == 0 {
+		return time.Time{}, nil
+	}
+	if v < 1 || v > maxExpiryDays {
+		return time.Time{}, fmt.Errorf("expires_in_days must be between 1 and %d", maxExpiryDays)
+	}
+	return time.Now().Add(time.Duration(v) * 24 * time.Hour), nil
+}
+
+// formatKeyExpiry describes a key expiry timestamp for display.
+func formatKeyExpiry(expiresAt string) string {
+	if expiresAt == "" {
+		return "never"
+	}
+	return expiresAt
+}
+
+func (s *Server) handleListStaleUsers(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	inactiveAfter := daysArg(args, "inactive_days", defaultInactiveDays)
+	rotateAfter := daysArg(args, "rotation_days", defaultRotationDays)
+
+	resp, err := s.api(ctx).ListUsers()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to list users: %v", err)), nil
+	}
+	stale := findStaleUsers(resp.Users, time.Now(), inactiveAfter, rotateAfter)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Stale Users (%d of %d)\n\n", len(stale), len(resp.Users)))
+	sb.WriteString(fmt.Sprintf("Flagging users not seen for %d days and keys older than %d days or expiring within %d days.\n\n",
+		days(inactiveAfter), days(rotateAfter), days(expiryWarning)))
+
+	if len(stale) == 0 {
+		sb.WriteString("No stale users or keys.\n")
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+
+	sb.WriteString("| ID | Name | Active | Last Seen | Key Expires | Rotate Key | Reasons |\n")
+	sb.WriteString("|-----|------|--------|-----------|-------------|------------|---------|\n")
+	rotatable := 0
+	for _, su := range stale {
+		lastSeen := su.User.LastSeenAt
+		if lastSeen == "" {
+			lastSeen = "never"
+		}
+		rotate := ""
+		if su.KeyStale {
+			rotate = "yes"
+			rotatable++
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s | %t | %s | %s | %s | %s |\n",
+			su.User.ID, su.User.Name, su.User.IsActive, lastSeen, formatKeyExpiry(su.User.KeyExpiresAt), rotate, strings.Join(su.Reasons, "; ")))
+	}
+
+	sb.WriteString("\n**Next steps:**\n")
+	if rotatable > 0 {
+		sb.WriteString(fmt.Sprintf("- Use `rotate_stale_keys` to rotate the %d flagged key(s)\n", rotatable))
+	}
+	sb.WriteString("- Use `set_user_active` with `active: false` to suspend users who no longer need access\n")
+
+	return mcp.NewToolResultText(sb.String()), nil
+}
+
+func (s *Server) handleRotateStaleKeys(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	rotateAfter := daysArg(args, "rotation_days", defaultRotationDays)
+	includeInactive, _ := args["include_inactive"].(bool)
+	expiresAt, err := expiryArg(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	resp, err := s.api(ctx).ListUsers()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to list users: %v", err)), nil
+	}
+
+	// Never rotate the key this server is using, or it would lock itself out
+	me, err := s.api(ctx).GetMe()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to identify the acting user, so its own key cannot be excluded: %v", err)), nil
+	}
+	if me == nil || me.ID == "" {
+		return mcp.NewToolResultError("failed to identify the acting user, so its own key cannot be excluded"), nil
+	}
+	selfID := me.ID
+
+	var targets, skipped []staleUser
+	for _, su := range findStaleUsers(resp.Users, time.Now(), daysArg(args, "inactive_days", defaultInactiveDays), rotateAfter) {
+		switch {
+		case !su.KeyStale:
+			continue
+		case su.User.ID == selfID:
+			su.Reasons = append(su.Reasons, "skipped: key used by this server")
+			skipped = append(skipped, su)
+		case !su.User.IsActive && !includeInactive:
+			su.Reasons = append(su.Reasons, "skipped: user is deactivated")
+			skipped = append(skipped, su)
+		default:
+			targets = append(targets, su)
+		}
+	}
+
+	slices.SortFunc(targets, func(a, b staleUser) int { return strings.Compare(a.User.ID, b.User.ID) })
+	targetIDs := make([]string, len(targets))
+	for i, su := range targets {
+		targetIDs[i] = su.User.ID
+	}
+
+	var sb strings.Builder
+	if len(targets) == 0 {
+		sb.WriteString("# Rotate Stale Keys\n\nNo keys need rotation.\n")
+		writeStaleList(&sb, "Skipped", skipped)
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+
+	var summary strings.Builder
+	summary.WriteString(fmt.Sprintf("## Rotate %d Stale API Key(s)\n\n", len(targets)))
+	writeStaleList(&summary, "", targets)
+	if expiresAt.IsZero() {
+		summary.WriteString("\nNew keys will not expire.")
+	} else {
+		summary.WriteString(fmt.Sprintf("\nNew keys expire %s.", expiresAt.UTC().Format("2006-01-02")))
+	}
+	summary.WriteString(" Each user's current key stops working immediately.")
+	summary.WriteString(fmt.Sprintf("\n\n**User IDs:** %s", strings.Join(targetIDs, ", ")))
+	if result := s.requireTargetConfirmation(ctx, request, summary.String(), targetIDs); result != nil {
+		return result, nil
+	}
+
+	sb.WriteString("# Stale Keys Rotated\n\n")
+	var rotated []string
+	failed := 0
+	for _, su := range targets {
+		u := su.User
+		sb.WriteString(fmt.Sprintf("## %s (%s)\n\n", u.Name, u.ID))
+		rot, err := s.api(ctx).RotateAPIKey(u.ID, expiresAt)
+		if err != nil {
+			sb.WriteString(fmt.Sprintf("**Error:** failed to rotate: %v\n\n", err))
+			failed++
+			continue
+		}
+		rotated = append(rotated, u.ID)
+		if rot.ExpiresAt != "" {
+			sb.WriteString(fmt.Sprintf("- **Expires:** %s\n", rot.ExpiresAt))
+		}
+		if err := s.writeNewAPIKey(&sb, u.Name+"-"+u.ID, rot.APIKey); err != nil {
+			sb.WriteString(fmt.Sprintf("**Error:** key was rotated but could not be stored: %v. The key was not displayed; rotate this user again with rotate_api_key.\n", err))
+			failed++
+		}
+		sb.WriteString("\n")
+	}
+	auditRef(ctx, "user_ids", strings.Join(rotated, ","))
+
+	writeStaleList(&sb, "Skipped", skipped)
+	sb.WriteString(fmt.Sprintf("**Summary:** %d rotated, %d failed, %d skipped\n", len(rotated), failed, len(skipped)))
+
+	if failed > 0 {
+		return mcp.NewToolResultError(sb.String()), nil
+	}
+	return mcp.NewToolResultText(sb.String()), nil
+}
+
+// writeStaleList writes a bullet list of users and the reasons they were
+// flagged, under an optional heading. Nothing is written for an empty list.
+func writeStaleList(sb *strings.Builder, heading string, users []staleUser) {
+	if len(users) == 0 {
+		return
+	}
+	if heading != "" {
+		sb.WriteString(fmt.Sprintf("\n## %s\n\n", heading))
+	}
+	for _, su := range users {
+		sb.WriteString(fmt.Sprintf("- **%s** (ID: %s): %s\n", su.User.Name, su.User.ID, strings.Join(su.Reasons, "; ")))
+	}
+	sb.WriteString("\n")
+}
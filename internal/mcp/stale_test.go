@@ -0,0 +1,138 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+)
+
+func TestFindStaleUsers(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	ago := func(d int) string { return now.Add(-time.Duration(d) * day).Format(time.RFC3339) }
+
+	users := []client.User{
+		{ID: "fresh", CreatedAt: ago(200), KeyCreatedAt: ago(10), LastSeenAt: ago(1)},
+		{ID: "old-key", CreatedAt: ago(200), KeyCreatedAt: ago(120), LastSeenAt: ago(1)},
+		{ID: "expiring", CreatedAt: ago(20), KeyExpiresAt: now.Add(3 * day).Format(time.RFC3339), LastSeenAt: ago(1)},
+		{ID: "idle", CreatedAt: ago(30), LastSeenAt: ago(100)},
+		{ID: "never", CreatedAt: ago(95)},
+	}
+
+	stale := findStaleUsers(users, now, 90*day, 90*day)
+	got := make(map[string]staleUser)
+	for _, su := range stale {
+		got[su.User.ID] = su
+	}
+
+	if _, ok := got["fresh"]; ok {
+		t.Error("fresh user flagged as stale")
+	}
+	if su := got["old-key"]; !su.KeyStale || su.Inactive {
+		t.Errorf("old-key = %+v, want KeyStale only", su)
+	}
+	if su := got["expiring"]; !su.KeyStale {
+		t.Errorf("expiring = %+v, want KeyStale", su)
+	}
+	if su := got["idle"]; su.KeyStale || !su.Inactive {
+		t.Errorf("idle = %+v, want Inactive only", su)
+	}
+	if su := got["never"]; !su.KeyStale || !su.Inactive || !strings.Contains(strings.Join(su.Reasons, ";"), "never used") {
+		t.Errorf("never = %+v, want KeyStale and Inactive with never used", su)
+	}
+}
+
+func TestRotateStaleKeys_SkipsSelfAndInactive(t *testing.T) {
+	old := time.Now().Add(-200 * 24 * time.Hour).Format(time.RFC3339)
+	var rotated []string
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/me"):
+			json.NewEncoder(w).Encode(client.MeResponse{User: client.User{ID: "usr_me"}})
+		case strings.HasSuffix(r.URL.Path, "/admin/users"):
+			json.NewEncoder(w).Encode(client.UsersResponse{Users: []client.User{
+				{ID: "usr_me", Name: "admin", IsActive: true, KeyCreatedAt: old},
+				{ID: "usr_bot", Name: "ci-bot", IsActive: true, KeyCreatedAt: old},
+				{ID: "usr_off", Name: "former", IsActive: false, KeyCreatedAt: old},
+			}})
+		case strings.HasSuffix(r.URL.Path, "/rotate-key"):
+			rotated = append(rotated, strings.Split(r.URL.Path, "/")[5])
+			json.NewEncoder(w).Encode(client.RotateKeyResponse{APIKey: "mk_new"})
+		default:
+			t.Errorf("unexpected API request: %s", r.URL.Path)
+		}
+	})
+	WithConfirmations(false)(s)
+
+	result, _ := s.handleRotateStaleKeys(context.Background(), toolRequest("rotate_stale_keys", map[string]any{}))
+	text := resultText(result)
+
+	if result.IsError {
+		t.Fatalf("rotate_stale_keys failed: %s", text)
+	}
+	if strings.Join(rotated, ",") != "usr_bot" {
+		t.Errorf("rotated %v, want only usr_bot", rotated)
+	}
+	if !strings.Contains(text, "key used by this server") || !strings.Contains(text, "user is deactivated") {
+		t.Errorf("result missing skip reasons:\n%s", text)
+	}
+}
+
+func TestRotateStaleKeys_RequiresActingUser(t *testing.T) {
+	old := time.Now().Add(-200 * 24 * time.Hour).Format(time.RFC3339)
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/me"):
+			w.WriteHeader(http.StatusBadGateway)
+		case strings.HasSuffix(r.URL.Path, "/admin/users"):
+			json.NewEncoder(w).Encode(client.UsersResponse{Users: []client.User{
+				{ID: "usr_me", Name: "admin", IsActive: true, KeyCreatedAt: old},
+			}})
+		default:
+			t.Errorf("unexpected API request: %s", r.URL.Path)
+		}
+	})
+	WithConfirmations(false)(s)
+
+	result, _ := s.handleRotateStaleKeys(context.Background(), toolRequest("rotate_stale_keys", map[string]any{}))
+	if !result.IsError || !strings.Contains(resultText(result), "acting user") {
+		t.Errorf("rotate_stale_keys should refuse without the acting user: %s", resultText(result))
+	}
+}
+
+func TestRotateStaleKeys_ConfirmationBoundToTargets(t *testing.T) {
+	old := time.Now().Add(-200 * 24 * time.Hour).Format(time.RFC3339)
+	users := []client.User{{ID: "usr_bot", Name: "ci-bot", IsActive: true, KeyCreatedAt: old}}
+	var rotated []string
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/me"):
+			json.NewEncoder(w).Encode(client.MeResponse{User: client.User{ID: "usr_me"}})
+		case strings.HasSuffix(r.URL.Path, "/admin/users"):
+			json.NewEncoder(w).Encode(client.UsersResponse{Users: users})
+		case strings.HasSuffix(r.URL.Path, "/rotate-key"):
+			rotated = append(rotated, strings.Split(r.URL.Path, "/")[5])
+			json.NewEncoder(w).Encode(client.RotateKeyResponse{APIKey: "mk_new"})
+		}
+	})
+
+	result, _ := s.handleRotateStaleKeys(context.Background(), toolRequest("rotate_stale_keys", map[string]any{}))
+	text := resultText(result)
+	if !strings.Contains(text, "**User IDs:** usr_bot") {
+		t.Fatalf("confirmation should list the target IDs:\n%s", text)
+	}
+	token := text[strings.Index(text, `confirm_token: "`)+len(`confirm_token: "`):]
+	token = token[:strings.Index(token, `"`)]
+
+	// Another key goes stale before the user approves
+	users = append(users, client.User{ID: "usr_new", Name: "new", IsActive: true, KeyCreatedAt: old})
+	result, _ = s.handleRotateStaleKeys(context.Background(), toolRequest("rotate_stale_keys", map[string]any{"confirm_token": token}))
+	if !result.IsError || len(rotated) != 0 {
+		t.Errorf("token approved for [usr_bot] rotated %v: %s", rotated, resultText(result))
+	}
+}
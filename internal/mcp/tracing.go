@@ -0,0 +1,46 @@
+package mcp
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName names the tracer of tool call spans. Spans go to the global
+// tracer provider, which is a no-op unless tracing is set up.
+const tracerName = "github.com/rmrfslashbin/manuals-mcp/internal/mcp"
+
+// toolNameKey is the span attribute holding the tool name.
+const toolNameKey = attribute.Key("mcp.tool.name")
+
+// tracingMiddleware runs each tool call in a span. API requests made
+// through s.api(ctx) become its children.
+func (s *Server) tracingMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		name := request.Params.Name
+		ctx, span := otel.Tracer(tracerName).Start(ctx, "tools/call "+name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(toolNameKey.String(name)),
+		)
+		defer span.End()
+
+		if b, ok := request.GetArguments()["backend"].(string); ok && b != "" {
+			span.SetAttributes(attribute.String("manuals.backend", b))
+		}
+
+		result, err := next(ctx, request)
+		switch {
+		case err != nil:
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		case result != nil && result.IsError:
+			span.SetStatus(codes.Error, resultErrorText(result))
+		}
+		return result, err
+	}
+}
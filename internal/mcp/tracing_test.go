@@ -0,0 +1,40 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestTracingMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(client.ReindexStatusResponse{Status: "idle"})
+	})
+	req := toolRequest("get_reindex_status", nil)
+	if _, err := s.tracingMiddleware(s.handleGetReindexStatus)(context.Background(), req); err != nil {
+		t.Fatalf("get_reindex_status error = %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	api, tool := spans[0], spans[1]
+	if tool.Name() != "tools/call get_reindex_status" {
+		t.Errorf("tool span = %q", tool.Name())
+	}
+	if api.Name() != "GET /rw/reindex/status" || api.Parent().SpanID() != tool.SpanContext().SpanID() {
+		t.Errorf("API span %q is not a child of the tool span", api.Name())
+	}
+}
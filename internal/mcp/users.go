@@ -0,0 +1,139 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+)
+
+// findUser returns the user with the given ID from the user list.
+func (s *Server) findUser(ctx context.Context, userID string) (*client.User, error) {
+	resp, err := s.api(ctx).ListUsers()
+	if err != nil {
+		return nil, err
+	}
+	for i := range resp.Users {
+		if resp.Users[i].ID == userID {
+			return &resp.Users[i], nil
+		}
+	}
+	return nil, fmt.Errorf("user %s not found", userID)
+}
+
+func (s *Server) handleSetUserActive(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	userID, _ := args["user_id"].(string)
+	active, ok := args["active"].(bool)
+
+	if userID == "" {
+		return mcp.NewToolResultError("user_id is required"), nil
+	}
+	if !ok {
+		return mcp.NewToolResultError("active is required (true to reactivate, false to deactivate)"), nil
+	}
+
+	// Deactivating the calling account would lock this server out
+	if !active {
+		meID, result := s.actingUserID(ctx, "its own account cannot be protected from deactivation")
+		if result != nil {
+			return result, nil
+		}
+		if meID == userID {
+			return mcp.NewToolResultError("refusing to deactivate the account this server is authenticated as"), nil
+		}
+	}
+
+	if err := s.api(ctx).SetUserActive(userID, active); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to update user status: %v", err)), nil
+	}
+	auditRef(ctx, "user_id", userID)
+
+	if active {
+		return mcp.NewToolResultText(fmt.Sprintf("# User Reactivated\n\nUser `%s` is active again and can use their existing API key.", userID)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("# User Deactivated\n\nUser `%s` is inactive. Their API key is rejected until the account is reactivated; capabilities are kept.", userID)), nil
+}
+
+func (s *Server) handleRenameUser(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	userID, _ := args["user_id"].(string)
+	name, _ := args["name"].(string)
+	name = strings.TrimSpace(name)
+
+	if userID == "" || name == "" {
+		return mcp.NewToolResultError("user_id and name are required"), nil
+	}
+
+	if err := s.api(ctx).RenameUser(userID, name); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to rename user: %v", err)), nil
+	}
+	auditRef(ctx, "user_id", userID)
+	s.forgetUserNames()
+
+	return mcp.NewToolResultText(fmt.Sprintf("# User Renamed\n\nUser `%s` is now named `%s`.", userID, name)), nil
+}
+
+func (s *Server) handleSetUserCapabilities(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	userID, _ := args["user_id"].(string)
+	list, _ := args["capabilities"].(string)
+
+	if userID == "" {
+		return mcp.NewToolResultError("user_id is required"), nil
+	}
+	caps, err := client.ParseCapabilities(list)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(caps) == 0 {
+		return mcp.NewToolResultError("capabilities must list at least one capability; use set_user_active to disable an account"), nil
+	}
+
+	// Dropping admin from the calling account would lock this server out
+	meID, result := s.actingUserID(ctx, "its own admin access cannot be protected")
+	if result != nil {
+		return result, nil
+	}
+	if meID == userID && !(&client.User{Capabilities: caps}).CanAdmin() {
+		return mcp.NewToolResultError("refusing to remove admin capabilities from the account this server is authenticated as"), nil
+	}
+
+	// Look up the current list so the change can be shown
+	before, err := s.findUser(ctx, userID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to look up user: %v", err)), nil
+	}
+
+	if err := s.api(ctx).UpdateUserCapabilities(userID, caps); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to update user capabilities: %v", err)), nil
+	}
+	auditRef(ctx, "user_id", userID)
+
+	after := client.User{Capabilities: caps}
+
+	var sb strings.Builder
+	sb.WriteString("# User Capabilities Updated\n\n")
+	sb.WriteString(fmt.Sprintf("- **User:** %s (ID: %s)\n", before.Name, userID))
+	sb.WriteString(fmt.Sprintf("- **Before:** %s (role: %s)\n", before.CapabilitiesString(), before.Role()))
+	sb.WriteString(fmt.Sprintf("- **After:** %s (role: %s)\n", after.CapabilitiesString(), after.Role()))
+
+	return mcp.NewToolResultText(sb.String()), nil
+}
+
+// actingUserID returns the ID of the account this server is authenticated
+// as. Guards against locking that account out fail closed: if it cannot be
+// identified, an error result naming what could not be protected is
+// returned instead.
+func (s *Server) actingUserID(ctx context.Context, protects string) (string, *mcp.CallToolResult) {
+	me, err := s.api(ctx).GetMe()
+	if err != nil {
+		return "", mcp.NewToolResultError(fmt.Sprintf("failed to identify the acting user, so %s: %v", protects, err))
+	}
+	if me == nil || me.ID == "" {
+		return "", mcp.NewToolResultError("failed to identify the acting user, so " + protects)
+	}
+	return me.ID, nil
+}
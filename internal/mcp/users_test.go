@@ -0,0 +1,119 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+)
+
+func TestSetUserCapabilities_ShowsChange(t *testing.T) {
+	var updated []string
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/me"):
+			json.NewEncoder(w).Encode(client.MeResponse{User: client.User{ID: "usr_me", Capabilities: []string{"*"}}})
+		case r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/admin/users"):
+			json.NewEncoder(w).Encode(client.UsersResponse{Users: []client.User{
+				{ID: "usr_1", Name: "ci-bot", Capabilities: []string{"read:*"}},
+			}})
+		case r.Method == "PUT" && strings.HasSuffix(r.URL.Path, "/admin/users/usr_1/capabilities"):
+			var req map[string][]string
+			json.NewDecoder(r.Body).Decode(&req)
+			updated = req["capabilities"]
+		default:
+			t.Errorf("unexpected API request: %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	result, _ := s.handleSetUserCapabilities(context.Background(), toolRequest("set_user_capabilities", map[string]any{
+		"user_id":      "usr_1",
+		"capabilities": "read:*,write:publish",
+	}))
+	text := resultText(result)
+
+	if result.IsError {
+		t.Fatalf("set_user_capabilities failed: %s", text)
+	}
+	if strings.Join(updated, ",") != "read:*,write:publish" {
+		t.Errorf("API received capabilities %v", updated)
+	}
+	if !strings.Contains(text, "read:* (role: ro)") || !strings.Contains(text, "read:*, write:publish (role: rw)") {
+		t.Errorf("result missing before/after capabilities:\n%s", text)
+	}
+}
+
+func TestSetUserCapabilities_KeepsOwnAdmin(t *testing.T) {
+	meStatus := http.StatusOK
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			t.Errorf("unexpected API request: %s %s", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(meStatus)
+		json.NewEncoder(w).Encode(client.MeResponse{User: client.User{ID: "usr_me", Name: "admin", Capabilities: []string{"*"}}})
+	})
+
+	args := map[string]any{"user_id": "usr_me", "capabilities": "read:*,write:*"}
+	result, _ := s.handleSetUserCapabilities(context.Background(), toolRequest("set_user_capabilities", args))
+	if !result.IsError || !strings.Contains(resultText(result), "refusing") {
+		t.Errorf("set_user_capabilities removed admin from the calling account: %s", resultText(result))
+	}
+
+	meStatus = http.StatusBadGateway
+	args["user_id"] = "usr_other"
+	result, _ = s.handleSetUserCapabilities(context.Background(), toolRequest("set_user_capabilities", args))
+	if !result.IsError || !strings.Contains(resultText(result), "acting user") {
+		t.Errorf("set_user_capabilities should refuse without the acting user: %s", resultText(result))
+	}
+}
+
+func TestSetUserActive_RefusesSelf(t *testing.T) {
+	meStatus := http.StatusOK
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			t.Errorf("unexpected API request: %s %s", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(meStatus)
+		json.NewEncoder(w).Encode(client.MeResponse{User: client.User{ID: "usr_me", Name: "admin"}})
+	})
+
+	args := map[string]any{"user_id": "usr_me", "active": false}
+	result, _ := s.handleSetUserActive(context.Background(), toolRequest("set_user_active", args))
+	if !result.IsError {
+		t.Errorf("set_user_active deactivated the calling account: %s", resultText(result))
+	}
+
+	meStatus = http.StatusBadGateway
+	args["user_id"] = "usr_other"
+	result, _ = s.handleSetUserActive(context.Background(), toolRequest("set_user_active", args))
+	if !result.IsError || !strings.Contains(resultText(result), "acting user") {
+		t.Errorf("set_user_active should refuse without the acting user: %s", resultText(result))
+	}
+}
+
+func TestUpdateUserRole_KeepsOwnAdmin(t *testing.T) {
+	meStatus := http.StatusOK
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			t.Errorf("unexpected API request: %s %s", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(meStatus)
+		json.NewEncoder(w).Encode(client.MeResponse{User: client.User{ID: "usr_me", Name: "admin", Capabilities: []string{"*"}}})
+	})
+
+	args := map[string]any{"user_id": "usr_me", "role": "rw"}
+	result, _ := s.handleUpdateUserRole(context.Background(), toolRequest("update_user_role", args))
+	if !result.IsError || !strings.Contains(resultText(result), "refusing") {
+		t.Errorf("update_user_role demoted the calling account: %s", resultText(result))
+	}
+
+	meStatus = http.StatusBadGateway
+	args["user_id"] = "usr_other"
+	result, _ = s.handleUpdateUserRole(context.Background(), toolRequest("update_user_role", args))
+	if !result.IsError || !strings.Contains(resultText(result), "acting user") {
+		t.Errorf("update_user_role should refuse without the acting user: %s", resultText(result))
+	}
+}
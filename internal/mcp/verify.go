@@ -0,0 +1,121 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+)
+
+// verifyPageSize is the page size used when scanning devices for a path.
+const verifyPageSize = 200
+
+// findDeviceByPath pages through ListDevices looking for the device at
+// destPath or whose folder is the directory of destPath. Devices in sibling
+// or parent folders do not match. Leading slashes are ignored on both
+// sides. Returns nil if no device matches.
+func (s *Server) findDeviceByPath(ctx context.Context, destPath string) (*client.Device, error) {
+	destPath = strings.TrimPrefix(destPath, "/")
+	dir := path.Dir(destPath)
+
+	for offset := 0; ; offset += verifyPageSize {
+		resp, err := s.api(ctx).ListDevices(verifyPageSize, offset, "", "")
+		if err != nil {
+			return nil, err
+		}
+		for i, d := range resp.Data {
+			devicePath := strings.TrimSuffix(strings.TrimPrefix(d.Path, "/"), "/")
+			folder := strings.TrimSuffix(devicePath, "/README.md")
+			if devicePath == destPath || folder == dir {
+				return &resp.Data[i], nil
+			}
+		}
+		if len(resp.Data) == 0 || offset+len(resp.Data) >= resp.Total {
+			return nil, nil
+		}
+	}
+}
+
+// deviceIndexedAt returns the IndexedAt value of the device at destPath, or
+// an empty string if there is none or it cannot be looked up. Publish reads
+// it before uploading so verification can tell whether the device was
+// reindexed afterwards.
+func (s *Server) deviceIndexedAt(ctx context.Context, destPath string) string {
+	device, err := s.findDeviceByPath(ctx, destPath)
+	if err != nil || device == nil {
+		return ""
+	}
+	return device.IndexedAt
+}
+
+// writePublishVerification checks that a published file produced a usable
+// device entry and renders the findings as a markdown section. It returns
+// the number of problems found. A device whose IndexedAt still equals
+// previousIndexedAt, read before the upload, reflects the previous content
+// and counts as a problem.
+func (s *Server) writePublishVerification(ctx context.Context, sb *strings.Builder, destPath, previousIndexedAt string, wait *client.ReindexWait) int {
+	problems := 0
+	sb.WriteString("\n## Verification\n\n")
+
+	if wait == nil || !wait.Completed {
+		sb.WriteString("- **Skipped:** Reindex has not finished, so the index cannot be verified yet. Use `wait_for_reindex()` and publish again with `verify: true`, or check manually.\n")
+		return 1
+	}
+
+	if lastRun := wait.Status.LastRun; lastRun != nil && lastRun.Errors > 0 {
+		sb.WriteString(fmt.Sprintf("- **⚠️ Indexer Errors:** %d error(s) in the last run. Check the API server logs.\n", lastRun.Errors))
+		problems++
+	}
+
+	device, err := s.findDeviceByPath(ctx, destPath)
+	if err != nil {
+		sb.WriteString(fmt.Sprintf("- **⚠️ Device Lookup Failed:** %v\n", err))
+		return problems + 1
+	}
+	if device == nil {
+		sb.WriteString(fmt.Sprintf("- **⚠️ Device Not Found:** No device is indexed at `%s`.\n", path.Dir(destPath)))
+		sb.WriteString("  Device folders need a README.md; see `ingest_workflow()` for the required structure.\n")
+		return problems + 1
+	}
+
+	if previousIndexedAt != "" && device.IndexedAt == previousIndexedAt {
+		sb.WriteString(fmt.Sprintf("- **⚠️ Stale Index:** %s was last indexed at %s, before this upload. The index may not include the new content yet.\n", device.ID, device.IndexedAt))
+		problems++
+	}
+
+	full, err := s.api(ctx).GetDevice(device.ID, false)
+	if err != nil {
+		sb.WriteString(fmt.Sprintf("- **⚠️ Device Fetch Failed:** %s - %v\n", device.ID, err))
+		return problems + 1
+	}
+	sb.WriteString(fmt.Sprintf("- **✓ Device:** %s (ID: %s)\n", full.Name, full.ID))
+	sb.WriteString(fmt.Sprintf("- **Path:** %s\n", full.Path))
+
+	pinout, err := s.api(ctx).GetDevicePinout(device.ID)
+	switch {
+	case err != nil:
+		sb.WriteString(fmt.Sprintf("- **⚠️ Pinout:** not available (%v)\n", err))
+		problems++
+	case len(pinout.Pins) == 0:
+		sb.WriteString("- **⚠️ Pinout:** no pins parsed. Check the pinout table format.\n")
+		problems++
+	default:
+		sb.WriteString(fmt.Sprintf("- **✓ Pinout:** %d pins parsed\n", len(pinout.Pins)))
+	}
+
+	specs, err := s.api(ctx).GetDeviceSpecs(device.ID)
+	switch {
+	case err != nil:
+		sb.WriteString(fmt.Sprintf("- **⚠️ Specs:** not available (%v)\n", err))
+		problems++
+	case len(specs.Specs) == 0:
+		sb.WriteString("- **⚠️ Specs:** no specifications parsed. Check the `specs:` frontmatter block.\n")
+		problems++
+	default:
+		sb.WriteString(fmt.Sprintf("- **✓ Specs:** %d specifications parsed\n", len(specs.Specs)))
+	}
+
+	return problems
+}
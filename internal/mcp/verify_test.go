@@ -0,0 +1,126 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+)
+
+func TestFindDeviceByPath(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		offset := r.URL.Query().Get("offset")
+		devices := []client.Device{{ID: "other", Path: "sensors/temperature/lm75"}}
+		if offset != "" {
+			devices = []client.Device{{ID: "ds18b20", Path: "sensors/temperature/ds18b20/README.md"}}
+		}
+		json.NewEncoder(w).Encode(client.DevicesResponse{Data: devices, Total: 2})
+	})
+
+	device, err := s.findDeviceByPath(context.Background(), "sensors/temperature/ds18b20/DS18B20_Reference.md")
+	if err != nil {
+		t.Fatalf("findDeviceByPath() error = %v", err)
+	}
+	if device == nil || device.ID != "ds18b20" {
+		t.Errorf("findDeviceByPath() = %+v, want ds18b20", device)
+	}
+}
+
+func TestFindDeviceByPath_IgnoresSiblings(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(client.DevicesResponse{
+			Data:  []client.Device{{ID: "lm75", Path: "sensors/temperature/lm75"}},
+			Total: 1,
+		})
+	})
+
+	device, err := s.findDeviceByPath(context.Background(), "sensors/temperature/tmp117/README.md")
+	if err != nil {
+		t.Fatalf("findDeviceByPath() error = %v", err)
+	}
+	if device != nil {
+		t.Errorf("findDeviceByPath() = %+v, want nil for a new device next to lm75", device)
+	}
+}
+
+func TestFindDeviceByPath_LeadingSlash(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(client.DevicesResponse{
+			Data:  []client.Device{{ID: "ds18b20", Path: "/sensors/temperature/ds18b20/"}},
+			Total: 1,
+		})
+	})
+
+	for _, destPath := range []string{"/sensors/temperature/ds18b20/README.md", "sensors/temperature/ds18b20/README.md"} {
+		device, err := s.findDeviceByPath(context.Background(), destPath)
+		if err != nil {
+			t.Fatalf("findDeviceByPath(%q) error = %v", destPath, err)
+		}
+		if device == nil || device.ID != "ds18b20" {
+			t.Errorf("findDeviceByPath(%q) = %+v, want ds18b20", destPath, device)
+		}
+	}
+}
+
+func TestWritePublishVerification_StaleIndex(t *testing.T) {
+	previousIndexedAt := "2026-10-18T10:00:00Z"
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/devices"):
+			json.NewEncoder(w).Encode(client.DevicesResponse{
+				Data: []client.Device{{
+					ID:        "bme280",
+					Path:      "sensors/environmental/bme280",
+					IndexedAt: previousIndexedAt,
+				}},
+				Total: 1,
+			})
+		case strings.HasSuffix(r.URL.Path, "/pinout"):
+			json.NewEncoder(w).Encode(client.PinoutResponse{DeviceID: "bme280", Pins: []client.PinoutPin{{Name: "VCC"}}})
+		case strings.HasSuffix(r.URL.Path, "/specs"):
+			json.NewEncoder(w).Encode(client.SpecsResponse{Specs: map[string]string{"voltage": "3.3V"}})
+		default:
+			json.NewEncoder(w).Encode(client.Device{ID: "bme280", Name: "BME280"})
+		}
+	})
+
+	wait := &client.ReindexWait{Completed: true, Status: &client.ReindexStatusResponse{Status: "idle"}}
+
+	var sb strings.Builder
+	problems := s.writePublishVerification(context.Background(), &sb, "sensors/environmental/bme280/README.md", previousIndexedAt, wait)
+	if problems != 1 || !strings.Contains(sb.String(), "Stale Index") {
+		t.Errorf("writePublishVerification() problems = %d, want 1 stale index\n%s", problems, sb.String())
+	}
+}
+
+func TestWritePublishVerification(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/devices"):
+			json.NewEncoder(w).Encode(client.DevicesResponse{
+				Data:  []client.Device{{ID: "bme280", Path: "sensors/environmental/bme280"}},
+				Total: 1,
+			})
+		case strings.HasSuffix(r.URL.Path, "/pinout"):
+			json.NewEncoder(w).Encode(client.PinoutResponse{DeviceID: "bme280"})
+		case strings.HasSuffix(r.URL.Path, "/specs"):
+			json.NewEncoder(w).Encode(client.SpecsResponse{Specs: map[string]string{"voltage": "3.3V"}})
+		default:
+			json.NewEncoder(w).Encode(client.Device{ID: "bme280", Name: "BME280"})
+		}
+	})
+
+	wait := &client.ReindexWait{Completed: true, Status: &client.ReindexStatusResponse{Status: "idle"}}
+
+	var sb strings.Builder
+	problems := s.writePublishVerification(context.Background(), &sb, "sensors/environmental/bme280/README.md", "", wait)
+	if problems != 1 {
+		t.Errorf("writePublishVerification() problems = %d, want 1 (empty pinout)\n%s", problems, sb.String())
+	}
+	if !strings.Contains(sb.String(), "no pins parsed") {
+		t.Errorf("writePublishVerification() output missing pinout warning:\n%s", sb.String())
+	}
+}
@@ -0,0 +1,148 @@
+// Package metrics exposes Prometheus metrics for tool calls, upstream API
+// requests, uploads and reindex jobs.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Status values of the tool call counter.
+const (
+	StatusSuccess = "success"
+	StatusError   = "error"
+)
+
+// Metrics holds the collectors. A nil *Metrics is valid and records
+// nothing, so callers need not check whether metrics are enabled.
+type Metrics struct {
+	registry    *prometheus.Registry
+	toolCalls   *prometheus.CounterVec
+	toolLatency *prometheus.HistogramVec
+	apiLatency  *prometheus.HistogramVec
+	uploadBytes prometheus.Counter
+	reindex     *prometheus.HistogramVec
+
+	mu sync.Mutex
+	// reindexSeen is the last completed reindex recorded per backend, so
+	// polling the same status twice does not count the run twice.
+	reindexSeen map[string]string
+}
+
+// New creates the collectors in a registry of their own, together with the
+// Go runtime and process collectors.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		toolCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "manuals_mcp_tool_calls_total",
+			Help: "MCP tool calls by tool and status (success or error).",
+		}, []string{"tool", "status"}),
+		toolLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "manuals_mcp_tool_duration_seconds",
+			Help:    "MCP tool call latency.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"tool"}),
+		apiLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "manuals_mcp_api_request_duration_seconds",
+			Help:    "Manuals API request latency by method, route and HTTP status (0 if no response).",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		uploadBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "manuals_mcp_upload_bytes_total",
+			Help: "Bytes uploaded to the Manuals API.",
+		}),
+		reindex: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "manuals_mcp_reindex_duration_seconds",
+			Help:    "Duration of completed reindex jobs as reported by the API.",
+			Buckets: []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800},
+		}, []string{"backend"}),
+		reindexSeen: make(map[string]string),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.toolCalls, m.toolLatency, m.apiLatency, m.uploadBytes, m.reindex,
+	)
+	return m
+}
+
+// ObserveTool records a tool call.
+func (m *Metrics) ObserveTool(tool string, failed bool, latency time.Duration) {
+	if m == nil {
+		return
+	}
+	status := StatusSuccess
+	if failed {
+		status = StatusError
+	}
+	m.toolCalls.WithLabelValues(tool, status).Inc()
+	m.toolLatency.WithLabelValues(tool).Observe(latency.Seconds())
+}
+
+// ObserveRequest records an API request. It matches client.RequestObserver.
+func (m *Metrics) ObserveRequest(method, route string, status int, latency time.Duration) {
+	if m == nil {
+		return
+	}
+	m.apiLatency.WithLabelValues(method, route, strconv.Itoa(status)).Observe(latency.Seconds())
+}
+
+// AddUploadBytes records an upload of n bytes.
+func (m *Metrics) AddUploadBytes(n int) {
+	if m == nil {
+		return
+	}
+	m.uploadBytes.Add(float64(n))
+}
+
+// ObserveReindex records the last reindex run of a backend, identified by
+// its completion time. A run already recorded is ignored.
+func (m *Metrics) ObserveReindex(backend, completed string, duration time.Duration) {
+	if m == nil || completed == "" {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.reindexSeen[backend] == completed {
+		return
+	}
+	m.reindexSeen[backend] = completed
+	m.reindex.WithLabelValues(backend).Observe(duration.Seconds())
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Serve listens on addr and serves the metrics at /metrics until ctx is
+// cancelled. It returns once the listener is open, with its address.
+func (m *Metrics) Serve(ctx context.Context, addr string) (net.Addr, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for metrics: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	go srv.Serve(ln)
+	return ln.Addr(), nil
+}
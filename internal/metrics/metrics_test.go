@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServe(t *testing.T) {
+	m := New()
+	m.ObserveTool("search", false, 20*time.Millisecond)
+	m.ObserveTool("publish", true, time.Second)
+	m.ObserveRequest("GET", "/devices/{id}", 200, 5*time.Millisecond)
+	m.AddUploadBytes(1024)
+	m.ObserveReindex("default", "2025-12-01T00:00:00Z", 90*time.Second)
+	m.ObserveReindex("default", "2025-12-01T00:00:00Z", 90*time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	addr, err := m.Serve(ctx, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+
+	resp, err := http.Get("http://" + addr.String() + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	for _, want := range []string{
+		`manuals_mcp_tool_calls_total{status="success",tool="search"} 1`,
+		`manuals_mcp_tool_calls_total{status="error",tool="publish"} 1`,
+		`manuals_mcp_tool_duration_seconds_count{tool="search"} 1`,
+		`manuals_mcp_api_request_duration_seconds_count{method="GET",route="/devices/{id}",status="200"} 1`,
+		`manuals_mcp_upload_bytes_total 1024`,
+		`manuals_mcp_reindex_duration_seconds_count{backend="default"} 1`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics missing %q", want)
+		}
+	}
+}
+
+func TestNil(t *testing.T) {
+	var m *Metrics
+	m.ObserveTool("search", false, time.Second)
+	m.ObserveRequest("GET", "/search", 200, time.Second)
+	m.AddUploadBytes(1)
+	m.ObserveReindex("default", "now", time.Second)
+}
@@ -0,0 +1,133 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/zalando/go-keyring"
+)
+
+// CommandTimeout limits how long a key command may run.
+const CommandTimeout = 10 * time.Second
+
+// Ref says where to read a secret from. At most one field may be set.
+type Ref struct {
+	// File is the path of a file holding the secret.
+	File string
+	// Command is a shell command that prints the secret, e.g.
+	// "pass show manuals".
+	Command string
+	// Keyring is an OS keyring entry as "account" (in KeyringService) or
+	// "service/account". The "keyring:" prefix of KeyringSink references
+	// is accepted.
+	Keyring string
+}
+
+// IsZero reports whether no source is set.
+func (r Ref) IsZero() bool {
+	return r.File == "" && r.Command == "" && r.Keyring == ""
+}
+
+// String describes the source without revealing the secret.
+func (r Ref) String() string {
+	switch {
+	case r.File != "":
+		return "file " + r.File
+	case r.Command != "":
+		return fmt.Sprintf("command %q", r.Command)
+	case r.Keyring != "":
+		service, account := r.keyringEntry()
+		return fmt.Sprintf("keyring %s/%s", service, account)
+	}
+	return "none"
+}
+
+// keyringEntry splits Keyring into service and account.
+func (r Ref) keyringEntry() (service, account string) {
+	entry := strings.TrimPrefix(r.Keyring, "keyring:")
+	if service, account, ok := strings.Cut(entry, "/"); ok {
+		return service, account
+	}
+	return KeyringService, entry
+}
+
+// Resolve reads the secret r refers to, trimming surrounding whitespace.
+// It returns "" for a zero Ref and an error if more than one source is set
+// or the source yields an empty secret.
+func Resolve(ctx context.Context, r Ref) (string, error) {
+	set := 0
+	for _, v := range []string{r.File, r.Command, r.Keyring} {
+		if v != "" {
+			set++
+		}
+	}
+	switch set {
+	case 0:
+		return "", nil
+	case 1:
+	default:
+		return "", errors.New("set only one of a key file, key command, or keyring entry")
+	}
+
+	var secret string
+	switch {
+	case r.File != "":
+		data, err := os.ReadFile(r.File)
+		if err != nil {
+			return "", fmt.Errorf("failed to read key file: %w", err)
+		}
+		secret = string(data)
+	case r.Command != "":
+		out, err := runCommand(ctx, r.Command)
+		if err != nil {
+			return "", err
+		}
+		secret = out
+	case r.Keyring != "":
+		service, account := r.keyringEntry()
+		v, err := keyring.Get(service, account)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s/%s from OS keyring: %w", service, account, err)
+		}
+		secret = v
+	}
+
+	secret = strings.TrimSpace(secret)
+	if secret == "" {
+		return "", fmt.Errorf("%s is empty", r)
+	}
+	return secret, nil
+}
+
+// runCommand runs command with the platform shell and returns its output.
+func runCommand(ctx context.Context, command string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, CommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	// Stdin is left as the null device: under "serve" it carries the MCP
+	// stream.
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("key command %q failed: %w: %s", command, err, msg)
+		}
+		return "", fmt.Errorf("key command %q failed: %w", command, err)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,89 @@
+// Package secrets stores newly generated API keys outside the MCP transcript.
+package secrets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/zalando/go-keyring"
+)
+
+// KeyringService is the OS keyring service name used for stored keys.
+const KeyringService = "manuals-mcp"
+
+// Sink stores a secret and returns a reference that can be shown to the
+// model in place of the secret itself.
+type Sink interface {
+	Store(account, secret string) (ref string, err error)
+}
+
+// New creates a sink by name: "file" writes to dir, "keyring" uses the OS
+// keyring. An empty name returns a nil sink.
+func New(name, dir string) (Sink, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case "file":
+		return &FileSink{Dir: dir}, nil
+	case "keyring":
+		return KeyringSink{}, nil
+	default:
+		return nil, fmt.Errorf("invalid secret sink: %s (must be file or keyring)", name)
+	}
+}
+
+// Fingerprint returns a short, non-reversible identifier for a secret so
+// it can be matched later without revealing it.
+func Fingerprint(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return "sha256:" + hex.EncodeToString(sum[:])[:16]
+}
+
+// unsafeChars matches characters not allowed in generated file names.
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// FileSink writes each secret to its own owner-only file in Dir.
+type FileSink struct {
+	Dir string
+}
+
+// Store writes the secret to a new file and returns its path.
+func (f *FileSink) Store(account, secret string) (string, error) {
+	if err := os.MkdirAll(f.Dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create key directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.key", unsafeChars.ReplaceAllString(account, "_"), time.Now().UTC().Format("20060102T150405Z"))
+	path := filepath.Join(f.Dir, name)
+
+	// O_EXCL so an existing key file is never overwritten
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return "", fmt.Errorf("failed to create key file: %w", err)
+	}
+	if _, err := file.WriteString(secret + "\n"); err != nil {
+		file.Close()
+		os.Remove(path)
+		return "", fmt.Errorf("failed to write key file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to close key file: %w", err)
+	}
+	return path, nil
+}
+
+// KeyringSink stores secrets in the OS keyring under KeyringService.
+type KeyringSink struct{}
+
+// Store saves the secret in the keyring and returns a keyring reference.
+func (KeyringSink) Store(account, secret string) (string, error) {
+	if err := keyring.Set(KeyringService, account, secret); err != nil {
+		return "", fmt.Errorf("failed to store key in OS keyring: %w", err)
+	}
+	return fmt.Sprintf("keyring:%s/%s", KeyringService, account), nil
+}
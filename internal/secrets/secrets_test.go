@@ -0,0 +1,104 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileSink_Store(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "keys")
+	sink := &FileSink{Dir: dir}
+
+	ref, err := sink.Store("ci-bot/usr_1", "mk_secret")
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	if filepath.Dir(ref) != dir {
+		t.Errorf("Store() ref = %s, want file in %s", ref, dir)
+	}
+	if strings.Contains(filepath.Base(ref), "/") || !strings.HasPrefix(filepath.Base(ref), "ci-bot_usr_1-") {
+		t.Errorf("Store() file name = %s, want sanitized account prefix", filepath.Base(ref))
+	}
+
+	info, err := os.Stat(ref)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("key file mode = %v, want 0600", info.Mode().Perm())
+	}
+	data, _ := os.ReadFile(ref)
+	if strings.TrimSpace(string(data)) != "mk_secret" {
+		t.Errorf("key file content = %q, want mk_secret", data)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	fp := Fingerprint("mk_secret")
+	if !strings.HasPrefix(fp, "sha256:") || len(fp) != len("sha256:")+16 {
+		t.Errorf("Fingerprint() = %s, want sha256: prefix and 16 hex chars", fp)
+	}
+	if strings.Contains(fp, "mk_secret") {
+		t.Error("Fingerprint() leaked the secret")
+	}
+	if Fingerprint("mk_secret") != fp {
+		t.Error("Fingerprint() is not deterministic")
+	}
+}
+
+func TestNew(t *testing.T) {
+	if sink, err := New("", ""); sink != nil || err != nil {
+		t.Errorf("New(\"\") = %v, %v, want nil, nil", sink, err)
+	}
+	if _, err := New("vault", ""); err == nil {
+		t.Error("New(\"vault\") should return error")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "key")
+	os.WriteFile(file, []byte("mk_from_file\n"), 0600)
+	empty := filepath.Join(t.TempDir(), "empty")
+	os.WriteFile(empty, []byte("\n"), 0600)
+
+	tests := []struct {
+		name    string
+		ref     Ref
+		want    string
+		wantErr string
+	}{
+		{name: "none", ref: Ref{}},
+		{name: "file", ref: Ref{File: file}, want: "mk_from_file"},
+		{name: "command", ref: Ref{Command: "echo '  mk_from_command  '"}, want: "mk_from_command"},
+		{name: "missing file", ref: Ref{File: file + ".missing"}, wantErr: "failed to read key file"},
+		{name: "empty file", ref: Ref{File: empty}, wantErr: "is empty"},
+		{name: "failing command", ref: Ref{Command: "echo locked >&2; exit 3"}, wantErr: "locked"},
+		{name: "two sources", ref: Ref{File: file, Command: "echo x"}, wantErr: "only one"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(context.Background(), tt.ref)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Resolve() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Resolve() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRef_String(t *testing.T) {
+	if got := (Ref{Keyring: "keyring:manuals-mcp/prod"}).String(); got != "keyring manuals-mcp/prod" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := (Ref{Keyring: "prod"}).String(); got != "keyring manuals-mcp/prod" {
+		t.Errorf("String() = %q, want default service", got)
+	}
+}
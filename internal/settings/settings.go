@@ -0,0 +1,253 @@
+// Package settings validates, diffs, and serializes Manuals API settings.
+package settings
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+	"gopkg.in/yaml.v3"
+)
+
+// Schema maps setting keys to their definitions.
+type Schema map[string]client.SettingSchema
+
+// NewSchema indexes a schema list by key.
+func NewSchema(defs []client.SettingSchema) Schema {
+	schema := make(Schema, len(defs))
+	for _, def := range defs {
+		schema[def.Key] = def
+	}
+	return schema
+}
+
+// Validate checks value against the schema for key and returns it in
+// canonical form, e.g. "yes" becomes "true" for a bool setting. Durations
+// and floats keep the spelling the user gave; use Equal to compare them.
+func (s Schema) Validate(key, value string) (string, error) {
+	def, ok := s[key]
+	if !ok {
+		if similar := s.similar(key); len(similar) > 0 {
+			return "", fmt.Errorf("unknown setting %q (did you mean %s?)", key, strings.Join(similar, ", "))
+		}
+		return "", fmt.Errorf("unknown setting %q", key)
+	}
+	return Normalize(def, value)
+}
+
+// similar returns known keys that look like a typo of key.
+func (s Schema) similar(key string) []string {
+	var keys []string
+	for k := range s {
+		if distance(strings.ToLower(k), strings.ToLower(key)) <= 2 || strings.Contains(k, key) {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Normalize validates value against def and returns its canonical form.
+// Durations and floats are returned as given, since re-rendering them
+// ("5m" as "5m0s") would make unchanged settings look different.
+func Normalize(def client.SettingSchema, value string) (string, error) {
+	value = strings.TrimSpace(value)
+
+	switch def.Type {
+	case "int":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("%s must be an integer, got %q", def.Key, value)
+		}
+		if err := checkRange(def, float64(n)); err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(n, 10), nil
+	case "float":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", fmt.Errorf("%s must be a number, got %q", def.Key, value)
+		}
+		if err := checkRange(def, f); err != nil {
+			return "", err
+		}
+		return value, nil
+	case "bool":
+		switch strings.ToLower(value) {
+		case "true", "yes", "on", "1":
+			return "true", nil
+		case "false", "no", "off", "0":
+			return "false", nil
+		}
+		return "", fmt.Errorf("%s must be true or false, got %q", def.Key, value)
+	case "duration":
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return "", fmt.Errorf("%s must be a duration like 30s or 5m, got %q", def.Key, value)
+		}
+		if err := checkRange(def, d.Seconds()); err != nil {
+			return "", err
+		}
+		return value, nil
+	case "url":
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return "", fmt.Errorf("%s must be an absolute URL, got %q", def.Key, value)
+		}
+		return value, nil
+	}
+
+	// enum, string, and types this client does not know about
+	if len(def.Allowed) > 0 && !slices.Contains(def.Allowed, value) {
+		return "", fmt.Errorf("%s must be one of %s, got %q", def.Key, strings.Join(def.Allowed, ", "), value)
+	}
+	return value, nil
+}
+
+// checkRange enforces the schema minimum and maximum, if any. Durations
+// are compared in seconds.
+func checkRange(def client.SettingSchema, v float64) error {
+	if def.Min != nil && v < *def.Min {
+		return fmt.Errorf("%s must be at least %g, got %g", def.Key, *def.Min, v)
+	}
+	if def.Max != nil && v > *def.Max {
+		return fmt.Errorf("%s must be at most %g, got %g", def.Key, *def.Max, v)
+	}
+	return nil
+}
+
+// Equal reports whether a and b are the same value of setting key, e.g.
+// "5m" and "300s" for a duration. Keys without a definition, and values
+// that do not parse, are compared as strings.
+func (s Schema) Equal(key, a, b string) bool {
+	if a == b {
+		return true
+	}
+	def, ok := s[key]
+	if !ok {
+		return false
+	}
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+
+	switch def.Type {
+	case "int", "float":
+		x, errA := strconv.ParseFloat(a, 64)
+		y, errB := strconv.ParseFloat(b, 64)
+		return errA == nil && errB == nil && x == y
+	case "duration":
+		x, errA := time.ParseDuration(a)
+		y, errB := time.ParseDuration(b)
+		return errA == nil && errB == nil && x == y
+	case "bool":
+		x, errA := Normalize(def, a)
+		y, errB := Normalize(def, b)
+		return errA == nil && errB == nil && x == y
+	}
+	return a == b
+}
+
+// Change is a setting whose desired value differs from its current value.
+type Change struct {
+	Key   string
+	Old   string
+	New   string
+	IsNew bool // the setting has no current value
+}
+
+// FormatChanges renders changes as diff lines, "- key: old" followed by
+// "+ key: new", with "(not set)" as the old value of new settings.
+func FormatChanges(changes []Change) string {
+	var sb strings.Builder
+	for _, c := range changes {
+		old := c.Old
+		if c.IsNew {
+			old = "(not set)"
+		}
+		fmt.Fprintf(&sb, "- %s: %s\n+ %s: %s\n", c.Key, old, c.Key, c.New)
+	}
+	return sb.String()
+}
+
+// Diff returns the settings in desired that differ from current, sorted
+// by key. Values are compared with schema.Equal; a nil schema compares
+// them as strings.
+func Diff(current []client.Setting, desired map[string]string, schema Schema) []Change {
+	values := make(map[string]string, len(current))
+	for _, s := range current {
+		values[s.Key] = s.Value
+	}
+
+	var changes []Change
+	for _, key := range slices.Sorted(maps.Keys(desired)) {
+		old, ok := values[key]
+		if ok && schema.Equal(key, old, desired[key]) {
+			continue
+		}
+		changes = append(changes, Change{Key: key, Old: old, New: desired[key], IsNew: !ok})
+	}
+	return changes
+}
+
+// File is the YAML layout used by Export and Import.
+type File struct {
+	ExportedAt string            `yaml:"exported_at,omitempty"`
+	Source     string            `yaml:"source,omitempty"`
+	Settings   map[string]string `yaml:"settings"`
+}
+
+// Export serializes settings as YAML. Source records where they came from,
+// typically the API URL.
+func Export(current []client.Setting, source string, now time.Time) ([]byte, error) {
+	f := File{
+		ExportedAt: now.UTC().Format(time.RFC3339),
+		Source:     source,
+		Settings:   make(map[string]string, len(current)),
+	}
+	for _, s := range current {
+		f.Settings[s.Key] = s.Value
+	}
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode settings: %w", err)
+	}
+	return data, nil
+}
+
+// Import parses settings exported by Export. Scalar values of any YAML
+// type are accepted and read as strings.
+func Import(data []byte) (map[string]string, error) {
+	var f File
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse settings YAML: %w", err)
+	}
+	if len(f.Settings) == 0 {
+		return nil, fmt.Errorf("settings YAML has no settings")
+	}
+	return f.Settings, nil
+}
+
+// distance returns the Levenshtein edit distance between a and b.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,133 @@
+package settings
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+)
+
+func ptr(f float64) *float64 { return &f }
+
+func TestSchema_Validate(t *testing.T) {
+	schema := NewSchema([]client.SettingSchema{
+		{Key: "reindex.workers", Type: "int", Min: ptr(1), Max: ptr(16)},
+		{Key: "search.fuzzy", Type: "bool"},
+		{Key: "reindex.interval", Type: "duration"},
+		{Key: "log.level", Type: "enum", Allowed: []string{"debug", "info", "warn"}},
+		{Key: "git.remote", Type: "url"},
+	})
+
+	tests := []struct {
+		key, value string
+		want       string
+		wantErr    string
+	}{
+		{"reindex.workers", " 4 ", "4", ""},
+		{"reindex.workers", "0", "", "at least 1"},
+		{"reindex.workers", "four", "", "must be an integer"},
+		{"search.fuzzy", "yes", "true", ""},
+		{"search.fuzzy", "maybe", "", "true or false"},
+		{"reindex.interval", "90s", "90s", ""},
+		{"log.level", "info", "info", ""},
+		{"log.level", "trace", "", "one of debug, info, warn"},
+		{"git.remote", "https://github.com/org/docs", "https://github.com/org/docs", ""},
+		{"git.remote", "github.com/org/docs", "", "absolute URL"},
+		{"reindex.worker", "4", "", "did you mean reindex.workers"},
+		{"unrelated", "x", "", "unknown setting"},
+	}
+	for _, tt := range tests {
+		got, err := schema.Validate(tt.key, tt.value)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate(%s, %q) error = %v, want %q", tt.key, tt.value, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Validate(%s, %q) = %q, %v, want %q", tt.key, tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	current := []client.Setting{{Key: "a", Value: "1"}, {Key: "b", Value: "2"}}
+	changes := Diff(current, map[string]string{"a": "1", "b": "3", "c": "4"}, nil)
+
+	if len(changes) != 2 {
+		t.Fatalf("Diff() returned %d changes, want 2: %+v", len(changes), changes)
+	}
+	if c := changes[0]; c.Key != "b" || c.Old != "2" || c.New != "3" || c.IsNew {
+		t.Errorf("changes[0] = %+v, want b 2 -> 3", c)
+	}
+	if c := changes[1]; c.Key != "c" || !c.IsNew {
+		t.Errorf("changes[1] = %+v, want new setting c", c)
+	}
+}
+
+func TestDiff_ComparesParsedValues(t *testing.T) {
+	schema := NewSchema([]client.SettingSchema{
+		{Key: "reindex.interval", Type: "duration"},
+		{Key: "search.threshold", Type: "float"},
+		{Key: "search.fuzzy", Type: "bool"},
+		{Key: "git.branch", Type: "string"},
+	})
+	current := []client.Setting{
+		{Key: "reindex.interval", Value: "5m0s"},
+		{Key: "search.threshold", Value: "0.50"},
+		{Key: "search.fuzzy", Value: "true"},
+		{Key: "git.branch", Value: "main"},
+	}
+
+	changes := Diff(current, map[string]string{
+		"reindex.interval": "5m",
+		"search.threshold": "0.5",
+		"search.fuzzy":     "yes",
+		"git.branch":       "Main",
+	}, schema)
+	if len(changes) != 1 || changes[0].Key != "git.branch" {
+		t.Errorf("Diff() = %+v, want only git.branch", changes)
+	}
+}
+
+func TestExportImport_RoundTrip(t *testing.T) {
+	current := []client.Setting{{Key: "reindex.workers", Value: "4"}, {Key: "search.fuzzy", Value: "true"}}
+	data, err := Export(current, "http://manuals.local", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if !strings.Contains(string(data), "source: http://manuals.local") {
+		t.Errorf("Export() missing source:\n%s", data)
+	}
+
+	got, err := Import(data)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if got["reindex.workers"] != "4" || got["search.fuzzy"] != "true" {
+		t.Errorf("Import() = %v, want exported values", got)
+	}
+}
+
+func TestImport_UnquotedScalars(t *testing.T) {
+	got, err := Import([]byte("settings:\n  reindex.workers: 8\n  search.fuzzy: false\n"))
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if got["reindex.workers"] != "8" || got["search.fuzzy"] != "false" {
+		t.Errorf("Import() = %v, want string values", got)
+	}
+
+	if _, err := Import([]byte("exported_at: now\n")); err == nil {
+		t.Error("Import() with no settings should return error")
+	}
+}
+
+func TestFormatChanges(t *testing.T) {
+	got := FormatChanges([]Change{{Key: "a", Old: "1", New: "2"}, {Key: "b", New: "x", IsNew: true}})
+	want := "- a: 1\n+ a: 2\n- b: (not set)\n+ b: x\n"
+	if got != want {
+		t.Errorf("FormatChanges() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,670 @@
+// Package tui implements the interactive terminal browser for the manuals
+// library.
+package tui
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+)
+
+// DefaultPageSize is the number of devices loaded per page.
+const DefaultPageSize = 50
+
+// domains are the values cycled by the domain filter key; "" means all.
+var domains = []string{"", "hardware", "software", "protocol"}
+
+// tabs are the sections of the device view.
+var tabs = []string{"README", "Pinout", "Specs", "Refs"}
+
+const (
+	tabReadme = iota
+	tabPinout
+	tabSpecs
+	tabRefs
+)
+
+// openURL opens an external reference in the user's browser. Only http
+// and https URLs are opened, so a reference cannot launch a local file or
+// another URL handler.
+func openURL(ref string) error {
+	u, err := url.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("refusing to open %q: only http and https links are supported", ref)
+	}
+	return startBrowser(u.String())
+}
+
+// startBrowser launches the platform browser for url. It is a variable so
+// tests can replace it.
+var startBrowser = func(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// Reap the launcher so repeated opens do not leave zombies behind
+	go cmd.Wait()
+	return nil
+}
+
+var (
+	titleStyle     = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	dimStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	errorStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	activeTabStyle = lipgloss.NewStyle().Bold(true).Reverse(true).Padding(0, 1)
+	tabStyle       = lipgloss.NewStyle().Padding(0, 1)
+	selectedStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+)
+
+// Options configures the browser.
+type Options struct {
+	// PageSize is the number of devices per page (default DefaultPageSize).
+	PageSize int
+	// Style is the glamour style used for README content: "auto" (default),
+	// "dark", "light", or "notty".
+	Style string
+}
+
+// Model is the bubbletea model of the browser.
+type Model struct {
+	client   *client.Client
+	pageSize int
+	style    string
+
+	width, height int
+
+	// Device list.
+	table      table.Model
+	items      []client.SearchResult
+	offset     int
+	total      int
+	domain     int
+	deviceType string
+	query      string
+
+	// Search and type filter input.
+	input     textinput.Model
+	inputKind string
+
+	// Device view; nil while the list is shown.
+	detail   *deviceDetail
+	history  []string
+	tab      int
+	refIndex int
+	viewport viewport.Model
+
+	loading bool
+	status  string
+	err     error
+}
+
+// deviceDetail holds everything shown for one device. Pinouts and specs
+// only exist for some devices, so their errors are kept per section.
+type deviceDetail struct {
+	device    *client.Device
+	pinout    *client.PinoutResponse
+	pinoutErr error
+	specs     *client.SpecsResponse
+	specsErr  error
+	refs      []client.Reference
+	refsErr   error
+	readme    string
+	readmeW   int
+}
+
+// listLoadedMsg carries a page of devices or search results.
+type listLoadedMsg struct {
+	items  []client.SearchResult
+	total  int
+	offset int
+}
+
+// deviceLoadedMsg carries a device and its sections.
+type deviceLoadedMsg struct {
+	detail *deviceDetail
+}
+
+// errMsg reports a failed request.
+type errMsg struct {
+	err error
+}
+
+// openedMsg reports the result of opening an external reference.
+type openedMsg struct {
+	url string
+	err error
+}
+
+// New creates a browser backed by c.
+func New(c *client.Client, opts Options) Model {
+	if opts.PageSize <= 0 {
+		opts.PageSize = DefaultPageSize
+	}
+	if opts.Style == "" {
+		opts.Style = "auto"
+	}
+
+	t := table.New(table.WithFocused(true))
+	input := textinput.New()
+	input.CharLimit = 200
+
+	return Model{
+		client:   c,
+		pageSize: opts.PageSize,
+		style:    opts.Style,
+		table:    t,
+		input:    input,
+		viewport: viewport.New(0, 0),
+		width:    100,
+		height:   30,
+	}
+}
+
+// Init loads the first page of devices.
+func (m Model) Init() tea.Cmd {
+	return m.loadList()
+}
+
+// loadList fetches the current page, or runs the current search.
+func (m Model) loadList() tea.Cmd {
+	c, query, domain, deviceType, limit, offset := m.client, m.query, domains[m.domain], m.deviceType, m.pageSize, m.offset
+	return func() tea.Msg {
+		if query != "" {
+			resp, err := c.Search(query, limit, domain, deviceType)
+			if err != nil {
+				return errMsg{fmt.Errorf("search failed: %w", err)}
+			}
+			return listLoadedMsg{items: resp.Results, total: resp.Total}
+		}
+
+		resp, err := c.ListDevices(limit, offset, domain, deviceType)
+		if err != nil {
+			return errMsg{fmt.Errorf("failed to list devices: %w", err)}
+		}
+		items := make([]client.SearchResult, len(resp.Data))
+		for i, d := range resp.Data {
+			items[i] = client.SearchResult{DeviceID: d.ID, Name: d.Name, Domain: d.Domain, Type: d.Type, Path: d.Path}
+		}
+		return listLoadedMsg{items: items, total: resp.Total, offset: resp.Offset}
+	}
+}
+
+// loadDevice fetches a device with its pinout, specs, and references.
+func (m Model) loadDevice(id string) tea.Cmd {
+	c := m.client
+	return func() tea.Msg {
+		device, err := c.GetDevice(id, true)
+		if err != nil {
+			return errMsg{fmt.Errorf("failed to get device %s: %w", id, err)}
+		}
+		d := &deviceDetail{device: device}
+		d.pinout, d.pinoutErr = c.GetDevicePinout(id)
+		d.specs, d.specsErr = c.GetDeviceSpecs(id)
+		if refs, err := c.GetDeviceRefs(id); err != nil {
+			d.refsErr = err
+		} else {
+			d.refs = refs.References
+		}
+		return deviceLoadedMsg{detail: d}
+	}
+}
+
+// Update handles messages.
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.resize()
+		return m, nil
+
+	case listLoadedMsg:
+		m.loading, m.err = false, nil
+		m.items, m.total, m.offset = msg.items, msg.total, msg.offset
+		m.setRows()
+		return m, nil
+
+	case deviceLoadedMsg:
+		m.loading, m.err = false, nil
+		m.detail = msg.detail
+		m.tab, m.refIndex = tabReadme, 0
+		m.resize()
+		return m, nil
+
+	case errMsg:
+		m.loading, m.err = false, msg.err
+		return m, nil
+
+	case openedMsg:
+		if msg.err != nil {
+			m.err = fmt.Errorf("failed to open %s: %w", msg.url, msg.err)
+		} else {
+			m.status = "Opened " + msg.url
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		if m.inputKind != "" {
+			return m.updateInput(msg)
+		}
+		m.status, m.err = "", nil
+		if m.detail != nil {
+			return m.updateDevice(msg)
+		}
+		return m.updateList(msg)
+	}
+	return m, nil
+}
+
+// updateInput handles keys while the search or type filter is being edited.
+func (m Model) updateInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		value := strings.TrimSpace(m.input.Value())
+		if m.inputKind == "search" {
+			m.query = value
+		} else {
+			m.deviceType = value
+		}
+		m.inputKind, m.offset, m.loading = "", 0, true
+		m.input.Blur()
+		m.table.Focus()
+		return m, m.loadList()
+	case "esc":
+		m.inputKind = ""
+		m.input.Blur()
+		m.table.Focus()
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// updateList handles keys in the device list.
+func (m Model) updateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q":
+		return m, tea.Quit
+	case "/":
+		return m.startInput("search", "Search: ", m.query)
+	case "t":
+		return m.startInput("type", "Type: ", m.deviceType)
+	case "d":
+		m.domain = (m.domain + 1) % len(domains)
+		m.offset, m.loading = 0, true
+		return m, m.loadList()
+	case "esc":
+		if m.query == "" {
+			return m, nil
+		}
+		m.query, m.offset, m.loading = "", 0, true
+		return m, m.loadList()
+	case "n":
+		if m.query != "" || m.offset+m.pageSize >= m.total {
+			return m, nil
+		}
+		m.offset += m.pageSize
+		m.loading = true
+		return m, m.loadList()
+	case "p":
+		if m.query != "" || m.offset == 0 {
+			return m, nil
+		}
+		m.offset = max(0, m.offset-m.pageSize)
+		m.loading = true
+		return m, m.loadList()
+	case "r":
+		m.loading = true
+		return m, m.loadList()
+	case "enter":
+		if len(m.items) == 0 {
+			return m, nil
+		}
+		m.history = nil
+		m.loading = true
+		return m, m.loadDevice(m.items[m.table.Cursor()].DeviceID)
+	}
+	var cmd tea.Cmd
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+// updateDevice handles keys in the device view.
+func (m Model) updateDevice(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch key := msg.String(); key {
+	case "q":
+		return m, tea.Quit
+	case "esc", "backspace":
+		if n := len(m.history); n > 0 {
+			id := m.history[n-1]
+			m.history = m.history[:n-1]
+			m.loading = true
+			return m, m.loadDevice(id)
+		}
+		m.detail = nil
+		return m, nil
+	case "tab", "right", "l":
+		m.setTab((m.tab + 1) % len(tabs))
+		return m, nil
+	case "shift+tab", "left", "h":
+		m.setTab((m.tab + len(tabs) - 1) % len(tabs))
+		return m, nil
+	case "1", "2", "3", "4":
+		m.setTab(int(key[0] - '1'))
+		return m, nil
+	}
+
+	if m.tab == tabRefs && len(m.detail.refs) > 0 {
+		switch msg.String() {
+		case "up", "k":
+			m.refIndex = max(0, m.refIndex-1)
+			m.viewport.SetContent(m.renderRefs())
+			return m, nil
+		case "down", "j":
+			m.refIndex = min(len(m.detail.refs)-1, m.refIndex+1)
+			m.viewport.SetContent(m.renderRefs())
+			return m, nil
+		case "enter":
+			return m.openRef(m.detail.refs[m.refIndex])
+		}
+	}
+
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+// openRef follows a reference: related devices are loaded in place, links
+// are opened in the browser.
+func (m Model) openRef(ref client.Reference) (tea.Model, tea.Cmd) {
+	if ref.ID != "" {
+		m.history = append(m.history, m.detail.device.ID)
+		m.loading = true
+		return m, m.loadDevice(ref.ID)
+	}
+	if ref.URL == "" {
+		return m, nil
+	}
+	url := ref.URL
+	return m, func() tea.Msg {
+		return openedMsg{url: url, err: openURL(url)}
+	}
+}
+
+// startInput focuses the text input for a search or type filter.
+func (m Model) startInput(kind, prompt, value string) (tea.Model, tea.Cmd) {
+	m.inputKind = kind
+	m.input.Prompt = prompt
+	m.input.SetValue(value)
+	m.input.CursorEnd()
+	m.table.Blur()
+	return m, m.input.Focus()
+}
+
+// setTab switches the device view tab and resets the scroll position.
+func (m *Model) setTab(tab int) {
+	m.tab = tab
+	m.viewport.SetContent(m.renderTab())
+	m.viewport.GotoTop()
+}
+
+// resize fits the table and viewport to the window.
+func (m *Model) resize() {
+	body := max(3, m.height-4)
+	m.table.SetHeight(body)
+	m.table.SetWidth(m.width)
+	m.setColumns()
+
+	m.viewport.Width = m.width
+	m.viewport.Height = max(1, body-2)
+	if m.detail != nil {
+		m.viewport.SetContent(m.renderTab())
+	}
+}
+
+// setColumns sizes the list columns to the window width.
+func (m *Model) setColumns() {
+	fixed := 24 + 10 + 14
+	if m.query != "" {
+		fixed += 7
+	}
+	name := max(12, m.width-fixed-10)
+
+	cols := []table.Column{
+		{Title: "ID", Width: 24},
+		{Title: "Name", Width: name},
+		{Title: "Domain", Width: 10},
+		{Title: "Type", Width: 14},
+	}
+	if m.query != "" {
+		cols = append([]table.Column{{Title: "Score", Width: 7}}, cols...)
+	}
+	// Columns must be set before rows of a different width.
+	m.table.SetRows(nil)
+	m.table.SetColumns(cols)
+}
+
+// setRows fills the table from the loaded items.
+func (m *Model) setRows() {
+	m.setColumns()
+	rows := make([]table.Row, len(m.items))
+	for i, it := range m.items {
+		row := table.Row{it.DeviceID, it.Name, it.Domain, it.Type}
+		if m.query != "" {
+			row = append(table.Row{fmt.Sprintf("%.2f", it.Score)}, row...)
+		}
+		rows[i] = row
+	}
+	m.table.SetRows(rows)
+	m.table.SetCursor(0)
+}
+
+// renderTab renders the content of the current device tab.
+func (m *Model) renderTab() string {
+	switch m.tab {
+	case tabPinout:
+		return m.renderPinout()
+	case tabSpecs:
+		return m.renderSpecs()
+	case tabRefs:
+		return m.renderRefs()
+	default:
+		return m.renderReadme()
+	}
+}
+
+// renderReadme renders the device content as markdown, caching the result
+// for the current width.
+func (m *Model) renderReadme() string {
+	d := m.detail
+	if d.device.Content == "" {
+		return dimStyle.Render("No README content for this device.")
+	}
+	if d.readme != "" && d.readmeW == m.width {
+		return d.readme
+	}
+
+	style := glamour.WithStandardStyle(m.style)
+	if m.style == "auto" {
+		style = glamour.WithAutoStyle()
+	}
+	r, err := glamour.NewTermRenderer(style, glamour.WithWordWrap(max(20, m.width-4)))
+	if err != nil {
+		return d.device.Content
+	}
+	out, err := r.Render(d.device.Content)
+	if err != nil {
+		return d.device.Content
+	}
+	d.readme, d.readmeW = out, m.width
+	return out
+}
+
+// renderPinout renders the pinout as an aligned table.
+func (m *Model) renderPinout() string {
+	d := m.detail
+	if d.pinoutErr != nil || d.pinout == nil || len(d.pinout.Pins) == 0 {
+		return dimStyle.Render("No pinout available for this device.")
+	}
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PIN\tGPIO\tNAME\tPULL\tALT FUNCTIONS\tDESCRIPTION")
+	for _, p := range d.pinout.Pins {
+		gpio := "-"
+		if p.GPIONum != nil {
+			gpio = fmt.Sprintf("%d", *p.GPIONum)
+		}
+		cells := []string{p.DefaultPull, strings.Join(p.AltFunctions, ", "), p.Description}
+		for i, c := range cells {
+			if c == "" {
+				cells[i] = "-"
+			}
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", p.PhysicalPin, gpio, p.Name, strings.Join(cells, "\t"))
+	}
+	tw.Flush()
+	return sb.String()
+}
+
+// renderSpecs renders the specifications sorted by name.
+func (m *Model) renderSpecs() string {
+	d := m.detail
+	if d.specsErr != nil || d.specs == nil || len(d.specs.Specs) == 0 {
+		return dimStyle.Render("No specifications available for this device.")
+	}
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+	for _, k := range slices.Sorted(maps.Keys(d.specs.Specs)) {
+		fmt.Fprintf(tw, "%s\t%s\n", k, d.specs.Specs[k])
+	}
+	tw.Flush()
+	return sb.String()
+}
+
+// renderRefs renders the references with the selected one highlighted.
+func (m *Model) renderRefs() string {
+	d := m.detail
+	if d.refsErr != nil || len(d.refs) == 0 {
+		return dimStyle.Render("No references for this device.")
+	}
+	var sb strings.Builder
+	for i, ref := range d.refs {
+		target := ref.URL
+		if ref.ID != "" {
+			target = "device " + ref.ID
+		}
+		line := fmt.Sprintf("[%s] %s — %s", ref.Type, ref.Title, target)
+		if i == m.refIndex {
+			sb.WriteString(selectedStyle.Render("> "+line) + "\n")
+		} else {
+			sb.WriteString("  " + line + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// View renders the browser.
+func (m Model) View() string {
+	var sb strings.Builder
+	sb.WriteString(m.headerView() + "\n\n")
+
+	if m.detail != nil {
+		var names []string
+		for i, name := range tabs {
+			label := fmt.Sprintf("%d %s", i+1, name)
+			if i == m.tab {
+				names = append(names, activeTabStyle.Render(label))
+			} else {
+				names = append(names, tabStyle.Render(label))
+			}
+		}
+		sb.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, names...) + "\n\n")
+		sb.WriteString(m.viewport.View() + "\n")
+	} else if m.inputKind != "" {
+		sb.WriteString(m.input.View() + "\n")
+		sb.WriteString(m.table.View() + "\n")
+	} else {
+		sb.WriteString(m.table.View() + "\n")
+	}
+
+	sb.WriteString(m.footerView())
+	return sb.String()
+}
+
+// headerView renders the title line with the current location and filters.
+func (m Model) headerView() string {
+	title := titleStyle.Render("Manuals")
+	if m.detail != nil {
+		d := m.detail.device
+		return fmt.Sprintf("%s  %s %s", title, d.Name, dimStyle.Render(fmt.Sprintf("(%s · %s/%s)", d.ID, d.Domain, d.Type)))
+	}
+
+	var filters []string
+	if m.query != "" {
+		filters = append(filters, fmt.Sprintf("search %q", m.query))
+	}
+	if domains[m.domain] != "" {
+		filters = append(filters, "domain "+domains[m.domain])
+	}
+	if m.deviceType != "" {
+		filters = append(filters, "type "+m.deviceType)
+	}
+	summary := fmt.Sprintf("%d devices", m.total)
+	if m.query == "" && len(m.items) > 0 {
+		summary = fmt.Sprintf("%d-%d of %d devices", m.offset+1, m.offset+len(m.items), m.total)
+	}
+	if len(filters) > 0 {
+		summary += " · " + strings.Join(filters, ", ")
+	}
+	return fmt.Sprintf("%s  %s", title, dimStyle.Render(summary))
+}
+
+// footerView renders the status line and key help.
+func (m Model) footerView() string {
+	var status string
+	switch {
+	case m.err != nil:
+		status = errorStyle.Render(m.err.Error())
+	case m.loading:
+		status = "Loading…"
+	default:
+		status = m.status
+	}
+
+	help := "↑/↓ move · enter open · / search · d domain · t type · n/p page · q quit"
+	switch {
+	case m.inputKind != "":
+		help = "enter apply · esc cancel"
+	case m.detail != nil && m.tab == tabRefs:
+		help = "tab/1-4 section · ↑/↓ select · enter open ref · esc back · q quit"
+	case m.detail != nil:
+		help = "tab/1-4 section · ↑/↓ scroll · esc back · q quit"
+	case m.query != "":
+		help = "↑/↓ move · enter open · / search · esc clear search · d domain · t type · q quit"
+	}
+	return status + "\n" + dimStyle.Render(help)
+}
@@ -0,0 +1,235 @@
+package tui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rmrfslashbin/manuals-mcp/internal/client"
+)
+
+// newTestModel creates a browser backed by a fake API with two related
+// devices.
+func newTestModel(t *testing.T) Model {
+	t.Helper()
+	gpio := 2
+	devices := map[string]client.Device{
+		"esp32":  {ID: "esp32", Name: "ESP32 DevKit", Domain: "hardware", Type: "mcu", Content: "# ESP32\n\nWi-Fi microcontroller."},
+		"bme280": {ID: "bme280", Name: "BME280", Domain: "hardware", Type: "sensor"},
+	}
+	prefix := "/api/" + client.APIVersion
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, prefix)
+		switch {
+		case path == "/devices":
+			resp := client.DevicesResponse{Total: 2}
+			for _, id := range []string{"esp32", "bme280"} {
+				d := devices[id]
+				if r.URL.Query().Get("type") == "" || r.URL.Query().Get("type") == d.Type {
+					resp.Data = append(resp.Data, d)
+				}
+			}
+			json.NewEncoder(w).Encode(resp)
+		case path == "/search":
+			json.NewEncoder(w).Encode(client.SearchResponse{
+				Query:   r.URL.Query().Get("q"),
+				Total:   1,
+				Results: []client.SearchResult{{DeviceID: "bme280", Name: "BME280", Domain: "hardware", Type: "sensor", Score: 1.5}},
+			})
+		case path == "/devices/esp32/pinout":
+			json.NewEncoder(w).Encode(client.PinoutResponse{DeviceID: "esp32", Pins: []client.PinoutPin{
+				{PhysicalPin: 1, GPIONum: &gpio, Name: "GPIO2", AltFunctions: []string{"ADC2_2", "TOUCH2"}},
+			}})
+		case path == "/devices/esp32/specs":
+			json.NewEncoder(w).Encode(client.SpecsResponse{DeviceID: "esp32", Specs: map[string]string{"flash": "4MB", "cpu": "Xtensa LX6"}})
+		case path == "/devices/esp32/refs":
+			json.NewEncoder(w).Encode(client.RefsResponse{DeviceID: "esp32", References: []client.Reference{
+				{Type: "device", Title: "BME280 sensor", ID: "bme280"},
+				{Type: "link", Title: "Datasheet", URL: "https://example.com/esp32.pdf"},
+			}})
+		case strings.HasPrefix(path, "/devices/") && !strings.Contains(strings.TrimPrefix(path, "/devices/"), "/"):
+			d, ok := devices[strings.TrimPrefix(path, "/devices/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			json.NewEncoder(w).Encode(d)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(client.ErrorResponse{})
+		}
+	}))
+	t.Cleanup(api.Close)
+
+	m := New(client.New(api.URL, "test-key"), Options{Style: "notty"})
+	m = run(t, m, tea.WindowSizeMsg{Width: 120, Height: 40})
+	return run(t, m, m.Init()())
+}
+
+// run feeds msg to m and then the messages of any commands it returns,
+// the way the bubbletea runtime would. Timer commands such as the cursor
+// blink are abandoned rather than waited for.
+func run(t *testing.T, m Model, msg tea.Msg) Model {
+	t.Helper()
+	next, cmd := m.Update(msg)
+	m = next.(Model)
+	if cmd == nil {
+		return m
+	}
+	done := make(chan tea.Msg, 1)
+	go func() { done <- cmd() }()
+	select {
+	case out := <-done:
+		switch out.(type) {
+		case listLoadedMsg, deviceLoadedMsg, errMsg, openedMsg:
+			return run(t, m, out)
+		}
+	case <-time.After(200 * time.Millisecond):
+	}
+	return m
+}
+
+// typeText types s into the focused input. Typing only returns cursor
+// blink commands, so they are not run.
+func typeText(m Model, s string) Model {
+	for _, r := range s {
+		next, _ := m.Update(key(string(r)))
+		m = next.(Model)
+	}
+	return m
+}
+
+// key builds a key press message.
+func key(s string) tea.Msg {
+	switch s {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	case "tab":
+		return tea.KeyMsg{Type: tea.KeyTab}
+	case "down":
+		return tea.KeyMsg{Type: tea.KeyDown}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func TestBrowser_ListAndFilter(t *testing.T) {
+	m := newTestModel(t)
+	if m.err != nil {
+		t.Fatalf("unexpected error: %v", m.err)
+	}
+	view := m.View()
+	for _, want := range []string{"1-2 of 2 devices", "ESP32 DevKit", "BME280"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("list view missing %q:\n%s", want, view)
+		}
+	}
+
+	m = run(t, m, key("t"))
+	m = typeText(m, "sensor")
+	m = run(t, m, key("enter"))
+	if m.deviceType != "sensor" || len(m.items) != 1 || m.items[0].DeviceID != "bme280" {
+		t.Errorf("type filter: deviceType = %q, items = %+v", m.deviceType, m.items)
+	}
+
+	m = run(t, m, key("d"))
+	if !strings.Contains(m.View(), "domain hardware") {
+		t.Errorf("header missing domain filter:\n%s", m.headerView())
+	}
+}
+
+func TestBrowser_Search(t *testing.T) {
+	m := newTestModel(t)
+
+	m = run(t, m, key("/"))
+	m = typeText(m, "humidity")
+	m = run(t, m, key("enter"))
+	if m.query != "humidity" || len(m.items) != 1 {
+		t.Fatalf("search: query = %q, items = %+v", m.query, m.items)
+	}
+	if !strings.Contains(m.View(), "1.50") {
+		t.Errorf("search results missing score:\n%s", m.View())
+	}
+
+	m = run(t, m, key("esc"))
+	if m.query != "" || len(m.items) != 2 {
+		t.Errorf("esc should clear the search: query = %q, items = %d", m.query, len(m.items))
+	}
+}
+
+func TestBrowser_DeviceTabsAndRefs(t *testing.T) {
+	m := newTestModel(t)
+
+	m = run(t, m, key("enter"))
+	if m.detail == nil || m.detail.device.ID != "esp32" {
+		t.Fatalf("enter should open esp32, got %+v", m.detail)
+	}
+	if view := m.View(); !strings.Contains(view, "Wi-Fi microcontroller") {
+		t.Errorf("README tab missing content:\n%s", view)
+	}
+
+	m = run(t, m, key("tab"))
+	if view := m.View(); !strings.Contains(view, "GPIO2") || !strings.Contains(view, "ADC2_2, TOUCH2") {
+		t.Errorf("pinout tab missing pins:\n%s", view)
+	}
+
+	m = run(t, m, key("3"))
+	if view := m.View(); strings.Index(view, "cpu") > strings.Index(view, "flash") {
+		t.Errorf("specs should be sorted:\n%s", view)
+	}
+
+	var opened string
+	orig := startBrowser
+	startBrowser = func(url string) error { opened = url; return nil }
+	t.Cleanup(func() { startBrowser = orig })
+
+	m = run(t, m, key("4"))
+	m = run(t, m, key("down"))
+	m = run(t, m, key("enter"))
+	if opened != "https://example.com/esp32.pdf" {
+		t.Errorf("opened = %q, want datasheet URL", opened)
+	}
+
+	m = run(t, m, key("k"))
+	m = run(t, m, key("enter"))
+	if m.detail == nil || m.detail.device.ID != "bme280" {
+		t.Fatalf("device ref should open bme280, got %+v", m.detail)
+	}
+	m = run(t, m, key("2"))
+	if view := m.View(); !strings.Contains(view, "No pinout available") {
+		t.Errorf("bme280 pinout should be unavailable:\n%s", view)
+	}
+
+	m = run(t, m, key("esc"))
+	if m.detail == nil || m.detail.device.ID != "esp32" {
+		t.Fatalf("esc should return to esp32, got %+v", m.detail)
+	}
+	m = run(t, m, key("esc"))
+	if m.detail != nil {
+		t.Error("esc should return to the list")
+	}
+}
+
+func TestOpenURL_OnlyWebLinks(t *testing.T) {
+	var opened []string
+	orig := startBrowser
+	startBrowser = func(url string) error { opened = append(opened, url); return nil }
+	t.Cleanup(func() { startBrowser = orig })
+
+	for _, ref := range []string{"file:///etc/passwd", "javascript:alert(1)", "smb://host/share", "/tmp/x.pdf", "https://"} {
+		if err := openURL(ref); err == nil {
+			t.Errorf("openURL(%q) error = nil, want refusal", ref)
+		}
+	}
+	if err := openURL("https://example.com/a.pdf"); err != nil {
+		t.Errorf("openURL(https) error = %v", err)
+	}
+	if len(opened) != 1 || opened[0] != "https://example.com/a.pdf" {
+		t.Errorf("opened %v, want only the https link", opened)
+	}
+}